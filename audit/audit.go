@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Outcome tells whether an audited request succeeded.
+type Outcome string
+
+// Define the outcomes of an audited request.
+const (
+	// OutcomeSuccess is recorded for requests answered with a 2xx or 3xx status.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeDenied is recorded for requests rejected with 401 Unauthorized or 403 Forbidden.
+	OutcomeDenied Outcome = "denied"
+	// OutcomeFailure is recorded for every other request.
+	OutcomeFailure Outcome = "failure"
+)
+
+// OutcomeOf returns the outcome of a request answered with the given HTTP status code.
+func OutcomeOf(status int) Outcome {
+	switch {
+	case status < http.StatusBadRequest:
+		return OutcomeSuccess
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return OutcomeDenied
+	default:
+		return OutcomeFailure
+	}
+}
+
+// Event records one management request: who made it, what it changed and how it ended.
+type Event struct {
+	Time      time.Time       `json:"time"`                 // When the request was handled.
+	Principal string          `json:"principal,omitempty"`  // The name of the authenticated principal, if any.
+	Operation string          `json:"operation"`            // The method and route of the request, such as "PATCH /:id".
+	ID        string          `json:"id,omitempty"`         // The ID of the link or the name of the API key concerned, if any.
+	Before    json.RawMessage `json:"before,omitempty"`     // The resource before the change, as JSON.
+	After     json.RawMessage `json:"after,omitempty"`      // The resource after the change, as JSON.
+	ClientIP  string          `json:"client_ip"`            // The IP address of the client.
+	RequestID string          `json:"request_id,omitempty"` // The ID of the request, for correlation with the request logs.
+	Outcome   Outcome         `json:"outcome"`              // Whether the request succeeded.
+	Status    int             `json:"status"`               // The HTTP status code of the response.
+}
+
+// Sink stores audit events. Sinks only ever append; events are never changed or removed.
+// Implementations must be safe for concurrent use by multiple goroutines.
+type Sink interface {
+	Write(ctx context.Context, event Event) error
+}
+
+// Filter selects the audit events returned by a query.
+type Filter struct {
+	Since  time.Time // Only events at or after this time, unless zero.
+	Until  time.Time // Only events before this time, unless zero.
+	Limit  int       // The maximum number of events to return.
+	Cursor string    // The NextCursor of the previous page, or empty to start.
+}
+
+// Matches reports whether the event lies within the time range of the filter.
+func (f Filter) Matches(event Event) bool {
+	if !f.Since.IsZero() && event.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !event.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// InvalidCursorError is returned by Querier implementations when the cursor of the filter was not
+// produced by the same sink, so that callers can reject the request instead of failing it.
+type InvalidCursorError struct {
+	Cursor string
+}
+
+// Error returns the error message of an InvalidCursorError.
+func (e *InvalidCursorError) Error() string {
+	return fmt.Sprintf(ErrMsgInvalidCursor, e.Cursor)
+}
+
+// Page is a page of audit events, oldest first, along with the cursor of the next page.
+type Page struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// Querier is implemented by sinks whose events can be read back.
+// A cursor the sink cannot decode is reported with an InvalidCursorError.
+type Querier interface {
+	Query(ctx context.Context, filter Filter) (*Page, error)
+}
+
+// NewFromEnv creates the sink selected by AUDIT_SINK. The datastore sink cannot be created here,
+// as it needs a client, so it is passed in as store and returned when AUDIT_SINK is datastore.
+// NewFromEnv returns a nil sink without error if AUDIT_SINK is not set.
+func NewFromEnv(store Sink) (Sink, error) {
+	switch sink := os.Getenv(AUDIT_SINK); sink {
+	case "":
+		return nil, nil
+	case SinkDatastore:
+		if store == nil {
+			return nil, fmt.Errorf(ErrMsgMissingDatastore)
+		}
+		return store, nil
+	case SinkFile:
+		path := os.Getenv(AUDIT_FILE)
+		if path == "" {
+			return nil, fmt.Errorf(ErrMsgMissingFile)
+		}
+		return NewFileSink(path)
+	case SinkStdout:
+		return NewWriterSink(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf(ErrMsgUnknownSink, sink)
+	}
+}
@@ -0,0 +1,119 @@
+// Gopher Unit Testing was here
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOutcomeOf checks the outcome recorded for each kind of status code.
+func TestOutcomeOf(t *testing.T) {
+	testCases := map[int]Outcome{
+		http.StatusOK:                  OutcomeSuccess,
+		http.StatusCreated:             OutcomeSuccess,
+		http.StatusUnauthorized:        OutcomeDenied,
+		http.StatusForbidden:           OutcomeDenied,
+		http.StatusNotFound:            OutcomeFailure,
+		http.StatusInternalServerError: OutcomeFailure,
+	}
+	for status, want := range testCases {
+		if got := OutcomeOf(status); got != want {
+			t.Errorf("OutcomeOf(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+// TestFileSink checks that events are appended and read back by time range, page by page.
+func TestFileSink(t *testing.T) {
+	sink, err := NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		event := Event{Time: start.Add(time.Duration(i) * time.Hour), Operation: "PATCH /:id", ID: string(rune('a' + i)), Outcome: OutcomeSuccess}
+		if err := sink.Write(context.Background(), event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	filter := Filter{Since: start.Add(time.Hour), Until: start.Add(4 * time.Hour), Limit: 2}
+	var ids []string
+	for {
+		page, err := sink.Query(context.Background(), filter)
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		for _, event := range page.Events {
+			ids = append(ids, event.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	if got := strings.Join(ids, ""); got != "bcd" {
+		t.Errorf("Query() returned %q, want %q", got, "bcd")
+	}
+
+	for _, cursor := range []string{"-1", "3"} {
+		if _, err := sink.Query(context.Background(), Filter{Limit: 1, Cursor: cursor}); !errors.As(err, new(*InvalidCursorError)) {
+			t.Errorf("Query() with cursor %q error = %v, want an InvalidCursorError", cursor, err)
+		}
+	}
+}
+
+// TestWriterSink checks that events are written as JSON Lines.
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+	sink.Write(context.Background(), Event{Operation: "POST /", Status: http.StatusOK, Outcome: OutcomeSuccess})
+	sink.Write(context.Background(), Event{Operation: "DELETE /:id", Status: http.StatusForbidden, Outcome: OutcomeDenied})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"outcome":"denied"`) {
+		t.Errorf("WriterSink wrote %q", buf.String())
+	}
+}
+
+// TestNewFromEnv checks the selection of the sink from the environment.
+func TestNewFromEnv(t *testing.T) {
+	store := NewWriterSink(&bytes.Buffer{})
+
+	t.Setenv(AUDIT_SINK, "")
+	if sink, err := NewFromEnv(store); sink != nil || err != nil {
+		t.Errorf("NewFromEnv() without AUDIT_SINK = %v, %v, want nil, nil", sink, err)
+	}
+
+	t.Setenv(AUDIT_SINK, SinkDatastore)
+	if sink, err := NewFromEnv(store); sink != store || err != nil {
+		t.Errorf("NewFromEnv(datastore) = %v, %v, want the store", sink, err)
+	}
+
+	t.Setenv(AUDIT_SINK, SinkFile)
+	t.Setenv(AUDIT_FILE, "")
+	if _, err := NewFromEnv(store); err == nil {
+		t.Error("NewFromEnv(file) without AUDIT_FILE succeeded, want an error")
+	}
+	t.Setenv(AUDIT_FILE, filepath.Join(t.TempDir(), "audit.jsonl"))
+	sink, err := NewFromEnv(store)
+	if _, ok := sink.(*FileSink); !ok || err != nil {
+		t.Errorf("NewFromEnv(file) = %T, %v, want a *FileSink", sink, err)
+	}
+	if info, err := os.Stat(os.Getenv(AUDIT_FILE)); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("audit file mode = %v, %v, want 0600", info, err)
+	}
+
+	t.Setenv(AUDIT_SINK, "syslog")
+	if _, err := NewFromEnv(store); err == nil {
+		t.Error("NewFromEnv(syslog) succeeded, want an error")
+	}
+}
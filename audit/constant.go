@@ -0,0 +1,24 @@
+package audit
+
+// Define environment variables used to configure the audit log.
+//
+// Note: Auditing is enabled only when AUDIT_SINK is set.
+const (
+	AUDIT_SINK = "AUDIT_SINK"
+	AUDIT_FILE = "AUDIT_FILE"
+)
+
+// Define the sinks that can be selected with AUDIT_SINK.
+const (
+	SinkDatastore = "datastore"
+	SinkFile      = "file"
+	SinkStdout    = "stdout"
+)
+
+// Define error messages for the audit log.
+const (
+	ErrMsgUnknownSink      = "audit: unknown AUDIT_SINK %q, expected datastore, file or stdout"
+	ErrMsgMissingFile      = "audit: AUDIT_FILE is required when AUDIT_SINK is file"
+	ErrMsgMissingDatastore = "audit: no datastore sink available"
+	ErrMsgInvalidCursor    = "audit: invalid cursor %q"
+)
@@ -0,0 +1,29 @@
+// Package audit records an append-only trail of the management requests handled by the URL
+// shortener service, so that it can be proven who created, edited or deleted which link.
+// Each request behind handlers.InternalOnly that changes something produces an Event with the
+// principal, the operation, the ID concerned, the resource before and after the change, the
+// client IP, the request ID and the outcome.
+//
+// # Sinks
+//
+// Events are stored through a pluggable Sink, selected with the AUDIT_SINK environment variable:
+//   - datastore: Stores each event as an entity under the Kind 'auditz', with datastore.NewAuditSink.
+//   - file: Appends events as JSON Lines to the file named by AUDIT_FILE, with NewFileSink.
+//   - stdout: Writes events as JSON Lines to the standard output, with NewWriterSink, for log
+//     collectors to pick up.
+//
+// Sinks that also implement Querier can be read back page by page within a time range, which the
+// admin endpoint "internal/audit" does. The standard output cannot be queried.
+//
+// # Example Usage
+//
+//	sink, err := audit.NewFromEnv(datastore.NewAuditSink(dsClient))
+//	if err != nil {
+//	    // Handle the error.
+//	}
+//	if sink != nil {
+//	    handlers.SetAuditSink(sink)
+//	}
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package audit
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// WriterSink writes audit events as JSON Lines to a writer, such as the standard output.
+// Its events cannot be read back.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a sink that writes to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write writes the event as one line of JSON.
+func (s *WriterSink) Write(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// FileSink appends audit events as JSON Lines to a file, and reads them back for queries.
+// The file is only ever appended to, so it can be shipped or rotated by external tools.
+type FileSink struct {
+	*WriterSink
+	file *os.File
+}
+
+// NewFileSink opens the file at path for appending, creating it if needed. The file is readable
+// only by its owner, as events contain the details of every change.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{WriterSink: NewWriterSink(file), file: file}, nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// Query reads the events in the time range of the filter, in the order they were written.
+// The cursor is the offset in the file where the next page starts.
+func (s *FileSink) Query(ctx context.Context, filter Filter) (*Page, error) {
+	offset, err := parseOffset(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	start := offset
+
+	file, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	page := &Page{Events: []Event{}}
+	reader := bufio.NewReader(file)
+	for len(page.Events) < filter.Limit {
+		line, err := reader.ReadBytes('\n')
+		// A line without its newline is still being written, so it is left for a later query.
+		if err == io.EOF {
+			return page, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset += int64(len(line))
+
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			// A cursor pointing into the middle of a line is not one this sink handed out.
+			if filter.Cursor != "" && offset == start+int64(len(line)) {
+				return nil, &InvalidCursorError{Cursor: filter.Cursor}
+			}
+			return nil, err
+		}
+		if filter.Matches(event) {
+			page.Events = append(page.Events, event)
+		}
+	}
+	page.NextCursor = strconv.FormatInt(offset, 10)
+	return page, nil
+}
+
+// parseOffset decodes a cursor of the file sink.
+func parseOffset(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	offset, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, &InvalidCursorError{Cursor: cursor}
+	}
+	return offset, nil
+}
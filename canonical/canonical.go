@@ -0,0 +1,102 @@
+package canonical
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// defaultPorts maps schemes to the port that is implied when none is given.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// Options controls how URLs are canonicalized.
+type Options struct {
+	// DropFragment removes the fragment ("#section"). Fragments are never sent to the destination server,
+	// but single-page applications may use them for routing, so they are kept by default.
+	DropFragment bool
+}
+
+// Canonicalize returns the canonical form of an absolute URL, so that different spellings of the same
+// destination compare equal. It lowercases the scheme and host, converts internationalized domain names
+// to punycode, removes a trailing dot and the default port of the host, replaces an empty path with "/",
+// sorts the query parameters by name, and optionally drops the fragment.
+//
+// The path and the parameter values are not decoded, so the canonical URL still reaches the same resource.
+func Canonicalize(rawURL string, opts Options) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return "", errors.New(ErrMsgNotAbsolute)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Host, err = canonicalHost(u.Scheme, u.Hostname(), u.Port()); err != nil {
+		return "", err
+	}
+	if u.Path == "" {
+		u.Path, u.RawPath = "/", ""
+	}
+	u.RawQuery = sortQuery(u.RawQuery)
+	u.ForceQuery = false
+	if opts.DropFragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+	return u.String(), nil
+}
+
+// Hash returns the hex-encoded SHA-256 hash of a canonical URL, suitable as an indexed property.
+func Hash(canonicalURL string) string {
+	sum := sha256.Sum256([]byte(canonicalURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalHost lowercases the host, converts it to punycode and joins it with the port unless the
+// port is the default one of the scheme.
+func canonicalHost(scheme, hostname, port string) (string, error) {
+	host := strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if net.ParseIP(host) == nil {
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", fmt.Errorf(ErrMsgInvalidHost, hostname, err)
+		}
+		host = ascii
+	}
+
+	if port == "" || port == defaultPorts[scheme] {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]", nil // IPv6 literals keep their brackets.
+		}
+		return host, nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// sortQuery sorts the parameters of a raw query by name, keeping the order of repeated parameters and
+// leaving their encoding untouched. Empty parameters, as in "a=1&&b=2", are removed.
+func sortQuery(rawQuery string) string {
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return paramName(params[i]) < paramName(params[j])
+	})
+	return strings.Join(params, "&")
+}
+
+// paramName returns the name of a raw query parameter.
+func paramName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	return name
+}
@@ -0,0 +1,61 @@
+// Gopher Unit Testing was here
+package canonical
+
+import "testing"
+
+// TestCanonicalize checks each normalization rule.
+func TestCanonicalize(t *testing.T) {
+	testCases := map[string]string{
+		"HTTPS://Example.COM":              "https://example.com/",
+		"https://example.com:443/path":     "https://example.com/path",
+		"http://example.com:80/path":       "http://example.com/path",
+		"http://example.com:8080/path":     "http://example.com:8080/path",
+		"https://example.com:80/":          "https://example.com:80/",
+		"https://example.com./":            "https://example.com/",
+		"https://Bücher.example/":          "https://xn--bcher-kva.example/",
+		"https://example.com/?b=2&a=1&b=1": "https://example.com/?a=1&b=2&b=1",
+		"https://example.com/?":            "https://example.com/",
+		"https://example.com/?a=1&&b=%20":  "https://example.com/?a=1&b=%20",
+		"https://example.com/A%2Fb/Path":   "https://example.com/A%2Fb/Path",
+		"https://example.com/page#Section": "https://example.com/page#Section",
+		"http://[2001:DB8::1]:80/":         "http://[2001:db8::1]/",
+		"http://[2001:db8::1]:8080/":       "http://[2001:db8::1]:8080/",
+		"https://user@example.com/":        "https://user@example.com/",
+	}
+	for rawURL, want := range testCases {
+		got, err := Canonicalize(rawURL, Options{})
+		if err != nil {
+			t.Errorf("Canonicalize(%q) error = %v", rawURL, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+// TestCanonicalize_DropFragment checks that the fragment is removed on request.
+func TestCanonicalize_DropFragment(t *testing.T) {
+	got, err := Canonicalize("https://example.com/page?b=1&a=2#Section", Options{DropFragment: true})
+	if want := "https://example.com/page?a=2&b=1"; err != nil || got != want {
+		t.Errorf("Canonicalize() = %q, %v, want %q", got, err, want)
+	}
+}
+
+// TestCanonicalize_Invalid checks that relative and malformed URLs are rejected.
+func TestCanonicalize_Invalid(t *testing.T) {
+	for _, rawURL := range []string{"/relative", "example.com/page", "http://[::1", "mailto:someone@example.com"} {
+		if got, err := Canonicalize(rawURL, Options{}); err == nil {
+			t.Errorf("Canonicalize(%q) = %q, want an error", rawURL, got)
+		}
+	}
+}
+
+// TestHash checks that spellings of the same destination share a hash.
+func TestHash(t *testing.T) {
+	a, _ := Canonicalize("HTTPS://Example.com:443?b=2&a=1", Options{})
+	b, _ := Canonicalize("https://example.com/?a=1&b=2", Options{})
+	if Hash(a) != Hash(b) || len(Hash(a)) != 64 {
+		t.Errorf("Hash(%q) = %s, Hash(%q) = %s, want equal 64-character hashes", a, Hash(a), b, Hash(b))
+	}
+}
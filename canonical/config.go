@@ -0,0 +1,40 @@
+package canonical
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Config holds the canonicalization settings of the service.
+type Config struct {
+	// Enabled canonicalizes destination URLs before they are stored, and reuses the existing short link
+	// when the same owner shortens the same destination again.
+	Enabled bool
+	Options Options
+}
+
+// NewConfigFromEnv builds a Config from the URL_CANONICALIZE and URL_CANONICAL_DROP_FRAGMENT
+// environment variables. Canonicalization is disabled unless configured otherwise.
+func NewConfigFromEnv() (Config, error) {
+	var config Config
+	var err error
+	if config.Enabled, err = parseEnvBool(URL_CANONICALIZE); err != nil {
+		return config, err
+	}
+	config.Options.DropFragment, err = parseEnvBool(URL_CANONICAL_DROP_FRAGMENT)
+	return config, err
+}
+
+// parseEnvBool parses the environment variable as a boolean, which is false if it is unset.
+func parseEnvBool(name string) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf(ErrMsgInvalidBool, name, value)
+	}
+	return b, nil
+}
@@ -0,0 +1,14 @@
+package canonical
+
+// Define environment variables used to configure canonicalization.
+const (
+	URL_CANONICALIZE            = "URL_CANONICALIZE"
+	URL_CANONICAL_DROP_FRAGMENT = "URL_CANONICAL_DROP_FRAGMENT"
+)
+
+// Define error messages for canonicalization.
+const (
+	ErrMsgNotAbsolute = "canonical: URL must be absolute"
+	ErrMsgInvalidHost = "canonical: invalid host %q: %v"
+	ErrMsgInvalidBool = "%s: invalid boolean %q"
+)
@@ -0,0 +1,28 @@
+// Package canonical normalizes destination URLs so that different spellings of the same destination,
+// such as "HTTPS://Example.COM:443/?b=2&a=1" and "https://example.com/?a=1&b=2", compare equal.
+// The URL shortener service uses it to reuse the existing short link when an owner shortens the same
+// destination twice, looking the link up by the Hash of its canonical URL.
+//
+// # Rules
+//
+//   - The scheme and host are lowercased, and a trailing dot is removed from the host.
+//   - Internationalized domain names are converted to their ASCII (punycode) form.
+//   - The default port of the scheme (80 for http, 443 for https) is removed.
+//   - An empty path becomes "/".
+//   - Query parameters are sorted by name; repeated parameters keep their relative order.
+//   - The fragment is kept, unless Options.DropFragment is set.
+//
+// Paths and parameter values are not decoded or re-encoded, as some servers treat encoded and
+// decoded spellings differently.
+//
+// # Example Usage
+//
+//	canonicalURL, err := canonical.Canonicalize("HTTPS://Bücher.example:443?b=2&a=1#top", canonical.Options{})
+//	if err != nil {
+//	    // Handle the error.
+//	}
+//	// canonicalURL is "https://xn--bcher-kva.example/?a=1&b=2#top"
+//	hash := canonical.Hash(canonicalURL)
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package canonical
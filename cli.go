@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/handlers"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"go.uber.org/zap"
+)
+
+// Define CLI subcommands and their shared flag values.
+const (
+	commandExport = "export"
+	commandImport = "import"
+	commandAPIKey = "apikey"
+	stdioPath     = "-"
+
+	apiKeyActionCreate = "create"
+	apiKeyActionList   = "list"
+	apiKeyActionRevoke = "revoke"
+)
+
+// runCommand runs the CLI subcommand named by args[0] instead of starting the HTTP server.
+// It exits the process with a non-zero status code if the subcommand fails.
+func runCommand(ctx context.Context, datastoreClient *datastore.Client, logger *zap.Logger, args []string) {
+	var err error
+	switch args[0] {
+	case commandExport:
+		err = runExportCommand(ctx, datastoreClient, args[1:])
+	case commandImport:
+		err = runImportCommand(ctx, datastoreClient, args[1:])
+	case commandAPIKey:
+		err = runAPIKeyCommand(ctx, datastoreClient, args[1:])
+	default:
+		err = fmt.Errorf(constant.UnknownCommandContextLog+" %q", args[0])
+	}
+
+	if closeErr := datastore.CloseClient(datastoreClient); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logFields := logmonitor.CreateLogFields(args[0],
+			logmonitor.WithComponent(constant.ComponentGopher),
+			logmonitor.WithError(err),
+		)
+		logger.Error(constant.SosEmoji+"  "+constant.WarningEmoji+"  "+constant.CommandFailedContextLog, logFields...)
+		os.Exit(1)
+	}
+}
+
+// runExportCommand streams every URL entity to a file or to stdout.
+//
+// Usage: go-urlshortner export [-format csv|jsonl] [-output file]
+func runExportCommand(ctx context.Context, datastoreClient *datastore.Client, args []string) error {
+	fs := flag.NewFlagSet(commandExport, flag.ContinueOnError)
+	format := fs.String("format", "", "export format: csv or jsonl (default: inferred from -output, else jsonl)")
+	output := fs.String("output", stdioPath, "file to write to, or - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	w, closeFn, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+
+	err = handlers.ExportURLs(ctx, w, datastoreClient, resolveTransferFormat(*format, *output))
+	if closeErr := closeFn(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// runImportCommand reads URL entities from a file or from stdin and prints the import report as JSON.
+//
+// Usage: go-urlshortner import [-format csv|jsonl] [-input file] [-dry-run]
+func runImportCommand(ctx context.Context, datastoreClient *datastore.Client, args []string) error {
+	fs := flag.NewFlagSet(commandImport, flag.ContinueOnError)
+	format := fs.String("format", "", "import format: csv or jsonl (default: inferred from -input, else jsonl)")
+	input := fs.String("input", stdioPath, "file to read from, or - for stdin")
+	dryRun := fs.Bool("dry-run", false, "validate and report conflicts without writing anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, closeFn, err := openInput(*input)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	report, err := handlers.ImportURLs(ctx, r, datastoreClient, resolveTransferFormat(*format, *input), *dryRun)
+	if report != nil {
+		if encErr := printJSON(report); encErr != nil && err == nil {
+			err = encErr
+		}
+	}
+	return err
+}
+
+// runAPIKeyCommand manages named API keys.
+//
+// Usage:
+//
+//	go-urlshortner apikey create -name ci -scopes create,edit [-ttl 720h]
+//	go-urlshortner apikey list
+//	go-urlshortner apikey revoke -name ci
+func runAPIKeyCommand(ctx context.Context, datastoreClient *datastore.Client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(constant.UnknownCommandContextLog+" %q", commandAPIKey)
+	}
+
+	switch args[0] {
+	case apiKeyActionCreate:
+		return runAPIKeyCreate(ctx, datastoreClient, args[1:])
+	case apiKeyActionList:
+		apiKeys, err := datastore.ListAPIKeys(ctx, datastoreClient)
+		if err != nil {
+			return err
+		}
+		return printJSON(apiKeys)
+	case apiKeyActionRevoke:
+		return runAPIKeyRevoke(ctx, datastoreClient, args[1:])
+	}
+	return fmt.Errorf(constant.UnknownCommandContextLog+" %q", commandAPIKey+" "+args[0])
+}
+
+// runAPIKeyCreate creates a new API key and prints the plaintext key, which is shown only once.
+func runAPIKeyCreate(ctx context.Context, datastoreClient *datastore.Client, args []string) error {
+	fs := flag.NewFlagSet(apiKeyActionCreate, flag.ContinueOnError)
+	name := fs.String("name", "", "unique name of the API key")
+	scopes := fs.String("scopes", "", "comma-separated scopes: create, edit, delete, read-stats, admin")
+	ttl := fs.Duration("ttl", 0, "lifetime of the key, e.g. 720h (default: never expires)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	plaintext, apiKey, err := handlers.GenerateAPIKey(ctx, datastoreClient, *name, strings.Split(*scopes, ","), *ttl)
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]any{
+		constant.HeaderResponseAPIKey: plaintext,
+		constant.HeaderResponseKey:    apiKey,
+	})
+}
+
+// runAPIKeyRevoke revokes the named API key.
+func runAPIKeyRevoke(ctx context.Context, datastoreClient *datastore.Client, args []string) error {
+	fs := flag.NewFlagSet(apiKeyActionRevoke, flag.ContinueOnError)
+	name := fs.String("name", "", "name of the API key to revoke")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	_, _, err := datastore.RevokeAPIKey(ctx, datastoreClient, *name)
+	return err
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// resolveTransferFormat returns the explicit format if set, otherwise infers it from the file path.
+func resolveTransferFormat(format string, path string) string {
+	if format != "" {
+		return format
+	}
+	return handlers.TransferFormatFromPath(path)
+}
+
+// openOutput opens the named file for writing, or returns stdout for "-".
+func openOutput(path string) (io.Writer, func() error, error) {
+	if path == stdioPath {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// openInput opens the named file for reading, or returns stdin for "-".
+func openInput(path string) (io.Reader, func() error, error) {
+	if path == stdioPath {
+		return os.Stdin, func() error { return nil }, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
@@ -26,17 +26,27 @@ import (
 	"time"
 
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/go-urlshortner/audit"
 	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
 	"github.com/H0llyW00dzZ/go-urlshortner/handlers"
+	"github.com/H0llyW00dzZ/go-urlshortner/health"
+	"github.com/H0llyW00dzZ/go-urlshortner/jwtauth"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
+	"github.com/H0llyW00dzZ/go-urlshortner/ratelimit"
+	"github.com/H0llyW00dzZ/go-urlshortner/scanner"
+	"github.com/H0llyW00dzZ/go-urlshortner/tracing"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func main() {
-	// Initialize the zap logger with a development configuration.
-	logger, err := zap.NewDevelopment()
+	// Initialize the zap logger with the configuration from the environment.
+	logger, err := setupLogger()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, constant.FailedToIntializeLoggerContextLog+" %v\n", err)
 		os.Exit(1)
@@ -44,9 +54,11 @@ func main() {
 	defer logger.Sync() // Flush any buffered log entries
 
 	// Pass the logger instance to other packages
-	datastore.SetLogger(logger)
-	logmonitor.SetLogger(logger)
-	handlers.SetLogger(logger)
+	// Each package gets a logger of its own, so that its level can be changed at runtime.
+	datastore.SetLogger(logmonitor.PackageLogger(logger, logmonitor.PackageDatastore))
+	logmonitor.SetLogger(logmonitor.PackageLogger(logger, logmonitor.PackageLogmonitor))
+	handlers.SetLogger(logmonitor.PackageLogger(logger, logmonitor.PackageHandlers))
+	health.SetLogger(logger)
 
 	if err := checkEnvironment(logger); err != nil {
 		handleStartupFailure(err, logger)
@@ -58,8 +70,57 @@ func main() {
 		handleStartupFailure(err, logger)
 	}
 
-	router := setupRouter(datastoreClient, logger)
-	startServer(router, logger, datastoreClient)
+	shutdownTracing, err := setupTracing(ctx, logger)
+	if err != nil {
+		handleStartupFailure(err, logger)
+	}
+	defer shutdownTracing()
+
+	if err := setupTokenVerifier(ctx, logger); err != nil {
+		handleStartupFailure(err, logger)
+	}
+
+	if err := setupRateLimitBackend(ctx, logger); err != nil {
+		handleStartupFailure(err, logger)
+	}
+
+	if err := setupURLScanner(logger); err != nil {
+		handleStartupFailure(err, logger)
+	}
+
+	if err := setupAuditSink(datastoreClient, logger); err != nil {
+		handleStartupFailure(err, logger)
+	}
+
+	// Run a CLI subcommand (e.g., "export" or "import") instead of the HTTP server if one is given.
+	if len(os.Args) > 1 {
+		runCommand(ctx, datastoreClient, logger, os.Args[1:])
+		return
+	}
+
+	accessLogConfig, err := logmonitor.NewAccessLogConfigFromEnv()
+	if err != nil {
+		handleStartupFailure(fmt.Errorf(constant.FailedToSetupAccessLogContextLog+" %v", err), logger)
+	}
+
+	drainDelay, err := shutdownDrainDelay()
+	if err != nil {
+		handleStartupFailure(fmt.Errorf(constant.FailedToSetupDrainDelayContextLog+" %v", err), logger)
+	}
+
+	router := setupRouter(datastoreClient, accessLogConfig)
+	startServer(router, logger, datastoreClient, drainDelay)
+}
+
+// setupLogger builds the logger from the LOG_* environment variables. With Cloud Logging field names,
+// trace IDs are qualified with the Datastore project, which is the project the service runs in.
+func setupLogger() (*zap.Logger, error) {
+	config, err := logmonitor.NewLoggerConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	config.ProjectID = os.Getenv("DATASTORE_PROJECT_ID")
+	return logmonitor.NewLogger(config)
 }
 
 // setupDatastoreClient creates a new Datastore client and performs a test operation to check connectivity.
@@ -75,6 +136,11 @@ func setupDatastoreClient(ctx context.Context, logger *zap.Logger) (*datastore.C
 		return nil, err
 	}
 
+	// Run the same probe on each readiness check, so that replicas that lose Datastore stop receiving traffic.
+	health.Register(health.CheckDatastore, func(ctx context.Context) error {
+		return testClientConnection(ctx, datastoreClient)
+	})
+
 	return datastoreClient, nil
 }
 
@@ -95,6 +161,126 @@ func testClientConnection(ctx context.Context, client *datastore.Client) error {
 	return nil
 }
 
+// setupTracing installs the W3C trace context propagator, so that log lines carry the trace IDs sent by
+// callers, and records spans to the exporter selected with TRACING_EXPORTER, if any. It returns a function
+// that flushes the spans that are still buffered, to be called before exiting.
+func setupTracing(ctx context.Context, logger *zap.Logger) (shutdown func(), err error) {
+	otel.SetTextMapPropagator(tracing.Propagator)
+
+	config, err := tracing.NewConfigFromEnv()
+	if err != nil || config == nil {
+		return func() {}, err
+	}
+	provider, err := tracing.NewProvider(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf(constant.FailedToSetupTracingContextLog+" %v", err)
+	}
+	otel.SetTracerProvider(provider)
+
+	logFields := logmonitor.CreateLogFields("setupTracing",
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("exporter", config.Exporter)),
+		logmonitor.WithAnyZapField(zap.Float64("sample_ratio", config.SampleRatio)),
+	)
+	logger.Info(constant.InfoEmoji+"  "+constant.TracingEnabledContextLog, logFields...)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			logger.Error(constant.WarningEmoji+"  "+constant.FailedToShutdownTracingContextLog, zap.Error(err))
+		}
+	}, nil
+}
+
+// setupTokenVerifier enables bearer token authentication for the management API if a JWKS source is configured.
+func setupTokenVerifier(ctx context.Context, logger *zap.Logger) error {
+	config, err := jwtauth.NewConfigFromEnv()
+	if err != nil || config == nil {
+		return err
+	}
+
+	verifier, err := jwtauth.NewVerifier(ctx, config)
+	if err != nil {
+		return fmt.Errorf(constant.FailedToSetupTokenVerifierContextLog+" %v", err)
+	}
+	handlers.SetTokenVerifier(verifier)
+	health.Register(health.CheckJWKS, verifier.Check)
+
+	logFields := logmonitor.CreateLogFields("setupTokenVerifier",
+		logmonitor.WithComponent(constant.ComponentAuth),
+		logmonitor.WithAnyZapField(zap.String("issuer", config.Issuer)),
+		logmonitor.WithAnyZapField(zap.String("audience", config.Audience)),
+	)
+	logger.Info(constant.InfoEmoji+"  "+constant.TokenVerifierEnabledContextLog, logFields...)
+	return nil
+}
+
+// setupRateLimitBackend replaces the per-process rate limiters with a shared backend if one is selected,
+// so that limits hold across every replica. While the shared backend is unavailable, the local
+// limiters take over and a warning is logged.
+func setupRateLimitBackend(ctx context.Context, logger *zap.Logger) error {
+	onError := func(err error) {
+		logger.Warn(constant.WarningEmoji+"  "+constant.RateLimitBackendUnavailableContextLog, zap.Error(err))
+	}
+	backend, err := ratelimit.NewBackendFromEnv(ctx, handlers.RateLimiterStore, onError)
+	if err != nil {
+		return fmt.Errorf(constant.FailedToSetupRateLimitBackendContextLog+" %v", err)
+	}
+	if backend == nil {
+		return nil
+	}
+	handlers.SetRateLimitBackend(backend)
+
+	logFields := logmonitor.CreateLogFields("setupRateLimitBackend",
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("backend", os.Getenv(ratelimit.RATE_LIMIT_BACKEND))),
+	)
+	logger.Info(constant.InfoEmoji+"  "+constant.RateLimitBackendEnabledContextLog, logFields...)
+	return nil
+}
+
+// setupURLScanner enables scanning of destination URLs if a hash-prefix list or a scanning webhook is configured.
+// When the scanner is configured to fail open, each URL allowed because of a scanner failure is logged as a warning.
+func setupURLScanner(logger *zap.Logger) error {
+	onError := func(err error) {
+		logger.Warn(constant.WarningEmoji+"  "+constant.URLScannerUnavailableContextLog, zap.Error(err))
+	}
+	urlScanner, err := scanner.NewFromEnv(onError)
+	if err != nil {
+		return fmt.Errorf(constant.FailedToSetupURLScannerContextLog+" %v", err)
+	}
+	if urlScanner == nil {
+		return nil
+	}
+	handlers.SetURLScanner(urlScanner)
+
+	logFields := logmonitor.CreateLogFields("setupURLScanner",
+		logmonitor.WithComponent(constant.ComponentGopher),
+	)
+	logger.Info(constant.InfoEmoji+"  "+constant.URLScannerEnabledContextLog, logFields...)
+	return nil
+}
+
+// setupAuditSink enables the audit log of management requests if a sink is selected with AUDIT_SINK.
+func setupAuditSink(datastoreClient *datastore.Client, logger *zap.Logger) error {
+	sink, err := audit.NewFromEnv(datastore.NewAuditSink(datastoreClient))
+	if err != nil {
+		return fmt.Errorf(constant.FailedToSetupAuditSinkContextLog+" %v", err)
+	}
+	if sink == nil {
+		return nil
+	}
+	handlers.SetAuditSink(sink)
+
+	logFields := logmonitor.CreateLogFields("setupAuditSink",
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("sink", os.Getenv(audit.AUDIT_SINK))),
+	)
+	logger.Info(constant.InfoEmoji+"  "+constant.AuditSinkEnabledContextLog, logFields...)
+	return nil
+}
+
 // checkEnvironment checks for the presence of required environment variables.
 func checkEnvironment(logger *zap.Logger) error {
 	// Check for the presence of required environment variables
@@ -118,7 +304,7 @@ func initializeDatastoreClient(ctx context.Context, logger *zap.Logger) (*datast
 }
 
 // setupRouter creates a new Gin router and sets up the middleware.
-func setupRouter(datastoreClient *datastore.Client, logger *zap.Logger) *gin.Engine {
+func setupRouter(datastoreClient *datastore.Client, accessLogConfig *logmonitor.AccessLogConfig) *gin.Engine {
 	// Set up the router and middleware
 	if os.Getenv("GIN_MODE") != "debug" {
 		gin.SetMode(gin.ReleaseMode)
@@ -128,21 +314,58 @@ func setupRouter(datastoreClient *datastore.Client, logger *zap.Logger) *gin.Eng
 	router := gin.New()
 	router.Use(gin.Recovery()) // Using only the recovery middleware
 
+	// Register the probes before the other middleware, so that they are neither logged nor counted,
+	// unless they are served on their own listener.
+	if os.Getenv(health.PROBE_ADDR) == "" {
+		router.GET(health.PathHealthz, gin.WrapH(health.LivenessHandler()))
+		router.GET(health.PathReadyz, gin.WrapH(health.ReadinessHandler()))
+	}
+
+	// Assign an ID to each request, so that its log lines and error responses can be correlated.
+	router.Use(logmonitor.RequestID())
+
+	// Start a span for each request, continuing the trace of the caller if it sent one.
+	router.Use(otelgin.Middleware(tracing.ServiceName))
+
 	// Using custom logging middleware with zap
-	router.Use(logmonitor.RequestLogger(logger))
+	router.Use(logmonitor.RequestLoggerWithConfig(logmonitor.Logger, accessLogConfig))
+	router.Use(metrics.Middleware())
 	handlers.RegisterHandlersGin(router, datastoreClient)
 
 	return router
 }
 
 // startServer sets up and starts the HTTP server, and waits for a shutdown signal.
-func startServer(router *gin.Engine, logger *zap.Logger, datastoreClient *datastore.Client) {
+// On shutdown, the server keeps serving for drainDelay after readiness starts to fail.
+func startServer(router *gin.Engine, logger *zap.Logger, datastoreClient *datastore.Client, drainDelay time.Duration) {
 	server := createServer(router, logger)
 
+	// Serve the metrics and the probes on their own listeners, if they are configured.
+	stopMetrics := startMetricsServer(logger)
+	defer stopMetrics()
+	stopProbes := startProbeServer(logger)
+	defer stopProbes()
+
+	// Periodically release the rate limiters of clients that have gone idle.
+	stopJanitor := handlers.StartRateLimiterJanitor(time.Minute)
+	defer stopJanitor()
+
+	// Periodically purge the deleted links whose retention period is over.
+	stopPurge := handlers.StartPurgeJob(datastoreClient)
+	defer stopPurge()
+
+	// Deliver webhook events in the background, retrying those that fail.
+	stopWebhooks := handlers.StartWebhookDispatcher(datastoreClient)
+	defer stopWebhooks()
+
+	// Change the log levels on signals, for instances that cannot be reached with an API key.
+	stopLevelSignals := handleLevelSignals(logger)
+	defer stopLevelSignals()
+
 	go runServer(server, logger)
 
 	// Wait for interrupt signal to gracefully shut down the server
-	waitForShutdownSignal(server, logger)
+	waitForShutdownSignal(server, logger, drainDelay)
 
 	// Close any other resources such as the datastore client
 	cleanupResources(logger, datastoreClient)
@@ -160,6 +383,53 @@ func createServer(router *gin.Engine, logger *zap.Logger) *http.Server {
 	return server
 }
 
+// startMetricsServer serves the Prometheus metrics of the service on METRICS_ADDR, apart from the public
+// listener, so that they are only reachable from where the scraper runs. It returns a function that stops
+// the listener, which does nothing if METRICS_ADDR is not set.
+func startMetricsServer(logger *zap.Logger) (stop func()) {
+	mux := http.NewServeMux()
+	mux.Handle(metrics.PathMetrics, metrics.Handler())
+	return startSideServer(logger, "startMetricsServer", os.Getenv(metrics.METRICS_ADDR), mux,
+		constant.MetricsServerStartContextLog, constant.MetricsServerFailContextLog)
+}
+
+// startProbeServer serves the liveness and readiness probes on PROBE_ADDR, apart from the public listener,
+// so that they are only reachable from where the orchestrator runs. It returns a function that stops the
+// listener, which does nothing if PROBE_ADDR is not set, in which case the probes are served by the router.
+func startProbeServer(logger *zap.Logger) (stop func()) {
+	mux := http.NewServeMux()
+	mux.Handle(health.PathHealthz, health.LivenessHandler())
+	mux.Handle(health.PathReadyz, health.ReadinessHandler())
+	return startSideServer(logger, "startProbeServer", os.Getenv(health.PROBE_ADDR), mux,
+		constant.ProbeServerStartContextLog, constant.ProbeServerFailContextLog)
+}
+
+// startSideServer serves handler on addr in the background, next to the public listener. It returns a
+// function that stops the listener, which does nothing if addr is empty.
+func startSideServer(logger *zap.Logger, operation, addr string, handler http.Handler, startLog, failLog string) (stop func()) {
+	if addr == "" {
+		return func() {}
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logFields := logmonitor.CreateLogFields(operation,
+		logmonitor.WithComponent(constant.ComponentGopher),
+	)
+	logger.Info(constant.InfoEmoji+"  "+startLog+" "+addr, logFields...)
+	go func() {
+		// A broken side listener must not take the service down, so the failure is only logged.
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error(constant.WarningEmoji+"  "+failLog, append(logFields, logmonitor.WithError(err)())...)
+		}
+	}()
+	return func() { server.Close() }
+}
+
 // getServerPort retrieves the server port from the environment variable or defaults to "8080".
 func getServerPort() string {
 	port := os.Getenv("PORT")
@@ -195,11 +465,67 @@ func runServer(server *http.Server, logger *zap.Logger) {
 	}
 }
 
+// handleLevelSignals changes the log level of every package when the process receives levelDebugSignal
+// or levelResetSignal. It returns a function that stops handling them.
+func handleLevelSignals(logger *zap.Logger) (stop func()) {
+	if levelDebugSignal == nil {
+		return func() {}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, levelDebugSignal, levelResetSignal)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case s := <-signals:
+				logFields := logmonitor.CreateLogFields("handleLevelSignals",
+					logmonitor.WithComponent(constant.ComponentGopher),
+					logmonitor.WithSignal(s),
+				)
+				if s == levelResetSignal {
+					logmonitor.ResetLevels()
+					logger.Warn(constant.SignalSatelliteEmoji+"  "+constant.LogLevelsResetContextLog, logFields...)
+					continue
+				}
+				// The error is ignored, as every package is always known.
+				_ = logmonitor.SetLevel("", zapcore.DebugLevel, logmonitor.DefaultLevelDuration)
+				logger.Warn(constant.SignalSatelliteEmoji+"  "+constant.LogLevelChangedContextLog, append(logFields,
+					zap.Stringer("level", zapcore.DebugLevel),
+					zap.Duration("revert_after", logmonitor.DefaultLevelDuration),
+				)...)
+			case <-done:
+				signal.Stop(signals)
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// shutdownTimeout is the time allowed for the shutdown, including the drain delay.
+const shutdownTimeout = 10 * time.Second
+
+// shutdownDrainDelay reads SHUTDOWN_DRAIN_DELAY, which must leave time within shutdownTimeout for the
+// in-flight requests to complete.
+func shutdownDrainDelay() (time.Duration, error) {
+	delay, err := health.DrainDelayFromEnv()
+	if err != nil {
+		return 0, err
+	}
+	if delay >= shutdownTimeout {
+		return 0, fmt.Errorf("%s %s %s", health.SHUTDOWN_DRAIN_DELAY, constant.DrainDelayTooLongContextLog, shutdownTimeout)
+	}
+	return delay, nil
+}
+
 // waitForShutdownSignal blocks until a SIGINT or SIGTERM signal is received, then shuts down the server.
+// Readiness fails first, and the server keeps serving for drainDelay, so that load balancers stop routing
+// new traffic to it before it stops accepting connections.
 // Note: This function can be ignored if you're using a managed service, such as Google Cloud Run. In such
 // environments, Google Cloud Run (on top of Knative) sends a SIGTERM signal and manages the shutdown process for you. Therefore, the
 // managed service continues to handle all operational aspects, indicating that the service is running within Google Cloud Run.
-func waitForShutdownSignal(server *http.Server, logger *zap.Logger) {
+func waitForShutdownSignal(server *http.Server, logger *zap.Logger, drainDelay time.Duration) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	// Testing human readable logging
@@ -212,7 +538,16 @@ func waitForShutdownSignal(server *http.Server, logger *zap.Logger) {
 	// Log the reception of the shutdown signal.
 	logger.Info(constant.SignalSatelliteEmoji+"  "+constant.SignalContextLog, logFields...)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Fail readiness right away, and keep serving until the load balancers have noticed, so that no new
+	// traffic is routed here once the listener is closed.
+	health.Drain()
+	if drainDelay > 0 {
+		logger.Info(constant.DrainingContextLog, append(logFields, zap.Duration("delay", drainDelay))...)
+		time.Sleep(drainDelay)
+	}
+
+	// The drain delay is part of the shutdown budget, which orchestrators enforce as a whole.
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout-drainDelay)
 	defer cancel()
 
 	logger.Info("Shutting down server...")
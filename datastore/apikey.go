@@ -0,0 +1,125 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	cloudDatastore "cloud.google.com/go/datastore"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+// APIKey represents a named API key used to authenticate management requests.
+// Only a hash of the secret part of the key is stored; the plaintext key is never persisted.
+type APIKey struct {
+	Name      string    `datastore:"name" json:"name"`                     // The unique name of the key, attached to logs and link ownership.
+	Hash      string    `datastore:"hash,noindex" json:"-"`                // The hex-encoded SHA-256 hash of the key secret.
+	Scopes    []string  `datastore:"scopes" json:"scopes"`                 // The operations the key is allowed to perform.
+	CreatedAt time.Time `datastore:"created_at" json:"created_at"`         // When the key was created.
+	ExpiresAt time.Time `datastore:"expires_at" json:"expires_at"`         // When the key expires; the zero value means it never expires.
+	Revoked   bool      `datastore:"revoked" json:"revoked"`               // Whether the key has been revoked.
+	RevokedAt time.Time `datastore:"revoked_at,noindex" json:"revoked_at"` // When the key was revoked, if it was.
+}
+
+// MarshalJSON encodes the API key with expires_at and revoked_at left out while they are zero,
+// the same way as URL.MarshalJSON.
+func (k APIKey) MarshalJSON() ([]byte, error) {
+	type plainAPIKey APIKey
+	return json.Marshal(struct {
+		plainAPIKey
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	}{plainAPIKey(k), optionalTime(k.ExpiresAt), optionalTime(k.RevokedAt)})
+}
+
+// IsExpired reports whether the API key has an expiry that lies before the given time.
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && now.After(k.ExpiresAt)
+}
+
+// InsertAPIKey saves a new API key to Datastore under the Kind 'apikeyz'.
+// It returns ErrAlreadyExists if a key with the same name already exists.
+func InsertAPIKey(ctx context.Context, client *Client, apiKey *APIKey) error {
+	key := cloudDatastore.NameKey(DataStoreAPIKeyNameKey, apiKey.Name, nil)
+	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		existing := new(APIKey)
+		if err := tx.Get(key, existing); err != cloudDatastore.ErrNoSuchEntity {
+			if err == nil {
+				return ErrAlreadyExists
+			}
+			return err
+		}
+
+		_, err := tx.Put(key, apiKey)
+		return err
+	})
+
+	if err != nil && err != ErrAlreadyExists {
+		logError(ctx, DataStoreFailedtoSaveAPIKey, zap.String("name", apiKey.Name), zap.Error(err))
+	}
+	return err
+}
+
+// GetAPIKey retrieves an API key by its name from Datastore.
+// The function returns ErrNotFound if no key with the given name exists.
+func GetAPIKey(ctx context.Context, client *Client, name string) (*APIKey, error) {
+	key := cloudDatastore.NameKey(DataStoreAPIKeyNameKey, name, nil)
+	apiKey := new(APIKey)
+	if err := client.Get(ctx, key, apiKey); err != nil {
+		if err == cloudDatastore.ErrNoSuchEntity {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+// RevokeAPIKey marks an API key as revoked within a transaction, and returns the key as it was before
+// and after the revocation. Revoked keys are kept so that their names remain reserved and visible in listings.
+func RevokeAPIKey(ctx context.Context, client *Client, name string) (before, after *APIKey, err error) {
+	key := cloudDatastore.NameKey(DataStoreAPIKeyNameKey, name, nil)
+	_, err = client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		apiKey := new(APIKey)
+		if err := tx.Get(key, apiKey); err != nil {
+			if err == cloudDatastore.ErrNoSuchEntity {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		stored := *apiKey
+		apiKey.Revoked = true
+		apiKey.RevokedAt = time.Now().UTC()
+		if _, err := tx.Put(key, apiKey); err != nil {
+			return err
+		}
+		before, after = &stored, apiKey
+		return nil
+	})
+
+	if err != nil {
+		if err != ErrNotFound {
+			logError(ctx, DataStoreFailedtoRevokeAPIKey, zap.String("name", name), zap.Error(err))
+		}
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// ListAPIKeys retrieves every API key from Datastore.
+func ListAPIKeys(ctx context.Context, client *Client) ([]*APIKey, error) {
+	var apiKeys []*APIKey
+	it := client.Run(ctx, cloudDatastore.NewQuery(DataStoreAPIKeyNameKey))
+	for {
+		apiKey := new(APIKey)
+		_, err := it.Next(apiKey)
+		if err == iterator.Done {
+			return apiKeys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+}
@@ -0,0 +1,112 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	cloudDatastore "cloud.google.com/go/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/audit"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+// auditRecord is the stored form of an audit event. The resources before and after the change are
+// kept as unindexed JSON, as they can exceed the size limit of indexed values.
+type auditRecord struct {
+	Time      time.Time `datastore:"time"`
+	Principal string    `datastore:"principal"`
+	Operation string    `datastore:"operation"`
+	ID        string    `datastore:"id"`
+	Before    []byte    `datastore:"before,noindex"`
+	After     []byte    `datastore:"after,noindex"`
+	ClientIP  string    `datastore:"client_ip"`
+	RequestID string    `datastore:"request_id"`
+	Outcome   string    `datastore:"outcome"`
+	Status    int       `datastore:"status,noindex"`
+}
+
+// AuditSink stores audit events in Datastore under the Kind 'auditz'. Each event gets its own entity
+// with an ID allocated by Datastore, so events are only ever added.
+type AuditSink struct {
+	client *Client
+}
+
+// NewAuditSink creates an audit sink that stores events with the given client.
+func NewAuditSink(client *Client) *AuditSink {
+	return &AuditSink{client: client}
+}
+
+// Write stores the event as a new entity.
+func (s *AuditSink) Write(ctx context.Context, event audit.Event) error {
+	record := &auditRecord{
+		Time:      event.Time,
+		Principal: event.Principal,
+		Operation: event.Operation,
+		ID:        event.ID,
+		Before:    event.Before,
+		After:     event.After,
+		ClientIP:  event.ClientIP,
+		RequestID: event.RequestID,
+		Outcome:   string(event.Outcome),
+		Status:    event.Status,
+	}
+	_, err := s.client.Put(ctx, cloudDatastore.IncompleteKey(DataStoreAuditNameKey, nil), record)
+	if err != nil {
+		logError(ctx, DataStoreFailedtoSaveAuditEvent, zap.String("operation", event.Operation), zap.Error(err))
+	}
+	return err
+}
+
+// Query retrieves a page of the events in the time range of the filter, oldest first.
+func (s *AuditSink) Query(ctx context.Context, filter audit.Filter) (*audit.Page, error) {
+	query := cloudDatastore.NewQuery(DataStoreAuditNameKey).Order("time")
+	if !filter.Since.IsZero() {
+		query = query.FilterField("time", ">=", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.FilterField("time", "<", filter.Until)
+	}
+	if filter.Cursor != "" {
+		start, err := cloudDatastore.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, &audit.InvalidCursorError{Cursor: filter.Cursor}
+		}
+		query = query.Start(start)
+	}
+
+	page := &audit.Page{Events: []audit.Event{}}
+	it := s.client.Run(ctx, query.Limit(filter.Limit))
+	for {
+		var record auditRecord
+		_, err := it.Next(&record)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			logError(ctx, DataStoreFailedtoListAuditEvents, zap.Error(err))
+			return nil, err
+		}
+		page.Events = append(page.Events, audit.Event{
+			Time:      record.Time,
+			Principal: record.Principal,
+			Operation: record.Operation,
+			ID:        record.ID,
+			Before:    record.Before,
+			After:     record.After,
+			ClientIP:  record.ClientIP,
+			RequestID: record.RequestID,
+			Outcome:   audit.Outcome(record.Outcome),
+			Status:    record.Status,
+		})
+	}
+
+	if len(page.Events) < filter.Limit {
+		return page, nil
+	}
+	next, err := it.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = next.String()
+	return page, nil
+}
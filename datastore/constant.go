@@ -8,19 +8,58 @@ package datastore
 //
 // Note: some constants are not used in the code, indicate that for future use.
 const (
-	DataStoreNosuchentity         = "datastore: no such entity"
-	DataStoreFailedtoCreateClient = "Failed to create client"
-	DataStoreFailedtoSaveURL      = "Failed to save URL"
-	DataStoreFailedtoGetURL       = "Failed to get URL"
-	DataStoreFailedtoUpdateURL    = "Failed to update URL"
-	DataStoreFailedtoDeleteURL    = "Failed to delete URL"
-	DataStoreFailedToCloseClient  = "Failed to close client"
-	DataStoreAuthInvalidToken     = "reauthentication required due to invalid token."
+	DataStoreNosuchentity                = "datastore: no such entity"
+	DataStoreFailedtoCreateClient        = "Failed to create client"
+	DataStoreFailedtoSaveURL             = "Failed to save URL"
+	DataStoreFailedtoGetURL              = "Failed to get URL"
+	DataStoreFailedtoUpdateURL           = "Failed to update URL"
+	DataStoreFailedtoDeleteURL           = "Failed to delete URL"
+	DataStoreFailedtoListURL             = "Failed to list URLs"
+	DataStoreFailedtoPurgeURLs           = "Failed to purge deleted URLs"
+	DataStoreFailedtoSaveAPIKey          = "Failed to save API key"
+	DataStoreFailedtoRevokeAPIKey        = "Failed to revoke API key"
+	DataStoreFailedtoSaveIdempotencyKey  = "Failed to save idempotency key"
+	DataStoreFailedtoSaveAuditEvent      = "Failed to save audit event"
+	DataStoreFailedtoListAuditEvents     = "Failed to list audit events"
+	DataStoreFailedtoSaveWebhook         = "Failed to save webhook subscription"
+	DataStoreFailedtoSaveWebhookDelivery = "Failed to save webhook delivery"
+	DataStoreEntityAlreadyExists         = "datastore: entity already exists"
+	DataStoreFailedToCloseClient         = "Failed to close client"
+	DataStoreAuthInvalidToken            = "reauthentication required due to invalid token."
+
+	// maxBatchSize is the maximum number of entities Datastore accepts in one batch operation.
+	maxBatchSize = 500
 
 	// DataStoreNameKey is the name of the Kind in Datastore for URL entities.
 	// Defining it here enables changing the Kind name in one place if needed.
 	DataStoreNameKey = "urlz"
 
+	// DataStoreAPIKeyNameKey is the name of the Kind in Datastore for API key entities.
+	DataStoreAPIKeyNameKey = "apikeyz"
+
+	// DataStoreHistoryNameKey is the name of the Kind in Datastore for the revisions of URL entities.
+	DataStoreHistoryNameKey = "urlz_history"
+
+	// DataStoreAuditNameKey is the name of the Kind in Datastore for audit events.
+	DataStoreAuditNameKey = "auditz"
+
+	// DataStoreWebhookNameKey is the name of the Kind in Datastore for webhook subscriptions.
+	DataStoreWebhookNameKey = "webhookz"
+
+	// DataStoreWebhookDeliveryNameKey is the name of the Kind in Datastore for webhook deliveries.
+	DataStoreWebhookDeliveryNameKey = "webhook_deliveryz"
+
+	// DataStoreWebhookStateNameKey is the name of the Kind in Datastore for the progress of webhook jobs,
+	// and DataStoreWebhookStateExpiry names the entity tracking the expiry checks.
+	DataStoreWebhookStateNameKey = "webhook_statez"
+	DataStoreWebhookStateExpiry  = "expiry"
+
+	// DataStoreClickNameKey is the name of the Kind in Datastore for the click counts of URL entities.
+	DataStoreClickNameKey = "clickz"
+
+	// DataStoreIdempotencyNameKey is the name of the Kind in Datastore for the records of idempotency keys.
+	DataStoreIdempotencyNameKey = "idempotencyz"
+
 	// URL Info Messages
 	InfoAttemptingToUpdateURLInDatastore = "Attempting to update URL in Datastore"
 	InfoFailedToUpdateURLInDatastore     = "Failed to update URL in Datastore"
@@ -41,4 +80,9 @@ const (
 // Define operation constants.
 const (
 	operation_CreateDatastoreClient = "CreateDatastoreClient"
+	operation_GetURL                = "GetURL"
+	operation_SaveURL               = "SaveURL"
+	operation_InsertURL             = "InsertURL"
+	operation_MutateURL             = "MutateURL"
+	operation_DeleteURL             = "DeleteURL"
 )
@@ -6,12 +6,16 @@
 // # Types
 //
 //   - Client: Wraps the Google Cloud Datastore client and provides methods for URL entity management.
-//   - URL: Represents a URL entity within the datastore with fields for the original URL and a unique identifier.
+//   - URL: Represents a URL entity within the datastore with fields for the original URL, a unique identifier, its owner, an optional password hash, its quarantine state and the hash of its canonical form.
+//   - URLPage: A page of URL entities along with the cursor of the next page.
+//   - IdempotencyRecord: The stored response to a request made with an Idempotency-Key header.
+//   - APIKey: Represents a named API key with its hashed secret, scopes, expiry and revocation state.
 //   - DatastoreError: Represents structured errors from the Datastore client, including an error code, description, and optional details or URL.
 //
 // # Variables
 //
 //   - ErrNotFound: An error representing the absence of a URL entity in the datastore.
+//   - ErrAlreadyExists: An error representing a conflict with an existing URL entity that has the same ID.
 //   - Logger: A package-level variable for consistent logging. It should be set using SetLogger before using logging functions.
 //
 // # Handler Functions
@@ -19,9 +23,26 @@
 // The package offers functions for datastore operations:
 //   - CreateDatastoreClient: Initializes and returns a new datastore client.
 //   - SaveURL: Saves a URL entity to the datastore.
+//   - InsertURL: Saves a URL entity only if its ID is not already taken, returning ErrAlreadyExists otherwise.
+//   - ListURLs: Streams every URL entity to a callback, for example to export them.
+//   - ListURLsByOwner, ListURLsPage: Retrieve URL entities page by page, for one owner or for everyone.
 //   - GetURL: Retrieves a URL entity from the datastore by ID.
+//   - FindURLByCanonicalHash: Retrieves the URL entity of an owner for a canonical destination.
 //   - UpdateURL: Updates an existing URL entity in the datastore.
+//   - MutateURL: Applies a function to an existing URL entity within a transaction, recording the change in its history.
+//   - ListURLRevisions, GetURLRevision: Retrieve the history of a URL entity, stored under the Kind 'urlz_history'.
 //   - DeleteURL: Deletes a URL entity from the datastore by ID.
+//   - SoftDeleteURL: Marks a URL entity as deleted, keeping it so that it can be restored and its ID is not reused.
+//   - PurgeDeletedURLs: Permanently deletes the URL entities deleted before a given time, along with their history and click counts.
+//   - InsertAPIKey, GetAPIKey, RevokeAPIKey, ListAPIKeys: Manage named API keys under the Kind 'apikeyz'.
+//   - NewAuditSink: Creates an audit.Sink that stores audit events under the Kind 'auditz' and can query them.
+//   - InsertWebhookSubscription, GetWebhookSubscription, ListWebhookSubscriptions, DeleteWebhookSubscription:
+//     Manage webhook subscriptions under the Kind 'webhookz'.
+//   - EnqueueWebhookDeliveries, ListDueWebhookDeliveries, ClaimWebhookDelivery, GetWebhookDelivery,
+//     SaveWebhookDelivery, ListWebhookDeliveries: Manage the queue of webhook deliveries under the Kind 'webhook_deliveryz'.
+//   - AddURLClicks: Adds to the click count of a URL entity, stored under the Kind 'clickz'.
+//   - ClaimExpiredWindow, ListURLsExpiredBetween, CompleteExpiredWindow: Find the URL entities that expired since the last check, recorded under the Kind 'webhook_statez'.
+//   - ClaimIdempotencyKey, CompleteIdempotencyKey, ReleaseIdempotencyKey: Manage idempotency records under the Kind 'idempotencyz'.
 //   - CloseClient: Closes the datastore client and releases resources.
 //   - ParseDatastoreClientError: Parses errors from the Datastore client into a structured format.
 //
@@ -70,6 +91,9 @@
 // The CreateContext function is provided for creating new contexts for datastore operations,
 // allowing for request lifetime control and value passing across API boundaries.
 //
+// GetURL, SaveURL, InsertURL, MutateURL and DeleteURL record an OpenTelemetry span as a child of the
+// span carried by the context, so passing the request context ties them to the trace of the request.
+//
 // # Cleanup
 //
 // It is important to close the datastore client with CloseClient to release resources.
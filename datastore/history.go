@@ -0,0 +1,135 @@
+package datastore
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"time"
+
+	cloudDatastore "cloud.google.com/go/datastore"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+// Define the operations recorded in the history of a URL.
+const (
+	OperationEdit     = "edit"
+	OperationPatch    = "patch"
+	OperationDelete   = "delete"
+	OperationRestore  = "restore"
+	OperationRollback = "rollback"
+)
+
+// Change describes who changes a URL entity and how, for its history.
+type Change struct {
+	Actor      string // The name of the principal making the change.
+	Operation  string // One of the Operation constants.
+	RollbackTo int64  // The revision restored by a rollback.
+}
+
+// URLRevision records one change of a URL entity, with the state of the URL before and after it.
+// Revisions are stored under the Kind 'urlz_history' as children of the URL entity, keyed by their number.
+type URLRevision struct {
+	Revision   int64     `datastore:"revision" json:"revision"`                         // The revision of the URL after the change, starting at 1.
+	Operation  string    `datastore:"operation,noindex" json:"operation"`               // What kind of change was made.
+	Actor      string    `datastore:"actor" json:"actor,omitempty"`                     // The name of the principal that made the change.
+	ChangedAt  time.Time `datastore:"changed_at" json:"changed_at"`                     // When the change was made.
+	RollbackTo int64     `datastore:"rollback_to,noindex" json:"rollback_to,omitempty"` // The revision restored, for rollbacks.
+	Before     URL       `datastore:"before,noindex" json:"before"`                     // The URL before the change.
+	After      URL       `datastore:"after,noindex" json:"after"`                       // The URL after the change.
+}
+
+// URLRevisionPage is a page of revisions, oldest first, along with the cursor of the next page.
+type URLRevisionPage struct {
+	Revisions  []*URLRevision `json:"revisions"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// clone returns a copy of the URL that does not share its tags.
+func (u *URL) clone() URL {
+	c := *u
+	c.Tags = slices.Clone(u.Tags)
+	return c
+}
+
+// putWithRevision stores the changed URL and its revision within the transaction. Nothing is written if
+// the change left the URL as it was.
+func putWithRevision(tx *cloudDatastore.Transaction, key *cloudDatastore.Key, before URL, after *URL, change Change) error {
+	if reflect.DeepEqual(before, *after) {
+		return nil
+	}
+
+	after.Revision = before.Revision + 1
+	revision := &URLRevision{
+		Revision:   after.Revision,
+		Operation:  change.Operation,
+		Actor:      change.Actor,
+		ChangedAt:  time.Now(),
+		RollbackTo: change.RollbackTo,
+		Before:     before,
+		After:      *after,
+	}
+	if _, err := tx.Put(key, after); err != nil {
+		return err
+	}
+	_, err := tx.Put(revisionKey(key, after.Revision), revision)
+	return err
+}
+
+// revisionKey returns the key of a revision of the URL entity with the given key.
+func revisionKey(urlKey *cloudDatastore.Key, revision int64) *cloudDatastore.Key {
+	return cloudDatastore.IDKey(DataStoreHistoryNameKey, revision, urlKey)
+}
+
+// GetURLRevision retrieves one revision of a URL entity.
+// The function returns ErrNotFound if the revision does not exist.
+func GetURLRevision(ctx context.Context, client *Client, id string, revision int64) (*URLRevision, error) {
+	key := revisionKey(cloudDatastore.NameKey(DataStoreNameKey, id, nil), revision)
+	rev := new(URLRevision)
+	if err := client.Get(ctx, key, rev); err != nil {
+		if err == cloudDatastore.ErrNoSuchEntity {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return rev, nil
+}
+
+// ListURLRevisions retrieves a page of the history of a URL entity, oldest revision first.
+// Pass the NextCursor of the previous page as cursor to continue, or an empty string to start.
+func ListURLRevisions(ctx context.Context, client *Client, id string, limit int, cursor string) (*URLRevisionPage, error) {
+	// Revisions are keyed by their number, so the default key order of an ancestor query is chronological.
+	query := cloudDatastore.NewQuery(DataStoreHistoryNameKey).Ancestor(cloudDatastore.NameKey(DataStoreNameKey, id, nil))
+	if cursor != "" {
+		start, err := cloudDatastore.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Start(start)
+	}
+
+	page := &URLRevisionPage{Revisions: []*URLRevision{}}
+	it := client.Run(ctx, query.Limit(limit))
+	for {
+		rev := new(URLRevision)
+		_, err := it.Next(rev)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			logError(ctx, DataStoreFailedtoListURL, zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		page.Revisions = append(page.Revisions, rev)
+	}
+
+	if len(page.Revisions) < limit {
+		return page, nil
+	}
+	next, err := it.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = next.String()
+	return page, nil
+}
@@ -0,0 +1,115 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	cloudDatastore "cloud.google.com/go/datastore"
+	"go.uber.org/zap"
+)
+
+// IdempotencyRecord stores the response to a request made with an Idempotency-Key header, so that
+// retries of the same request receive the same response instead of repeating its effects.
+// A record whose StatusCode is zero belongs to a request that is still being processed, and holds the key
+// until PendingUntil, after which the request is presumed lost and the key can be claimed again.
+type IdempotencyRecord struct {
+	Name         string    `datastore:"name" json:"name"`                           // The key of the record, scoped to the principal that sent it.
+	RequestHash  string    `datastore:"request_hash,noindex" json:"-"`              // The hash of the request, to detect reuse with a different request.
+	StatusCode   int       `datastore:"status_code,noindex" json:"-"`               // The status code of the stored response.
+	Body         []byte    `datastore:"body,noindex" json:"-"`                      // The body of the stored response.
+	CreatedAt    time.Time `datastore:"created_at" json:"created_at"`               // When the request was first received.
+	PendingUntil time.Time `datastore:"pending_until,noindex" json:"pending_until"` // When a record that is still pending can be claimed again.
+	ExpiresAt    time.Time `datastore:"expires_at" json:"expires_at"`               // When the record stops being replayed; suitable for a Datastore TTL policy.
+}
+
+// IsCompleted reports whether the response to the request has been stored.
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.StatusCode != 0
+}
+
+// holdsKey reports whether the record still prevents the key from being claimed at the given time:
+// it has not expired, and it is either completed or its request is still within its lease.
+func (r *IdempotencyRecord) holdsKey(now time.Time) bool {
+	if !now.Before(r.ExpiresAt) {
+		return false
+	}
+	return r.IsCompleted() || now.Before(r.PendingUntil)
+}
+
+// isClaimOf reports whether the stored record is the claim made with record, and not a later claim
+// of the same key made after the lease of record ran out. Datastore keeps timestamps to the microsecond.
+func (r *IdempotencyRecord) isClaimOf(record *IdempotencyRecord) bool {
+	return r.RequestHash == record.RequestHash && r.CreatedAt.Equal(record.CreatedAt.Truncate(time.Microsecond))
+}
+
+// ClaimIdempotencyKey saves a pending record under the Kind 'idempotencyz', unless a record with the same
+// name still holds the key, in which case that record is returned and nothing is written. Expired records,
+// and pending records whose lease ran out, are replaced.
+// The function returns a nil record when the key was claimed by this call.
+func ClaimIdempotencyKey(ctx context.Context, client *Client, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	key := cloudDatastore.NameKey(DataStoreIdempotencyNameKey, record.Name, nil)
+	var existing *IdempotencyRecord
+	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		existing = new(IdempotencyRecord)
+		err := tx.Get(key, existing)
+		if err == nil && existing.holdsKey(record.CreatedAt) {
+			return nil
+		}
+		if err != nil && err != cloudDatastore.ErrNoSuchEntity {
+			return err
+		}
+
+		// The key is new, or its record expired or was abandoned and can be replaced.
+		existing = nil
+		_, err = tx.Put(key, record)
+		return err
+	})
+
+	if err != nil {
+		logError(ctx, DataStoreFailedtoSaveIdempotencyKey, zap.String("name", record.Name), zap.Error(err))
+		return nil, err
+	}
+	return existing, nil
+}
+
+// CompleteIdempotencyKey stores the response of a claimed record, so that it is replayed from now on.
+// Nothing is written if the key was claimed again after the lease of the record ran out.
+func CompleteIdempotencyKey(ctx context.Context, client *Client, record *IdempotencyRecord) error {
+	return updateIdempotencyClaim(ctx, client, record, func(tx *cloudDatastore.Transaction, key *cloudDatastore.Key) error {
+		_, err := tx.Put(key, record)
+		return err
+	})
+}
+
+// ReleaseIdempotencyKey deletes a claimed record, so that the request can be retried with the same key.
+// It is used when the request failed without an outcome worth replaying. Nothing is deleted if the key
+// was claimed again after the lease of the record ran out.
+func ReleaseIdempotencyKey(ctx context.Context, client *Client, record *IdempotencyRecord) error {
+	return updateIdempotencyClaim(ctx, client, record, func(tx *cloudDatastore.Transaction, key *cloudDatastore.Key) error {
+		return tx.Delete(key)
+	})
+}
+
+// updateIdempotencyClaim applies update in a transaction if the stored record is still the claim made with record.
+func updateIdempotencyClaim(ctx context.Context, client *Client, record *IdempotencyRecord, update func(*cloudDatastore.Transaction, *cloudDatastore.Key) error) error {
+	key := cloudDatastore.NameKey(DataStoreIdempotencyNameKey, record.Name, nil)
+	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		var stored IdempotencyRecord
+		if err := tx.Get(key, &stored); err != nil {
+			if err == cloudDatastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		if !stored.isClaimOf(record) {
+			return nil
+		}
+		return update(tx, key)
+	})
+
+	if err != nil {
+		logError(ctx, DataStoreFailedtoSaveIdempotencyKey, zap.String("name", record.Name), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package datastore
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// observeCall is a gRPC interceptor that records the latency of every call made by the Datastore client,
+// and counts the calls that failed, by operation. The operation is the name of the RPC, such as "Lookup",
+// "RunQuery" or "Commit", so that calls made by every function of this package are covered.
+func observeCall(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	operation := path.Base(method)
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	metrics.DatastoreCallDuration.Observe(time.Since(start).Seconds(), operation)
+	if err != nil {
+		metrics.DatastoreErrors.Inc(operation, status.Code(err).String())
+	}
+	return err
+}
@@ -6,11 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	cloudDatastore "cloud.google.com/go/datastore"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 )
 
 // Client wraps the cloudDatastore.Client to abstract away the underlying implementation.
@@ -22,8 +27,75 @@ type Client struct {
 // URL represents a shortened URL with its original URL and a unique identifier.
 // The struct tags specify how each field is stored in the datastore.
 type URL struct {
-	Original string `datastore:"original"` // The original URL.
-	ID       string `datastore:"id"`       // The unique identifier for the shortened URL.
+	Original string `datastore:"original" json:"original"`     // The original URL.
+	ID       string `datastore:"id" json:"id"`                 // The unique identifier for the shortened URL.
+	Owner    string `datastore:"owner" json:"owner,omitempty"` // The name of the principal that created the shortened URL.
+
+	// PasswordHash is the bcrypt hash of the password required to follow the link, or empty if the link is public.
+	// It is never serialized to JSON, so it does not leak through listings; admin exports carry it explicitly.
+	PasswordHash string `datastore:"password_hash,noindex" json:"-"`
+
+	// Quarantined is set when a URL scanner flagged the destination as suspicious. Visitors of a quarantined
+	// link are shown a warning page instead of being redirected.
+	Quarantined      bool   `datastore:"quarantined" json:"quarantined,omitempty"`
+	QuarantineReason string `datastore:"quarantine_reason,noindex" json:"quarantine_reason,omitempty"` // Why the link was quarantined.
+
+	// CanonicalHash is the hash of the canonical form of the original URL, indexed to find the existing link
+	// of an owner for a destination. It is only set while canonicalization is enabled and the link has no password.
+	CanonicalHash string `datastore:"canonical_hash" json:"-"`
+
+	ExpiresAt      time.Time `datastore:"expires_at" json:"expires_at"`                             // When the link stops redirecting; the zero value means it never expires.
+	RedirectStatus int       `datastore:"redirect_status,noindex" json:"redirect_status,omitempty"` // The redirect status code; zero means the default 302 Found.
+	Tags           []string  `datastore:"tags" json:"tags,omitempty"`                               // Free-form labels for organizing links.
+	Disabled       bool      `datastore:"disabled" json:"disabled,omitempty"`                       // Whether the link has been switched off by its owner.
+
+	// Revision counts the changes made to the link; each change is recorded as a URLRevision with this number.
+	Revision int64 `datastore:"revision,noindex" json:"revision"`
+
+	// DeletedAt is set when the link is deleted. Deleted links stay in Datastore until they are purged after the
+	// retention period, so they can be restored and their IDs are not handed out again in the meantime.
+	DeletedAt time.Time `datastore:"deleted_at" json:"deleted_at"`
+}
+
+// IsProtected reports whether a password is required to follow the shortened URL.
+func (u *URL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
+// IsDeleted reports whether the shortened URL has been deleted and is waiting to be purged.
+func (u *URL) IsDeleted() bool {
+	return !u.DeletedAt.IsZero()
+}
+
+// IsExpired reports whether the shortened URL has an expiry that lies before the given time.
+func (u *URL) IsExpired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && now.After(u.ExpiresAt)
+}
+
+// IsAvailable reports whether the shortened URL redirects at the given time: it is neither deleted,
+// disabled nor expired.
+func (u *URL) IsAvailable(now time.Time) bool {
+	return !u.IsDeleted() && !u.Disabled && !u.IsExpired(now)
+}
+
+// MarshalJSON encodes the URL with expires_at and deleted_at left out while they are zero, which the
+// omitempty option cannot do for a time.Time. Datastore does not support *time.Time fields, so the
+// struct keeps time.Time and the pointers only exist in the JSON form.
+func (u URL) MarshalJSON() ([]byte, error) {
+	type plainURL URL
+	return json.Marshal(struct {
+		plainURL
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	}{plainURL(u), optionalTime(u.ExpiresAt), optionalTime(u.DeletedAt)})
+}
+
+// optionalTime returns nil for the zero time, so that omitempty leaves it out of JSON.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
 }
 
 // Config holds the configuration settings for the datastore client.
@@ -71,6 +143,10 @@ var Logger *zap.Logger
 // This error is used to signal that a URL entity with the provided ID does not exist.
 var ErrNotFound = errors.New(DataStoreNosuchentity)
 
+// ErrAlreadyExists is the error returned when an entity with the same ID is already present in the datastore.
+// This error is used to signal a conflict, for example when importing URL entities that preserve their IDs.
+var ErrAlreadyExists = errors.New(DataStoreEntityAlreadyExists)
+
 // SetLogger sets the logger instance for the package.
 // This function configures the package-level Logger variable for use throughout the datastore package.
 func SetLogger(logger *zap.Logger) {
@@ -97,7 +173,9 @@ func CreateContext() context.Context {
 // It initializes the connection using the provided context and configuration settings.
 // The function returns a new Client instance or an error if the connection could not be established.
 func CreateDatastoreClient(ctx context.Context, config *Config) (*Client, error) {
-	cloudClient, err := cloudDatastore.NewClient(ctx, config.ProjectID)
+	// Record the latency and the errors of every call to Datastore.
+	cloudClient, err := cloudDatastore.NewClient(ctx, config.ProjectID,
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(observeCall)))
 	if err != nil {
 		// Create structured log fields using logmonitor
 		logFields := logmonitor.CreateLogFields(operation_CreateDatastoreClient,
@@ -115,24 +193,175 @@ func CreateDatastoreClient(ctx context.Context, config *Config) (*Client, error)
 // SaveURL saves a new URL entity to Datastore under the Kind 'urlz'.
 // It uses the provided context and datastore client to save the URL struct to the datastore.
 // The function returns an error if the URL entity could not be saved.
-func SaveURL(ctx context.Context, client *Client, url *URL) error {
+func SaveURL(ctx context.Context, client *Client, url *URL) (err error) {
+	ctx, span := startSpan(ctx, operation_SaveURL, url.ID)
+	defer func() { endSpan(span, err) }()
+
 	key := cloudDatastore.NameKey(DataStoreNameKey, url.ID, nil)
-	_, err := client.Put(ctx, key, url)
+	_, err = client.Put(ctx, key, url)
 	if err != nil {
 		// Use zap logger to log the error for consistent logging.
-		logmonitor.Logger.Error(constant.AlertEmoji+" "+DataStoreFailedtoCreateClient, zap.Error(err))
+		logError(ctx, DataStoreFailedtoCreateClient, zap.Error(err))
 		return err
 	}
 	return nil
 }
 
+// InsertURL saves a new URL entity to Datastore only if no entity with the same ID exists.
+// The existence check and the write are performed within a transaction to ensure the operation is atomic.
+// The function returns ErrAlreadyExists if the ID is already taken.
+func InsertURL(ctx context.Context, client *Client, url *URL) (err error) {
+	ctx, span := startSpan(ctx, operation_InsertURL, url.ID)
+	defer func() { endSpan(span, err) }()
+
+	key := cloudDatastore.NameKey(DataStoreNameKey, url.ID, nil)
+	_, err = client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		existing := new(URL)
+		if err := tx.Get(key, existing); err != cloudDatastore.ErrNoSuchEntity {
+			if err == nil {
+				return ErrAlreadyExists
+			}
+			return err
+		}
+
+		_, err := tx.Put(key, url)
+		return err
+	})
+
+	if err != nil && err != ErrAlreadyExists {
+		logError(ctx, DataStoreFailedtoSaveURL, zap.String("id", url.ID), zap.Error(err))
+	}
+	return err
+}
+
+// ListURLs iterates over every URL entity of the Kind 'urlz' in Datastore.
+// Entities are streamed one by one to the provided callback, so the full set never has to be held in memory.
+// The iteration stops at the first error returned by the datastore or by the callback.
+func ListURLs(ctx context.Context, client *Client, fn func(*URL) error) error {
+	it := client.Run(ctx, cloudDatastore.NewQuery(DataStoreNameKey))
+	for {
+		url := new(URL)
+		_, err := it.Next(url)
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			logError(ctx, DataStoreFailedtoListURL, zap.Error(err))
+			return err
+		}
+		if err := fn(url); err != nil {
+			return err
+		}
+	}
+}
+
+// URLPage is a page of URL entities along with the cursor to pass to retrieve the next page.
+// NextCursor is empty when there are no more entities.
+type URLPage struct {
+	URLs       []*URL `json:"urls"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListURLsByOwner retrieves a page of URL entities owned by the given principal. Deleted URL entities are
+// skipped unless includeDeleted is set, so a page may hold fewer than limit entities and still have a NextCursor.
+// Pass the NextCursor of the previous page as cursor to continue, or an empty string to start.
+func ListURLsByOwner(ctx context.Context, client *Client, owner string, limit int, cursor string, includeDeleted bool) (*URLPage, error) {
+	query := cloudDatastore.NewQuery(DataStoreNameKey).FilterField("owner", "=", owner)
+	return queryURLPage(ctx, client, query, limit, cursor, includeDeleted)
+}
+
+// ListURLsPage retrieves a page of URL entities regardless of their owner. Deleted URL entities are skipped
+// unless includeDeleted is set, so a page may hold fewer than limit entities and still have a NextCursor.
+// Pass the NextCursor of the previous page as cursor to continue, or an empty string to start.
+func ListURLsPage(ctx context.Context, client *Client, limit int, cursor string, includeDeleted bool) (*URLPage, error) {
+	return queryURLPage(ctx, client, cloudDatastore.NewQuery(DataStoreNameKey), limit, cursor, includeDeleted)
+}
+
+// queryURLPage runs the query from the given cursor and collects the URL entities among the next limit results.
+// Deleted URL entities are filtered here rather than in the query, as entities saved before soft deletion
+// existed have no deleted_at property and would not match an equality filter on it.
+func queryURLPage(ctx context.Context, client *Client, query *cloudDatastore.Query, limit int, cursor string, includeDeleted bool) (*URLPage, error) {
+	if cursor != "" {
+		start, err := cloudDatastore.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Start(start)
+	}
+
+	page := &URLPage{URLs: []*URL{}}
+	it := client.Run(ctx, query.Limit(limit))
+	scanned := 0
+	for {
+		url := new(URL)
+		_, err := it.Next(url)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			logError(ctx, DataStoreFailedtoListURL, zap.Error(err))
+			return nil, err
+		}
+		scanned++
+		if url.IsDeleted() && !includeDeleted {
+			continue
+		}
+		page.URLs = append(page.URLs, url)
+	}
+
+	return page, setNextCursor(page, it, scanned, limit)
+}
+
+// setNextCursor records the cursor after the last entity if the query returned a full page, in which case
+// more entities may follow.
+func setNextCursor(page *URLPage, it *cloudDatastore.Iterator, scanned int, limit int) error {
+	if scanned < limit {
+		return nil
+	}
+	next, err := it.Cursor()
+	if err != nil {
+		return err
+	}
+	page.NextCursor = next.String()
+	return nil
+}
+
+// FindURLByCanonicalHash retrieves an available URL entity of the given owner whose canonical hash matches.
+// The function returns ErrNotFound if the owner has no such URL entity that still redirects.
+func FindURLByCanonicalHash(ctx context.Context, client *Client, owner string, hash string) (*URL, error) {
+	query := cloudDatastore.NewQuery(DataStoreNameKey).
+		FilterField("owner", "=", owner).
+		FilterField("canonical_hash", "=", hash)
+
+	// Deleted, disabled and expired links keep their hash, so they are skipped rather than reused.
+	now := time.Now()
+	it := client.Run(ctx, query)
+	for {
+		var url URL
+		_, err := it.Next(&url)
+		if err == iterator.Done {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			logError(ctx, DataStoreFailedtoGetURL, zap.String("canonical_hash", hash), zap.Error(err))
+			return nil, err
+		}
+		if url.IsAvailable(now) {
+			return &url, nil
+		}
+	}
+}
+
 // GetURL retrieves a URL entity by its ID from Datastore.
 // It uses the provided context and datastore client to look up the URL entity by its unique identifier.
 // The function returns the found URL entity or an error if the entity could not be retrieved.
-func GetURL(ctx context.Context, dsClient *Client, id string) (*URL, error) {
+func GetURL(ctx context.Context, dsClient *Client, id string) (_ *URL, err error) {
+	ctx, span := startSpan(ctx, operation_GetURL, id)
+	defer func() { endSpan(span, err) }()
+
 	key := cloudDatastore.NameKey(DataStoreNameKey, id, nil)
 	url := new(URL)
-	err := dsClient.Get(ctx, key, url)
+	err = dsClient.Get(ctx, key, url)
 	if err != nil {
 		if err == cloudDatastore.ErrNoSuchEntity {
 			return nil, ErrNotFound
@@ -144,12 +373,29 @@ func GetURL(ctx context.Context, dsClient *Client, id string) (*URL, error) {
 }
 
 // UpdateURL updates an existing URL entity in Datastore with a new URL.
-// It performs the update within a transaction to ensure the operation is atomic.
-// The function returns an error if the URL entity could not be updated.
-func UpdateURL(ctx context.Context, client *Client, id string, newURL string) error {
+// It performs the update within a transaction to ensure the operation is atomic, and records the change
+// in the history of the URL. The function returns an error if the URL entity could not be updated.
+func UpdateURL(ctx context.Context, client *Client, id string, newURL string, change Change) error {
+	return MutateURL(ctx, client, id, change, func(url *URL) error {
+		// Update the URL's Original field with the new URL.
+		url.Original = newURL
+		return nil
+	})
+}
+
+// MutateURL applies fn to an existing URL entity and stores the result, within a transaction so that
+// concurrent changes are not lost. If fn returns an error, nothing is stored and the error is returned.
+// If fn changed the URL, its revision is incremented and a URLRevision describing the change is written
+// in the same transaction, so the history always matches the entity. The function returns ErrNotFound
+// if the URL entity does not exist.
+func MutateURL(ctx context.Context, client *Client, id string, change Change, fn func(*URL) error) (err error) {
+	ctx, span := startSpan(ctx, operation_MutateURL, id)
+	span.SetAttributes(attribute.String(attributeChange, change.Operation))
+	defer func() { endSpan(span, err) }()
+
 	key := cloudDatastore.NameKey(DataStoreNameKey, id, nil)
 	// Transactionally retrieve the existing URL and update it.
-	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+	_, err = client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
 		url := new(URL)
 		if err := tx.Get(key, url); err != nil {
 			if err == cloudDatastore.ErrNoSuchEntity {
@@ -158,32 +404,113 @@ func UpdateURL(ctx context.Context, client *Client, id string, newURL string) er
 			return err
 		}
 
-		// Update the URL's Original field with the new URL.
-		url.Original = newURL
-		_, err := tx.Put(key, url)
-		return err
+		before := url.clone()
+		if err := fn(url); err != nil {
+			return err
+		}
+		return putWithRevision(tx, key, before, url, change)
+	})
+
+	if err != nil && err != ErrNotFound {
+		logError(ctx, DataStoreFailedtoUpdateURL, zap.String("id", id), zap.Error(err))
+	}
+	return err
+}
+
+// SoftDeleteURL marks a URL entity as deleted at the given time, keeping it in Datastore until it is purged.
+// The canonical hash is cleared so that the deleted link is no longer reused for new requests.
+// The function returns ErrNotFound if the URL entity does not exist.
+func SoftDeleteURL(ctx context.Context, client *Client, id string, actor string, deletedAt time.Time) error {
+	return MutateURL(ctx, client, id, Change{Actor: actor, Operation: OperationDelete}, func(url *URL) error {
+		url.DeletedAt = deletedAt
+		url.CanonicalHash = ""
+		return nil
 	})
+}
 
+// PurgeDeletedURLs permanently deletes the URL entities that were deleted before the given time, along with
+// their history. It returns the number of purged URL entities. An error may leave some of them purged.
+func PurgeDeletedURLs(ctx context.Context, client *Client, before time.Time) (int, error) {
+	query := cloudDatastore.NewQuery(DataStoreNameKey).
+		FilterField("deleted_at", ">", time.Time{}).
+		FilterField("deleted_at", "<", before).
+		KeysOnly()
+	keys, err := client.GetAll(ctx, query, nil)
 	if err != nil {
-		logmonitor.Logger.Error(constant.AlertEmoji+" "+DataStoreFailedtoUpdateURL, zap.String("id", id), zap.Error(err))
-		return err
+		logError(ctx, DataStoreFailedtoPurgeURLs, zap.Error(err))
+		return 0, err
 	}
 
-	return nil
+	purged := 0
+	for _, key := range keys {
+		ok, err := purgeDeletedURL(ctx, client, key, before)
+		if err != nil {
+			logError(ctx, DataStoreFailedtoPurgeURLs, zap.Int("purged", purged), zap.Error(err))
+			return purged, err
+		}
+		if ok {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purgeDeletedURL deletes a URL entity and its history, unless it was restored, or deleted again later, since
+// the query that found it. It reports whether the entity was purged.
+//
+// Each transaction checks the entity again before deleting a batch of at most maxBatchSize entities, so a
+// restore made while a long history is purged stops the purge. Descendants are deleted first, and the
+// entity itself goes with the last batch, so that an interrupted purge never leaves orphaned history behind.
+func purgeDeletedURL(ctx context.Context, client *Client, key *cloudDatastore.Key, before time.Time) (bool, error) {
+	for {
+		var done, purged bool
+		_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+			done, purged = true, false
+			var url URL
+			if err := tx.Get(key, &url); err != nil {
+				if err == cloudDatastore.ErrNoSuchEntity {
+					return nil
+				}
+				return err
+			}
+			if !url.IsDeleted() || !url.DeletedAt.Before(before) {
+				return nil
+			}
+
+			// A kindless ancestor query returns the entity itself first, followed by its descendants.
+			keys, err := client.GetAll(ctx, cloudDatastore.NewQuery("").Ancestor(key).KeysOnly().Transaction(tx), nil)
+			if err != nil {
+				return err
+			}
+			if len(keys) > maxBatchSize {
+				done = false
+				keys = keys[len(keys)-maxBatchSize:]
+			} else {
+				purged = true
+			}
+			return tx.DeleteMulti(keys)
+		})
+		if err != nil || done {
+			return purged, err
+		}
+	}
 }
 
 // DeleteURL deletes a URL entity by its ID from Datastore.
 // It uses the provided context and datastore client to delete the URL entity by its unique identifier.
 // The function returns an error if the entity could not be deleted.
-func DeleteURL(ctx context.Context, client *Client, id string) error {
+func DeleteURL(ctx context.Context, client *Client, id string) (err error) {
+	ctx, span := startSpan(ctx, operation_DeleteURL, id)
+	defer func() { endSpan(span, err) }()
+
 	key := cloudDatastore.NameKey(DataStoreNameKey, id, nil)
-	err := client.Delete(ctx, key)
+	err = client.Delete(ctx, key)
 	if err != nil {
 		if err == cloudDatastore.ErrNoSuchEntity {
 			return ErrNotFound
 		}
 		// Log and handle other possible errors.
-		logmonitor.Logger.Error(constant.AlertEmoji+" "+DataStoreFailedtoUpdateURL, zap.String("id", id), zap.Error(err))
+		logError(ctx, DataStoreFailedtoUpdateURL, zap.String("id", id), zap.Error(err))
 		return err
 	}
 	return nil
@@ -0,0 +1,55 @@
+package datastore
+
+import (
+	"context"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Define the attributes of the spans of datastore operations.
+const (
+	attributeDBSystem    = "db.system"
+	attributeDBOperation = "db.operation"
+	attributeLinkID      = "link.id"
+	attributeChange      = "link.change"
+	dbSystem             = "google_cloud_datastore"
+)
+
+// tracer creates the spans of datastore operations. It follows the tracer provider installed with
+// otel.SetTracerProvider, even when that happens after this package is initialized.
+var tracer = otel.Tracer("github.com/H0llyW00dzZ/go-urlshortner/datastore")
+
+// startSpan starts a span for a datastore operation on the URL entity with the given ID.
+// The span is a child of the span carried by ctx, such as the one of the request being handled.
+func startSpan(ctx context.Context, operation string, id string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "datastore."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String(attributeDBSystem, dbSystem),
+			attribute.String(attributeDBOperation, operation),
+			attribute.String(attributeLinkID, id),
+		),
+	)
+}
+
+// endSpan ends the span of a datastore operation, marking it as failed if err is set. A missing entity
+// is an expected outcome, not a failure, so ErrNotFound is not recorded.
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != ErrNotFound {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// logError logs a failed datastore operation along with the request and trace IDs carried by ctx, so
+// that storage errors can be found from the request or the trace they happened in.
+func logError(ctx context.Context, message string, fields ...zap.Field) {
+	logmonitor.Logger.Error(constant.AlertEmoji+" "+message, append(fields, logmonitor.ContextFields(ctx)...)...)
+}
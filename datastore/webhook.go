@@ -0,0 +1,352 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	cloudDatastore "cloud.google.com/go/datastore"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+// Define the statuses of a webhook delivery.
+const (
+	DeliveryPending   = "pending"   // The delivery is waiting for its next attempt.
+	DeliveryDelivered = "delivered" // The subscriber accepted the delivery.
+	DeliveryDead      = "dead"      // Every attempt failed; the delivery is kept for inspection.
+)
+
+// WebhookSubscription represents an endpoint that receives the events of the given types.
+// The secret signs each delivery, so it is stored as is, but it is never serialized to JSON.
+type WebhookSubscription struct {
+	Name      string    `datastore:"name" json:"name"`             // The unique name of the subscription.
+	URL       string    `datastore:"url,noindex" json:"url"`       // The endpoint the events are posted to.
+	Secret    string    `datastore:"secret,noindex" json:"-"`      // The HMAC secret of the signatures.
+	Events    []string  `datastore:"events" json:"events"`         // The event types the subscription receives.
+	CreatedAt time.Time `datastore:"created_at" json:"created_at"` // When the subscription was created.
+}
+
+// WebhookDelivery is one event queued for one subscription. Deliveries are stored under the Kind
+// 'webhook_deliveryz', keyed by their ID, and are kept after they succeed or are dead-lettered so
+// that their status can be inspected.
+type WebhookDelivery struct {
+	ID             string          `datastore:"id" json:"id"`                                               // The ID of the delivery, sent in the Webhook-Delivery header.
+	Subscription   string          `datastore:"subscription" json:"subscription"`                           // The name of the subscription.
+	EventType      string          `datastore:"event_type" json:"event_type"`                               // The type of the event.
+	Payload        json.RawMessage `datastore:"payload,noindex" json:"payload"`                             // The JSON body of the event.
+	Status         string          `datastore:"status" json:"status"`                                       // One of the Delivery statuses.
+	Attempts       int             `datastore:"attempts,noindex" json:"attempts"`                           // The number of attempts made so far.
+	NextAttemptAt  time.Time       `datastore:"next_attempt_at" json:"next_attempt_at"`                     // When the next attempt is due; zero once the delivery is finished.
+	LastStatusCode int             `datastore:"last_status_code,noindex" json:"last_status_code,omitempty"` // The status code of the last response, if any.
+	LastError      string          `datastore:"last_error,noindex" json:"last_error,omitempty"`             // Why the last attempt failed, if it did.
+	CreatedAt      time.Time       `datastore:"created_at,noindex" json:"created_at"`                       // When the delivery was queued.
+	DeliveredAt    time.Time       `datastore:"delivered_at,noindex" json:"delivered_at"`                   // When the subscriber accepted the delivery.
+}
+
+// MarshalJSON encodes the delivery with next_attempt_at and delivered_at left out while they are zero,
+// the same way as URL.MarshalJSON.
+func (d WebhookDelivery) MarshalJSON() ([]byte, error) {
+	type plainWebhookDelivery WebhookDelivery
+	return json.Marshal(struct {
+		plainWebhookDelivery
+		NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+		DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	}{plainWebhookDelivery(d), optionalTime(d.NextAttemptAt), optionalTime(d.DeliveredAt)})
+}
+
+// WebhookDeliveryPage is a page of deliveries, oldest first, along with the cursor of the next page.
+type WebhookDeliveryPage struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// clickCount is the number of clicks of a link, stored as a child of the URL entity.
+type clickCount struct {
+	Count int64 `datastore:"count,noindex"`
+}
+
+// webhookState records how far the expiry of links has been checked for link.expired events, and until
+// when the replica checking the next period holds it.
+type webhookState struct {
+	ExpiredUntil time.Time `datastore:"expired_until,noindex"`
+	LeasedUntil  time.Time `datastore:"leased_until,noindex"`
+}
+
+// ExpiredWindow is a period in which the expiry of links is checked by one replica, claimed with
+// ClaimExpiredWindow and marked as checked with CompleteExpiredWindow.
+type ExpiredWindow struct {
+	From        time.Time // The end of the last checked period.
+	Until       time.Time // The end of this period.
+	leasedUntil time.Time
+}
+
+// InsertWebhookSubscription saves a new webhook subscription to Datastore under the Kind 'webhookz'.
+// It returns ErrAlreadyExists if a subscription with the same name already exists.
+func InsertWebhookSubscription(ctx context.Context, client *Client, sub *WebhookSubscription) error {
+	key := cloudDatastore.NameKey(DataStoreWebhookNameKey, sub.Name, nil)
+	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		existing := new(WebhookSubscription)
+		if err := tx.Get(key, existing); err != cloudDatastore.ErrNoSuchEntity {
+			if err == nil {
+				return ErrAlreadyExists
+			}
+			return err
+		}
+
+		_, err := tx.Put(key, sub)
+		return err
+	})
+
+	if err != nil && err != ErrAlreadyExists {
+		logError(ctx, DataStoreFailedtoSaveWebhook, zap.String("name", sub.Name), zap.Error(err))
+	}
+	return err
+}
+
+// GetWebhookSubscription retrieves a webhook subscription by its name.
+// The function returns ErrNotFound if no subscription with the given name exists.
+func GetWebhookSubscription(ctx context.Context, client *Client, name string) (*WebhookSubscription, error) {
+	sub := new(WebhookSubscription)
+	if err := client.Get(ctx, cloudDatastore.NameKey(DataStoreWebhookNameKey, name, nil), sub); err != nil {
+		if err == cloudDatastore.ErrNoSuchEntity {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ListWebhookSubscriptions retrieves every webhook subscription, or only those receiving the given
+// event type if it is not empty.
+func ListWebhookSubscriptions(ctx context.Context, client *Client, eventType string) ([]*WebhookSubscription, error) {
+	query := cloudDatastore.NewQuery(DataStoreWebhookNameKey)
+	if eventType != "" {
+		query = query.FilterField("events", "=", eventType)
+	}
+	var subs []*WebhookSubscription
+	if _, err := client.GetAll(ctx, query, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// DeleteWebhookSubscription deletes a webhook subscription. Its pending deliveries are dead-lettered
+// when they are next attempted. The function returns ErrNotFound if the subscription does not exist.
+func DeleteWebhookSubscription(ctx context.Context, client *Client, name string) error {
+	key := cloudDatastore.NameKey(DataStoreWebhookNameKey, name, nil)
+	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		if err := tx.Get(key, new(WebhookSubscription)); err != nil {
+			if err == cloudDatastore.ErrNoSuchEntity {
+				return ErrNotFound
+			}
+			return err
+		}
+		return tx.Delete(key)
+	})
+	return err
+}
+
+// EnqueueWebhookDeliveries stores new deliveries, due immediately.
+func EnqueueWebhookDeliveries(ctx context.Context, client *Client, deliveries []*WebhookDelivery) error {
+	keys := make([]*cloudDatastore.Key, len(deliveries))
+	for i, d := range deliveries {
+		keys[i] = cloudDatastore.NameKey(DataStoreWebhookDeliveryNameKey, d.ID, nil)
+	}
+	for start := 0; start < len(keys); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(keys))
+		if _, err := client.PutMulti(ctx, keys[start:end], deliveries[start:end]); err != nil {
+			logError(ctx, DataStoreFailedtoSaveWebhookDelivery, zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDueWebhookDeliveries retrieves up to limit pending deliveries whose next attempt is due.
+// Finished deliveries have no next attempt, so they are never returned.
+func ListDueWebhookDeliveries(ctx context.Context, client *Client, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	query := cloudDatastore.NewQuery(DataStoreWebhookDeliveryNameKey).
+		FilterField("next_attempt_at", ">", time.Time{}).
+		FilterField("next_attempt_at", "<=", now).
+		Order("next_attempt_at").
+		Limit(limit)
+	var deliveries []*WebhookDelivery
+	if _, err := client.GetAll(ctx, query, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDelivery reserves a due delivery for one attempt, so that other replicas leave it
+// alone for the duration of the lease, and counts the attempt. It returns nil without error if the
+// delivery is no longer due, because another replica claimed it first.
+func ClaimWebhookDelivery(ctx context.Context, client *Client, id string, now time.Time, lease time.Duration) (*WebhookDelivery, error) {
+	key := cloudDatastore.NameKey(DataStoreWebhookDeliveryNameKey, id, nil)
+	var claimed *WebhookDelivery
+	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		claimed = nil
+		d := new(WebhookDelivery)
+		if err := tx.Get(key, d); err != nil {
+			return err
+		}
+		if d.Status != DeliveryPending || d.NextAttemptAt.After(now) {
+			return nil
+		}
+		d.Attempts++
+		d.NextAttemptAt = now.Add(lease)
+		if _, err := tx.Put(key, d); err != nil {
+			return err
+		}
+		claimed = d
+		return nil
+	})
+	return claimed, err
+}
+
+// GetWebhookDelivery retrieves a delivery by its ID.
+// The function returns ErrNotFound if no delivery with the given ID exists.
+func GetWebhookDelivery(ctx context.Context, client *Client, id string) (*WebhookDelivery, error) {
+	d := new(WebhookDelivery)
+	if err := client.Get(ctx, cloudDatastore.NameKey(DataStoreWebhookDeliveryNameKey, id, nil), d); err != nil {
+		if err == cloudDatastore.ErrNoSuchEntity {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// SaveWebhookDelivery stores the outcome of an attempt, or a delivery queued again by an admin.
+func SaveWebhookDelivery(ctx context.Context, client *Client, d *WebhookDelivery) error {
+	_, err := client.Put(ctx, cloudDatastore.NameKey(DataStoreWebhookDeliveryNameKey, d.ID, nil), d)
+	if err != nil {
+		logError(ctx, DataStoreFailedtoSaveWebhookDelivery, zap.String("id", d.ID), zap.Error(err))
+	}
+	return err
+}
+
+// ListWebhookDeliveries retrieves a page of deliveries, oldest first, optionally only those of one
+// subscription or with one status. Pass the NextCursor of the previous page as cursor to continue,
+// or an empty string to start.
+func ListWebhookDeliveries(ctx context.Context, client *Client, subscription, status string, limit int, cursor string) (*WebhookDeliveryPage, error) {
+	// Delivery IDs sort chronologically, so the default key order lists the oldest deliveries first.
+	query := cloudDatastore.NewQuery(DataStoreWebhookDeliveryNameKey)
+	if subscription != "" {
+		query = query.FilterField("subscription", "=", subscription)
+	}
+	if status != "" {
+		query = query.FilterField("status", "=", status)
+	}
+	if cursor != "" {
+		start, err := cloudDatastore.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Start(start)
+	}
+
+	page := &WebhookDeliveryPage{Deliveries: []*WebhookDelivery{}}
+	it := client.Run(ctx, query.Limit(limit))
+	for {
+		d := new(WebhookDelivery)
+		_, err := it.Next(d)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		page.Deliveries = append(page.Deliveries, d)
+	}
+
+	if len(page.Deliveries) < limit {
+		return page, nil
+	}
+	next, err := it.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = next.String()
+	return page, nil
+}
+
+// AddURLClicks adds n clicks to the click count of a link, and returns the count before and after.
+// The count is stored as a child of the URL entity, so it is purged along with it.
+func AddURLClicks(ctx context.Context, client *Client, id string, n int64) (before, after int64, err error) {
+	key := cloudDatastore.NameKey(DataStoreClickNameKey, id, cloudDatastore.NameKey(DataStoreNameKey, id, nil))
+	_, err = client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		count := new(clickCount)
+		if err := tx.Get(key, count); err != nil && err != cloudDatastore.ErrNoSuchEntity {
+			return err
+		}
+		before = count.Count
+		count.Count += n
+		after = count.Count
+		_, err := tx.Put(key, count)
+		return err
+	})
+	return before, after, err
+}
+
+// ClaimExpiredWindow reserves the period from the end of the last checked period until now, in which the
+// expiry of links has not been checked yet, so that each expiry is reported by one replica only. The period
+// is only marked as checked by CompleteExpiredWindow, once its events are queued; if that never happens,
+// for example because the replica crashed, the reservation lapses after lease and the same period is
+// claimed again. The function returns a nil window if another replica holds the reservation.
+// The first claim starts at now, so links that expired before webhooks were set up are not reported.
+func ClaimExpiredWindow(ctx context.Context, client *Client, now time.Time, lease time.Duration) (window *ExpiredWindow, err error) {
+	key := cloudDatastore.NameKey(DataStoreWebhookStateNameKey, DataStoreWebhookStateExpiry, nil)
+	_, err = client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		window = nil
+		state := new(webhookState)
+		if err := tx.Get(key, state); err != nil && err != cloudDatastore.ErrNoSuchEntity {
+			return err
+		}
+		if now.Before(state.LeasedUntil) {
+			return nil
+		}
+
+		window = &ExpiredWindow{From: state.ExpiredUntil, Until: now, leasedUntil: now.Add(lease).Truncate(time.Microsecond)}
+		if window.From.IsZero() {
+			window.From = now
+		}
+		state.LeasedUntil = window.leasedUntil
+		_, err := tx.Put(key, state)
+		return err
+	})
+	return window, err
+}
+
+// CompleteExpiredWindow marks the period of a claimed window as checked and releases the reservation.
+// The reservation is left alone if it lapsed and was taken by another replica in the meantime.
+func CompleteExpiredWindow(ctx context.Context, client *Client, window *ExpiredWindow) error {
+	key := cloudDatastore.NameKey(DataStoreWebhookStateNameKey, DataStoreWebhookStateExpiry, nil)
+	_, err := client.RunInTransaction(ctx, func(tx *cloudDatastore.Transaction) error {
+		state := new(webhookState)
+		if err := tx.Get(key, state); err != nil && err != cloudDatastore.ErrNoSuchEntity {
+			return err
+		}
+		if window.Until.After(state.ExpiredUntil) {
+			state.ExpiredUntil = window.Until
+		}
+		if state.LeasedUntil.Equal(window.leasedUntil) {
+			state.LeasedUntil = time.Time{}
+		}
+		_, err := tx.Put(key, state)
+		return err
+	})
+	return err
+}
+
+// ListURLsExpiredBetween retrieves the URL entities whose expiry lies after from and at or before until.
+func ListURLsExpiredBetween(ctx context.Context, client *Client, from, until time.Time) ([]*URL, error) {
+	query := cloudDatastore.NewQuery(DataStoreNameKey).
+		FilterField("expires_at", ">", from).
+		FilterField("expires_at", "<=", until)
+	var urls []*URL
+	if _, err := client.GetAll(ctx, query, &urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/shortid"
+	"github.com/gin-gonic/gin"
+)
+
+// Define the scopes that can be granted to an API key.
+// ScopeAdmin implies every other scope and is required for the internal endpoints.
+const (
+	ScopeCreate    = "create"
+	ScopeEdit      = "edit"
+	ScopeDelete    = "delete"
+	ScopeReadStats = "read-stats"
+	ScopeAdmin     = "admin"
+)
+
+// apiKeySecretLength is the number of URL-safe characters in the secret part of an API key.
+const apiKeySecretLength = 43
+
+// apiKeySeparator separates the key name from its secret in the plaintext API key.
+const apiKeySeparator = "."
+
+// apiKeyNamePattern restricts API key names so that they can never contain the separator.
+var apiKeyNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validScopes is the set of scopes accepted when creating an API key.
+var validScopes = map[string]bool{
+	ScopeCreate:    true,
+	ScopeEdit:      true,
+	ScopeDelete:    true,
+	ScopeReadStats: true,
+	ScopeAdmin:     true,
+}
+
+// Principal identifies the authenticated caller of a management request and what it may do.
+type Principal struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// HasScope reports whether the principal was granted the given scope, either directly or through ScopeAdmin.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyPayload defines the structure for the JSON payload when creating a new API key.
+// TTL is a Go duration string such as "720h"; an empty TTL creates a key that never expires.
+type CreateAPIKeyPayload struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	TTL    string   `json:"ttl,omitempty"`
+}
+
+// GenerateAPIKey creates and stores a new API key with the given name, scopes and lifetime.
+// It returns the plaintext key, which is shown only once, along with the stored entity.
+// A ttl of zero creates a key that never expires.
+func GenerateAPIKey(ctx context.Context, dsClient *datastore.Client, name string, scopes []string, ttl time.Duration) (string, *datastore.APIKey, error) {
+	if err := validateAPIKeyRequest(name, scopes); err != nil {
+		return "", nil, err
+	}
+
+	secret, err := shortid.Generate(apiKeySecretLength)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC()
+	apiKey := &datastore.APIKey{
+		Name:      name,
+		Hash:      hashAPIKeySecret(secret),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		apiKey.ExpiresAt = now.Add(ttl)
+	}
+
+	if err := datastore.InsertAPIKey(ctx, dsClient, apiKey); err != nil {
+		return "", nil, err
+	}
+	return name + apiKeySeparator + secret, apiKey, nil
+}
+
+// validateAPIKeyRequest checks the name and scopes of a new API key.
+func validateAPIKeyRequest(name string, scopes []string) error {
+	if !apiKeyNamePattern.MatchString(name) {
+		return &BadRequestError{Message: constant.HeaderResponseInvalidAPIKeyName}
+	}
+	for _, scope := range scopes {
+		if !validScopes[scope] {
+			return &BadRequestError{Message: constant.HeaderResponseInvalidScope + ": " + scope}
+		}
+	}
+	return nil
+}
+
+// hashAPIKeySecret returns the hex-encoded SHA-256 hash of an API key secret.
+// API key secrets are long and random, so a fast hash is sufficient to protect them at rest.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyAPIKey looks up the API key named in the plaintext key and checks its secret, expiry and revocation.
+// The secret hash is compared in constant time.
+func verifyAPIKey(ctx context.Context, dsClient *datastore.Client, plaintext string) (*Principal, error) {
+	name, secret, ok := strings.Cut(plaintext, apiKeySeparator)
+	if !ok || !apiKeyNamePattern.MatchString(name) {
+		return nil, errUnauthorized
+	}
+
+	apiKey, err := datastore.GetAPIKey(ctx, dsClient, name)
+	if err == datastore.ErrNotFound {
+		return nil, errUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !secretsEqual(hashAPIKeySecret(secret), apiKey.Hash) || apiKey.Revoked || apiKey.IsExpired(time.Now()) {
+		return nil, errUnauthorized
+	}
+	return &Principal{Name: apiKeyPrincipalName(apiKey.Name), Scopes: apiKey.Scopes}, nil
+}
+
+// apiKeyPrincipalName returns the principal name of an API key, "key:<name>".
+// The prefix keeps key names apart from bearer token subjects, see tokenPrincipalName.
+func apiKeyPrincipalName(name string) string {
+	return PrincipalPrefixAPIKey + name
+}
+
+// secretsEqual compares two secrets in constant time.
+func secretsEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// createAPIKeyHandlerGin returns a Gin handler function that creates a new API key.
+// The plaintext key is included in the response and cannot be retrieved again.
+func createAPIKeyHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateAPIKeyPayload
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, err)
+			return
+		}
+
+		ttl, err := parseOptionalDuration(req.TTL)
+		if err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, err)
+			return
+		}
+
+		plaintext, apiKey, err := GenerateAPIKey(c.Request.Context(), dsClient, req.Name, req.Scopes, ttl)
+		if err != nil {
+			handleAPIKeyError(c, req.Name, err)
+			return
+		}
+
+		setAuditChange(c, apiKey.Name, nil, apiKey)
+		LogAPIKeyCreated(c.Request.Context(), apiKey.Name)
+		c.JSON(http.StatusCreated, gin.H{
+			constant.HeaderResponseAPIKey: plaintext,
+			constant.HeaderResponseKey:    apiKey,
+		})
+	}
+}
+
+// listAPIKeysHandlerGin returns a Gin handler function that lists every API key without their secrets.
+func listAPIKeysHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKeys, err := datastore.ListAPIKeys(c.Request.Context(), dsClient)
+		if err != nil {
+			handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{constant.HeaderResponseKeys: apiKeys})
+	}
+}
+
+// revokeAPIKeyHandlerGin returns a Gin handler function that revokes an API key by name.
+func revokeAPIKeyHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param(constant.HeaderName)
+		before, after, err := datastore.RevokeAPIKey(c.Request.Context(), dsClient, name)
+		if err != nil {
+			handleAPIKeyError(c, name, err)
+			return
+		}
+		setAuditChange(c, name, before, after)
+
+		LogAPIKeyRevoked(c.Request.Context(), name)
+		c.JSON(http.StatusOK, gin.H{constant.HeaderMessage: constant.HeaderResponseAPIKeyRevoked})
+	}
+}
+
+// handleAPIKeyError maps API key management errors to HTTP responses.
+func handleAPIKeyError(c *gin.Context, name string, err error) {
+	switch {
+	case err == datastore.ErrAlreadyExists:
+		handleError(c, constant.HeaderResponseAPIKeyExists, http.StatusConflict, err)
+	case err == datastore.ErrNotFound:
+		handleError(c, constant.HeaderResponseAPIKeyNotFound, http.StatusNotFound, err)
+	case isBadRequestError(err):
+		handleError(c, err.Error(), http.StatusBadRequest, nil)
+	default:
+		LogInternalError(c.Request.Context(), operation_apiKey, name, err)
+		handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+	}
+}
+
+// parseOptionalDuration parses a Go duration string, treating the empty string as zero.
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/audit"
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+)
+
+// auditSink receives an event for each management request. Auditing is disabled while it is nil,
+// which is the default.
+var auditSink audit.Sink
+
+// auditWriteTimeout bounds the time spent storing one audit event.
+const auditWriteTimeout = 5 * time.Second
+
+// auditedReads holds the routes of read-only requests that are audited all the same, because they
+// disclose password hashes or the audit log itself. It is filled by RegisterHandlersGin with auditReads,
+// as the routes depend on the base path.
+var auditedReads = map[string]bool{}
+
+// auditReads marks the GET routes of the group with the given relative paths as audited.
+func auditReads(group *gin.RouterGroup, relativePaths ...string) {
+	for _, relativePath := range relativePaths {
+		auditedReads[path.Join(group.BasePath(), relativePath)] = true
+	}
+}
+
+// SetAuditSink installs the sink that records management requests handled behind InternalOnly.
+// Passing nil disables auditing.
+func SetAuditSink(s audit.Sink) {
+	auditSink = s
+}
+
+// auditChange is the resource changed by a request, as recorded by the handler for the audit log.
+type auditChange struct {
+	id     string
+	before any
+	after  any
+}
+
+// setAuditChange records the resource changed by the request, before and after the change.
+// Either side may be nil, for resources that are created or deleted.
+func setAuditChange(c *gin.Context, id string, before, after any) {
+	c.Set(constant.GinContextAuditChange, &auditChange{id: id, before: before, after: after})
+}
+
+// auditURLChange wraps a mutation of a link so that the link before and after it is recorded for the
+// audit log. The mutation runs inside the update transaction, so the recorded state is the stored one.
+func auditURLChange(c *gin.Context, fn func(*datastore.URL) error) func(*datastore.URL) error {
+	return func(url *datastore.URL) error {
+		before := *url
+		before.Tags = slices.Clone(url.Tags)
+		if err := fn(url); err != nil {
+			return err
+		}
+		setAuditChange(c, url.ID, &before, url)
+		return nil
+	}
+}
+
+// auditRequest writes the audit event of a management request once it has been handled. Read-only
+// requests are not audited, unless their route is in auditedReads. The event is stored even if the
+// client has gone away in the meantime.
+func auditRequest(c *gin.Context) {
+	if auditSink == nil || (isReadOnly(c.Request.Method) && !auditedReads[c.FullPath()]) {
+		return
+	}
+
+	event := audit.Event{
+		Time:      time.Now(),
+		Principal: principalName(c),
+		Operation: c.Request.Method + " " + c.FullPath(),
+		ID:        c.Param(constant.HeaderID),
+		ClientIP:  c.ClientIP(),
+		RequestID: logmonitor.RequestIDFromContext(c.Request.Context()),
+		Status:    c.Writer.Status(),
+	}
+	event.Outcome = audit.OutcomeOf(event.Status)
+	if event.ID == "" {
+		event.ID = c.Param(constant.HeaderName)
+	}
+	if v, ok := c.Get(constant.GinContextAuditChange); ok {
+		change := v.(*auditChange)
+		event.ID = change.id
+		event.Before = marshalAuditValue(change.before)
+		event.After = marshalAuditValue(change.after)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), auditWriteTimeout)
+	defer cancel()
+	if err := auditSink.Write(ctx, event); err != nil {
+		LogAuditWriteFailed(c.Request.Context(), event.Operation, event.ID, err)
+	}
+}
+
+// isReadOnly reports whether the request method does not change anything.
+func isReadOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// marshalAuditValue encodes a resource for the audit log, or returns nil if there is none.
+// Secrets never appear, as the resources exclude them from their JSON encoding.
+func marshalAuditValue(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// auditLogHandlerGin returns a Gin handler function that lists the audit log page by page, oldest
+// event first. The "since" and "until" query parameters, in RFC 3339 format, restrict it to a time
+// range. It responds with HTTP 400 if the cursor was not handed out by the sink, and with HTTP 501 if
+// the audit sink cannot be queried, such as the standard output.
+func auditLogHandlerGin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		querier, ok := auditSink.(audit.Querier)
+		if !ok {
+			handleError(c, constant.HeaderResponseAuditNotQueryable, http.StatusNotImplemented, nil)
+			return
+		}
+
+		filter, err := parseAuditFilter(c)
+		if err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequest, http.StatusBadRequest, nil)
+			return
+		}
+
+		page, err := querier.Query(c.Request.Context(), filter)
+		if _, ok := err.(*audit.InvalidCursorError); ok {
+			handleError(c, constant.HeaderResponseInvalidRequest, http.StatusBadRequest, nil)
+			return
+		}
+		if err != nil {
+			LogInternalError(c.Request.Context(), operation_audit, "", err)
+			handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// parseAuditFilter reads the time range and the page of the audit log from the query parameters.
+func parseAuditFilter(c *gin.Context) (audit.Filter, error) {
+	limit, err := parseListLimit(c.Query(QueryLimit))
+	if err != nil {
+		return audit.Filter{}, err
+	}
+	filter := audit.Filter{Limit: limit, Cursor: c.Query(QueryCursor)}
+	if filter.Since, err = parseOptionalTime(c.Query(QuerySince)); err != nil {
+		return audit.Filter{}, err
+	}
+	if filter.Until, err = parseOptionalTime(c.Query(QueryUntil)); err != nil {
+		return audit.Filter{}, err
+	}
+	return filter, nil
+}
+
+// parseOptionalTime parses an RFC 3339 time, treating the empty string as the zero time.
+func parseOptionalTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
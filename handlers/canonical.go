@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/canonical"
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+)
+
+// canonicalConfig controls whether destination URLs are canonicalized and links are reused.
+// It is configured from the URL_CANONICAL* environment variables during package initialization.
+var canonicalConfig canonical.Config
+
+// SetCanonicalization replaces the canonicalization settings applied when links are created or edited.
+func SetCanonicalization(config canonical.Config) {
+	canonicalConfig = config
+}
+
+// canonicalizeURL returns the canonical form of the destination URL if canonicalization is enabled,
+// or the URL unchanged otherwise. If the URL cannot be canonicalized, it responds with HTTP 400 and returns false.
+func canonicalizeURL(c *gin.Context, rawURL string) (string, bool) {
+	if !canonicalConfig.Enabled {
+		return rawURL, true
+	}
+	canonicalURL, err := canonical.Canonicalize(rawURL, canonicalConfig.Options)
+	if err != nil {
+		handleError(c, constant.HeaderResponseInvalidURLFormat, http.StatusBadRequest, nil)
+		return rawURL, false
+	}
+	return canonicalURL, true
+}
+
+// canonicalHashOf returns the canonical hash to index the link under, or an empty string if the link
+// must not be reused. Password-protected links are never reused, as the password is part of the link.
+// The original URL is canonicalized again, as imported links are stored as they were exported.
+func canonicalHashOf(url *datastore.URL) string {
+	if !canonicalConfig.Enabled || url.IsProtected() {
+		return ""
+	}
+	canonicalURL, err := canonical.Canonicalize(url.Original, canonicalConfig.Options)
+	if err != nil {
+		return ""
+	}
+	return canonical.Hash(canonicalURL)
+}
+
+// findReusableURL returns the caller's existing link for the canonical destination of the request,
+// or nil if there is none or canonicalization is disabled. Requests carrying a password always get a new link.
+//
+// Note: The lookup is not transactional with the creation, so concurrent requests for the same destination
+// may still create several links; this is harmless, as each of them works.
+func findReusableURL(c *gin.Context, dsClient *datastore.Client, req CreateURLPayload) (*datastore.URL, error) {
+	if !canonicalConfig.Enabled || req.Password != "" {
+		return nil, nil
+	}
+	url, err := datastore.FindURLByCanonicalHash(c.Request.Context(), dsClient, principalName(c), canonical.Hash(req.URL))
+	if err == datastore.ErrNotFound {
+		return nil, nil
+	}
+	return url, err
+}
+
+// sameDestination reports whether the URL given by a client designates the stored destination.
+// With canonicalization enabled, the client may send any spelling of it, such as the URL it originally shortened.
+func sameDestination(stored string, given string) bool {
+	if stored == given {
+		return true
+	}
+	if !canonicalConfig.Enabled {
+		return false
+	}
+	canonicalURL, err := canonical.Canonicalize(given, canonicalConfig.Options)
+	return err == nil && canonicalURL == stored
+}
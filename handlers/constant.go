@@ -23,12 +23,120 @@ const (
 	operation_mismatch_error        = "mismatch_error"
 	operation_shorten_url           = "shorten_url"
 	operation_url_mismatch_error    = "url_mismatch_error"
+	operation_exportURL             = "exportURL"
+	operation_importURL             = "importURL"
+	operation_authenticate          = "authenticate"
+	operation_authorize             = "authorize"
+	operation_apiKey                = "apiKey"
+	operation_unlockURL             = "unlockURL"
+	operation_rateLimit             = "rateLimit"
+	operation_scanURL               = "scanURL"
+	operation_idempotency           = "idempotency"
+	operation_patchURL              = "patchURL"
+	operation_restoreURL            = "restoreURL"
+	operation_purgeURLs             = "purgeURLs"
+	operation_historyURL            = "historyURL"
+	operation_rollbackURL           = "rollbackURL"
+	operation_audit                 = "audit"
+	operation_webhook               = "webhook"
+	operation_clicks                = "clicks"
+	operation_logLevel              = "logLevel"
 )
 
 // Define Internal Object
 const (
-	PathObjectID          = ":id"
-	PathObjectBasePath    = "/"
-	CUSTOM_BASE_PATH      = "CUSTOM_BASE_PATH"
-	INTERNAL_SECRET_VALUE = "INTERNAL_SECRET_VALUE"
+	PathObjectID            = ":id"
+	PathObjectBasePath      = "/"
+	CUSTOM_BASE_PATH        = "CUSTOM_BASE_PATH"
+	INTERNAL_SECRET_VALUE   = "INTERNAL_SECRET_VALUE"
+	TRUSTED_PROXIES         = "TRUSTED_PROXIES"
+	RATE_LIMIT_REDIRECT     = "RATE_LIMIT_REDIRECT"
+	RATE_LIMIT_MANAGEMENT   = "RATE_LIMIT_MANAGEMENT"
+	RATE_LIMIT_AUTH         = "RATE_LIMIT_AUTH"
+	RATE_LIMIT_API_KEYS     = "RATE_LIMIT_API_KEYS"
+	RATE_LIMIT_UNLOCK_LINK  = "RATE_LIMIT_UNLOCK_LINK"
+	RATE_LIMIT_ALLOWLIST    = "RATE_LIMIT_ALLOWLIST"
+	RATE_LIMIT_STORE_SIZE   = "RATE_LIMIT_STORE_SIZE"
+	RATE_LIMIT_STORE_SHARDS = "RATE_LIMIT_STORE_SHARDS"
+	RATE_LIMIT_STORE_TTL    = "RATE_LIMIT_STORE_TTL"
+	IDEMPOTENCY_KEY_TTL     = "IDEMPOTENCY_KEY_TTL"
+	IDEMPOTENCY_KEY_LEASE   = "IDEMPOTENCY_KEY_LEASE"
+	URL_DELETE_RETENTION    = "URL_DELETE_RETENTION"
+	URL_PURGE_INTERVAL      = "URL_PURGE_INTERVAL"
+	PathObjectInternal      = "internal/"
+	PathObjectExport        = "export"
+	PathObjectImport        = "import"
+	DataTransferFileName    = "urlz"
+	PathObjectAPIKeys       = "apikeys"
+	PathObjectName          = ":name"
+	PathObjectURLs          = "urls"
+	PathObjectVars          = "vars"
+	PathObjectRestore       = "restore"
+	PathObjectHistory       = "history"
+	PathObjectRollback      = "rollback"
+	PathObjectAudit         = "audit"
+	PathObjectWebhooks      = "webhooks"
+	PathObjectDeliveries    = "deliveries"
+	PathObjectDelivery      = ":delivery"
+	PathObjectRetry         = "retry"
+	PathObjectLogLevels     = "loglevels"
+	RateLimitStoreVar       = "ratelimit_store"
+
+	// InternalSecretPrincipal is the principal name given to callers using the legacy shared secret.
+	InternalSecretPrincipal = "internal"
+
+	// PrincipalPrefixAPIKey and PrincipalPrefixToken prefix the principal names of API keys and
+	// bearer token subjects, so that a key and a token subject with the same name are different owners.
+	PrincipalPrefixAPIKey = "key:"
+	PrincipalPrefixToken  = "jwt:"
+)
+
+// Define query parameters for internal endpoints.
+const (
+	QueryFormat = "format"
+	QueryDryRun = "dry_run"
+	QueryOwner  = "owner"
+	QueryAll    = "all"
+	QueryLimit  = "limit"
+	QueryCursor = "cursor"
+	QuerySince  = "since"
+	QueryUntil  = "until"
+
+	QueryIncludeDeleted = "include_deleted"
+
+	QuerySubscription = "subscription"
+	QueryStatus       = "status"
+)
+
+// Define form fields for public endpoints.
+const (
+	FormPassword = "password"
+)
+
+// Define the mutable fields of a link in a merge patch.
+const (
+	PatchFieldURL            = "url"
+	PatchFieldExpiresAt      = "expires_at"
+	PatchFieldRedirectStatus = "redirect_status"
+	PatchFieldTags           = "tags"
+	PatchFieldPassword       = "password"
+	PatchFieldEnabled        = "enabled"
+)
+
+// Define the fields of an imported link that are validated besides the mutable ones.
+const (
+	ImportFieldOwner     = "owner"
+	ImportFieldDeletedAt = "deleted_at"
+)
+
+// Define error messages for merge patches, which are returned to the client.
+const (
+	ErrMsgUnknownPatchField = "Unknown field %q"
+	ErrMsgInvalidPatchField = "Invalid value for %q"
+)
+
+// Define error messages for the configuration of the handlers.
+const (
+	ErrMsgInvalidRateLimit   = "invalid rate limit %q, expected rate:burst"
+	ErrMsgInvalidPositiveInt = "%s: invalid value %q, expected a positive integer"
 )
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/webhook"
+)
+
+// Define the limits of the webhook dispatcher.
+const (
+	// webhookBatchSize is the number of due deliveries fetched at once.
+	webhookBatchSize = 100
+	// webhookConcurrency is the number of deliveries sent in parallel, so that one slow subscriber
+	// does not hold up the others.
+	webhookConcurrency = 8
+	// expiredWindowLease is how long a replica may take to queue the link.expired events of a period
+	// before another replica checks the same period again.
+	expiredWindowLease = time.Minute
+)
+
+// clickCounts accumulates the clicks of each link between two flushes, so that a popular link does not
+// cause a Datastore write for every redirect. Clicks are only counted when click thresholds are configured.
+var clickCounts = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: make(map[string]int64)}
+
+// countClick records a click on a link, to be stored at the next flush.
+func countClick(id string) {
+	if len(webhookConfig.ClickThresholds) == 0 {
+		return
+	}
+	clickCounts.Lock()
+	clickCounts.counts[id]++
+	clickCounts.Unlock()
+}
+
+// StartWebhookDispatcher periodically sends the due webhook deliveries, stores the accumulated click
+// counts and reports the links that expired, every WEBHOOK_POLL_INTERVAL or as soon as deliveries are
+// queued. It returns a function that stops the dispatcher after storing the remaining click counts.
+// Running it on every replica is safe, as each delivery and each expiry is claimed by one replica.
+func StartWebhookDispatcher(dsClient *datastore.Client) (stop func()) {
+	ticker := time.NewTicker(webhookConfig.PollInterval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				flushClickCounts(dsClient)
+				emitExpiredLinks(dsClient)
+				deliverWebhooks(dsClient)
+			case <-webhookWake:
+				deliverWebhooks(dsClient)
+			case <-done:
+				ticker.Stop()
+				flushClickCounts(dsClient)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// flushClickCounts adds the accumulated clicks to the stored counts, and emits a link.clicked event for
+// each threshold a link passed. Clicks that cannot be stored are kept for the next flush.
+func flushClickCounts(dsClient *datastore.Client) {
+	clickCounts.Lock()
+	counts := clickCounts.counts
+	clickCounts.counts = make(map[string]int64)
+	clickCounts.Unlock()
+
+	ctx := context.Background()
+	for id, n := range counts {
+		before, after, err := datastore.AddURLClicks(ctx, dsClient, id, n)
+		if err != nil {
+			LogClickFlushFailed(ctx, id, err)
+			clickCounts.Lock()
+			clickCounts.counts[id] += n
+			clickCounts.Unlock()
+			continue
+		}
+
+		crossed := webhook.CrossedThresholds(webhookConfig.ClickThresholds, before, after)
+		if len(crossed) == 0 {
+			continue
+		}
+		url, err := datastore.GetURL(ctx, dsClient, id)
+		if err != nil {
+			LogWebhookEnqueueFailed(ctx, webhook.EventLinkClicked, id, err)
+			continue
+		}
+		for _, threshold := range crossed {
+			emitLinkEvent(ctx, dsClient, webhook.EventLinkClicked, linkEventData{Link: url, Clicks: threshold})
+		}
+	}
+}
+
+// emitExpiredLinks emits a link.expired event for each link whose expiry passed since the last check
+// by any replica. Deleted links are skipped. The period is only marked as checked once every event is
+// queued; otherwise it is checked again later, which may queue some of its events twice.
+func emitExpiredLinks(dsClient *datastore.Client) {
+	ctx := context.Background()
+	window, err := datastore.ClaimExpiredWindow(ctx, dsClient, time.Now(), expiredWindowLease)
+	if err != nil || window == nil {
+		if err != nil {
+			LogWebhookEnqueueFailed(ctx, webhook.EventLinkExpired, "", err)
+		}
+		return
+	}
+	urls, err := datastore.ListURLsExpiredBetween(ctx, dsClient, window.From, window.Until)
+	if err != nil {
+		LogWebhookEnqueueFailed(ctx, webhook.EventLinkExpired, "", err)
+		return
+	}
+	for _, url := range urls {
+		if url.IsDeleted() {
+			continue
+		}
+		if err := enqueueLinkEvent(ctx, dsClient, webhook.EventLinkExpired, linkEventData{Link: url}); err != nil {
+			LogWebhookEnqueueFailed(ctx, webhook.EventLinkExpired, url.ID, err)
+			return
+		}
+	}
+	if err := datastore.CompleteExpiredWindow(ctx, dsClient, window); err != nil {
+		LogWebhookEnqueueFailed(ctx, webhook.EventLinkExpired, "", err)
+	}
+}
+
+// deliverWebhooks sends the due deliveries, a batch at a time, until none are left.
+func deliverWebhooks(dsClient *datastore.Client) {
+	ctx := context.Background()
+	for {
+		due, err := datastore.ListDueWebhookDeliveries(ctx, dsClient, time.Now(), webhookBatchSize)
+		if err != nil {
+			LogInternalError(ctx, operation_webhook, "", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, webhookConcurrency)
+		for _, d := range due {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(id string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				attemptDelivery(ctx, dsClient, id)
+			}(d.ID)
+		}
+		wg.Wait()
+
+		if len(due) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attemptDelivery claims a due delivery and sends it to its subscription. A failed delivery is retried
+// with exponential backoff until WEBHOOK_MAX_ATTEMPTS is reached, after which it is dead-lettered.
+// Deliveries of deleted subscriptions are dead-lettered immediately.
+func attemptDelivery(ctx context.Context, dsClient *datastore.Client, id string) {
+	// The lease outlasts the request, so that no other replica sends the delivery in the meantime.
+	d, err := datastore.ClaimWebhookDelivery(ctx, dsClient, id, time.Now(), 2*webhookConfig.Timeout)
+	if err != nil || d == nil {
+		if err != nil {
+			LogInternalError(ctx, operation_webhook, id, err)
+		}
+		return
+	}
+
+	sub, err := datastore.GetWebhookSubscription(ctx, dsClient, d.Subscription)
+	switch {
+	case err == datastore.ErrNotFound:
+		finishDelivery(ctx, d, datastore.DeliveryDead, 0, errSubscriptionDeleted)
+	case err != nil:
+		// The subscription could not be read; the lease expires and the delivery is retried.
+		LogInternalError(ctx, operation_webhook, id, err)
+		return
+	default:
+		status, err := webhookSender.Send(ctx, sub.URL, sub.Secret, d.ID, d.EventType, d.Payload)
+		switch {
+		case err == nil:
+			finishDelivery(ctx, d, datastore.DeliveryDelivered, status, nil)
+		case d.Attempts >= webhookConfig.MaxAttempts:
+			finishDelivery(ctx, d, datastore.DeliveryDead, status, err)
+		default:
+			d.LastStatusCode = status
+			d.LastError = err.Error()
+			d.NextAttemptAt = time.Now().Add(webhookConfig.Backoff.Delay(d.Attempts))
+			LogWebhookAttemptFailed(ctx, d.ID, d.Subscription, d.Attempts, err)
+		}
+	}
+
+	if err := datastore.SaveWebhookDelivery(ctx, dsClient, d); err != nil {
+		LogInternalError(ctx, operation_webhook, id, err)
+	}
+}
+
+// finishDelivery records the final outcome of a delivery, which leaves the queue.
+func finishDelivery(ctx context.Context, d *datastore.WebhookDelivery, status string, statusCode int, err error) {
+	d.Status = status
+	d.LastStatusCode = statusCode
+	d.NextAttemptAt = time.Time{}
+	d.LastError = ""
+	if err != nil {
+		d.LastError = err.Error()
+		LogWebhookDeadLettered(ctx, d.ID, d.Subscription, d.Attempts, err)
+		return
+	}
+	d.DeliveredAt = time.Now()
+}
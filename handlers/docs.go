@@ -10,6 +10,8 @@
 //
 // Consistent and structured logging is maintained across the package using centralized logging functions,
 // which aid in the systematic recording of operational events for ease of debugging and service monitoring.
+// The logging functions take the context of the request they are logging for, so that each line carries the
+// ID and the trace of the request. Error responses include the ID of the request in their body as well.
 //
 // # Example of package usage
 //
@@ -38,7 +40,8 @@
 // The following code snippets illustrate the structures of these types:
 //
 //	type CreateURLPayload struct {
-//	    URL string `json:"url" binding:"required,url"`
+//	    URL      string `json:"url" binding:"required,url"`
+//	    Password string `json:"password,omitempty"`
 //	}
 //
 //	type UpdateURLPayload struct {
@@ -52,20 +55,24 @@
 //	    URL string `json:"url" binding:"required,url"`
 //	}
 //
-// Middleware functions such as InternalOnly enforce access control by requiring a secret
-// value in the request header, compared against an environment variable.
+// Middleware functions such as InternalOnly enforce access control by requiring a named API key
+// in the X-API-Key header. Each key is stored hashed, carries a set of scopes (create, edit, delete,
+// read-stats, admin), and can expire or be revoked. The legacy shared secret compared against an
+// environment variable is still accepted when configured, and is treated as an admin key.
+// When SetTokenVerifier has been called, "Authorization: Bearer" JWTs validated by the jwtauth
+// package are accepted too, with their claims mapped to the same scopes.
 //
 // # The package also exports several key variables
 //
 //   - basePath: A string representing the base path for the URL shortener's endpoints.
-//   - internalSecretValue: A string used by the InternalOnly middleware to validate requests against internal services.
-//   - RateLimiterStore: A sync.Map that stores rate limiters for each client IP address.
+//   - internalSecretValue: An optional legacy secret accepted by the InternalOnly middleware as an admin credential.
+//   - RateLimiterStore: A bounded *ratelimit.Store that holds the rate limiter of each client, evicting idle ones.
 //
 // # The following code snippets illustrate the declaration of these variables
 //
 //	var basePath string
 //	var internalSecretValue string
-//	var RateLimiterStore sync.Map
+//	var RateLimiterStore *ratelimit.Store
 //
 // # Handler Functions
 //
@@ -78,21 +85,88 @@
 //     and redirects the client to it. Responds with HTTP 404 if the URL is not found, HTTP 429 if rate limit is exceeded,
 //     or HTTP 500 for other errors.
 //
+//   - unlockURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Checks the password submitted through the unlock form of a password-protected link and
+//     redirects to the original URL if it matches. Attempts are rate limited per link ID and client IP,
+//     and per link ID across all clients.
+//
 //   - postURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
 //     Handles the creation of a new shortened URL. It expects a JSON payload with the original
-//     URL, generates a short identifier, stores the mapping, and returns the shortened URL.
+//     URL and an optional password, generates a short identifier, stores the mapping, and returns
+//     the shortened URL. Passwords are stored as bcrypt hashes.
 //
 //   - editURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
 //     Manages the updating of an existing shortened URL. It validates the request payload,
 //     verifies the existing URL, and updates it with the new URL provided.
 //
+//   - patchURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Partially updates a shortened URL with a JSON Merge Patch document. The destination, expiry,
+//     redirect status, tags, password and enabled flag can be changed; null resets a field. All
+//     fields are validated first and applied within one transaction.
+//
 //   - deleteURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
 //     Handles the deletion of an existing shortened URL. It validates the provided ID and URL,
-//     and if they match the stored entity, deletes the URL from the datastore.
+//     and if they match the stored entity, marks the URL as deleted. Deleted links answer HTTP 410
+//     and are purged by StartPurgeJob once URL_DELETE_RETENTION has passed.
+//
+//   - restoreURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Restores a deleted link that has not been purged yet.
+//
+//   - auditLogHandlerGin() gin.HandlerFunc:
+//     Lists the audit log of management requests within an optional time range, when the sink
+//     installed with SetAuditSink can be queried.
+//
+//   - createWebhookHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Subscribes an endpoint to link events. The signing secret is returned once, in the response.
+//
+//   - listWebhooksHandlerGin, deleteWebhookHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     List the webhook subscriptions, or remove one by name.
+//
+//   - listWebhookDeliveriesHandlerGin, getWebhookDeliveryHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Inspect the deliveries of webhook events, filtered by "subscription" and "status".
+//
+//   - retryWebhookDeliveryHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Queues a dead-lettered delivery again, with its attempts reset.
+//
+//   - listLogLevelsHandlerGin, setLogLevelHandlerGin, resetLogLevelsHandlerGin() gin.HandlerFunc:
+//     List the log level of each package, change one or all of them for a while, or set them all
+//     back to the configured level.
+//
+//   - historyURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Lists the revisions of a link page by page, each with the link before and after the change,
+//     who made it and when.
+//
+//   - rollbackURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Restores the settings a link had at a chosen revision, after checking its destination against
+//     the policy and the scanner again. The rollback is recorded as a new revision.
+//
+//   - exportURLsHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Streams every URL entity as CSV or JSON Lines, selected by the "format" query parameter.
+//
+//   - importURLsHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Imports URL entities from the request body in CSV or JSON Lines, preserving their IDs.
+//     Rows are validated with isValidURL, conflicting IDs are reported instead of overwritten,
+//     and "dry_run=true" produces the report without writing anything.
+//
+//   - listURLsHandlerGin(dsClient *datastore.Client) gin.HandlerFunc:
+//     Lists URL entities page by page using "limit" and "cursor", with the read-stats scope. Callers
+//     see only the links they own; admins may pass "owner" to list another principal's links, or
+//     "all=true" for every link.
+//     Deleted links are left out unless "include_deleted=true" is passed.
 //
 // Each handler function utilizes the provided datastore client to interact with Google Cloud
 // Datastore and leverages structured logging for operational events.
 //
+// # Import and Export
+//
+// ExportURLs and ImportURLs are exported so that the same logic backs both the internal
+// endpoints and the "export" and "import" CLI subcommands. Imported destinations are checked against
+// the URL policy and the scanner, like those of new links, and rows with a redirect status, tags or
+// owner the handlers would not accept are reported as invalid. Imported links start at revision zero:
+//
+//	go-urlshortner export -format csv -output backup.csv
+//	go-urlshortner import -input backup.csv -dry-run
+//
 // # Helper Functions
 //
 // The package contains a variety of helper functions that support the primary handler functions.
@@ -104,9 +178,96 @@
 // processing, such as rate limiting and access control. These middleware functions are
 // applied to certain handler functions to enforce security policies and request validation.
 //
-//   - InternalOnly():
-//     A middleware function that restricts access to certain endpoints to internal services
-//     only by requiring a secret value in the request header.
+//   - InternalOnly(dsClient *datastore.Client, scopes ...string):
+//     A middleware function that restricts access to certain endpoints to callers holding an
+//     API key with the required scopes. The key secret is verified in constant time, and the
+//     principal name ("key:<name>", or "jwt:<issuer>|<subject>" for bearer tokens) is attached
+//     to request logs and to the ownership of created links.
+//
+//   - Idempotent(dsClient *datastore.Client):
+//     A middleware function that stores the response to requests carrying an Idempotency-Key
+//     header and replays it for retries. It runs after InternalOnly on the POST route.
+//
+// # Destination Policy
+//
+// Destination URLs are checked by the urlpolicy package when links are created or edited. Disallowed
+// schemes, embedded credentials, blocked domains, private or loopback addresses and links back to
+// this shortener are rejected with HTTP 400 and the reason. The policy is configured with the URL_*
+// environment variables, or replaced with SetURLPolicy. Imports are trusted admin operations and
+// only go through the basic isValidURL check.
+//
+// # Idempotency
+//
+// The Idempotent middleware makes link creation safe to retry. The response to a request carrying an
+// Idempotency-Key header is stored for IDEMPOTENCY_KEY_TTL and replayed for retries with the same key
+// and body; a different body is rejected with HTTP 422. Keys are scoped to the principal. A request holds
+// its key for IDEMPOTENCY_KEY_LEASE while it is processed, so a request lost to a crash does not block
+// retries until the record expires.
+//
+// # Canonicalization
+//
+// With URL_CANONICALIZE=true, destination URLs are canonicalized by the canonical package before the
+// policy check and stored in that form, and each link is indexed by the hash of its canonical URL.
+// When a principal shortens a destination they already have a link for, the existing ID is returned
+// with "reused": true instead of creating a new link. Links with a password are never reused.
+//
+// # URL Scanning
+//
+// After the policy check, destination URLs are passed to the URL scanner installed with SetURLScanner,
+// if any (see the scanner package). Denied URLs are rejected with HTTP 400, and a scanner failure
+// rejects the request with HTTP 503. Quarantined links are stored with their reason, and visitors
+// are served a warning page with a link to the destination instead of a redirect. Editing a link
+// scans the new URL again, so an edit can quarantine or release a link.
+//
+// # Audit Log
+//
+// InternalOnly records each management request that changes something with the audit sink installed
+// with SetAuditSink, if any (see the audit package), including requests it rejects, except those over the
+// per-IP limit checked before authentication, so that a flood cannot fill the audit log. Handlers record the
+// resource before and after the change with setAuditChange, or auditURLChange for links, so the event
+// holds the stored state. Read-only requests are not audited, except exports, which carry password
+// hashes, and reads of the audit log itself.
+//
+// # Webhooks
+//
+// When a link is created, edited, deleted or restored, an event is queued for each webhook subscribed to
+// its type (see the webhook package). StartWebhookDispatcher sends the queued deliveries in the background,
+// retrying failures with backoff until WEBHOOK_MAX_ATTEMPTS is reached, after which they are dead-lettered.
+// It also queues link.expired events for links whose expiry has passed, and link.clicked events when the
+// click count of a link passes one of WEBHOOK_CLICK_THRESHOLDS. Clicks are counted in memory and stored on
+// each poll, so they are only counted when thresholds are configured. Endpoints must pass the URL policy
+// when they are subscribed, and every connection made to deliver to them is checked against it again, so
+// that a hostname cannot be rebound to an internal address afterwards.
+//
+// # Link Ownership
+//
+// Every link records the principal that created it as its owner, prefixed with the way it
+// authenticated, so that an API key and a token subject of the same name are different owners. Edits and deletes are only
+// permitted for the owner or for an admin, and anyone else receives HTTP 403. Links created before
+// ownership was recorded have no owner and can therefore only be modified by an admin.
+//
+// # Rate Limiting
+//
+// The public redirect route is limited per client IP, and the management routes are limited per
+// authenticated principal, so that one busy client cannot starve the others. Management requests are
+// also limited per client IP before their credentials are checked, so that guessing keys cannot flood
+// Datastore with lookups. The policies are configured with RATE_LIMIT_REDIRECT, RATE_LIMIT_MANAGEMENT
+// and RATE_LIMIT_AUTH as "rate:burst", individual API keys
+// can be given their own policy with RATE_LIMIT_API_KEYS, and connections from the networks listed in
+// RATE_LIMIT_ALLOWLIST are never limited. Client IPs are only taken from forwarding headers sent by the
+// proxies listed in TRUSTED_PROXIES, which RegisterHandlersGin passes to the router. Every limited response carries the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers; rejected requests receive HTTP 429 with a
+// Retry-After header.
+//
+// The limiters live in RateLimiterStore, which is capped by RATE_LIMIT_STORE_SIZE with LRU eviction,
+// drops limiters idle for longer than RATE_LIMIT_STORE_TTL, and is split into RATE_LIMIT_STORE_SHARDS
+// independently locked shards. Its size and eviction count are published as the "ratelimit_store"
+// expvar, served at "internal/vars" to admins.
+//
+// Limits are enforced by a ratelimit.Backend. By default it is local to the process, so limits
+// multiply with the number of replicas; SetRateLimitBackend installs a shared backend instead, such
+// as the Redis backend selected with RATE_LIMIT_BACKEND=redis. If the backend fails, the request
+// is let through rather than rejected.
 //
 // Middleware functions are registered within the Gin router setup and are executed in the
 // order they are applied to the routes.
@@ -119,9 +280,34 @@
 //
 //	func RegisterHandlersGin(router *gin.Engine, dsClient *datastore.Client) {
 //	    router.GET(basePath+":id", getURLHandlerGin(dsClient))
-//	    router.POST(basePath, InternalOnly(), postURLHandlerGin(dsClient))
-//	    router.PUT(basePath+":id", InternalOnly(), editURLHandlerGin(dsClient))
-//	    router.DELETE(basePath+":id", InternalOnly(), deleteURLHandlerGin(dsClient))
+//	    router.POST(basePath+":id", unlockURLHandlerGin(dsClient))
+//	    router.POST(basePath, InternalOnly(dsClient, ScopeCreate), Idempotent(dsClient), postURLHandlerGin(dsClient))
+//	    router.PUT(basePath+":id", InternalOnly(dsClient, ScopeEdit), editURLHandlerGin(dsClient))
+//	    router.PATCH(basePath+":id", InternalOnly(dsClient, ScopeEdit), patchURLHandlerGin(dsClient))
+//	    router.DELETE(basePath+":id", InternalOnly(dsClient, ScopeDelete), deleteURLHandlerGin(dsClient))
+//
+//	    internal := router.Group(basePath + "internal/")
+//	    internal.GET("urls", InternalOnly(dsClient, ScopeReadStats), listURLsHandlerGin(dsClient))
+//	    internal.POST("urls/:id/restore", InternalOnly(dsClient, ScopeDelete), restoreURLHandlerGin(dsClient))
+//	    internal.GET("urls/:id/history", InternalOnly(dsClient, ScopeReadStats), historyURLHandlerGin(dsClient))
+//	    internal.POST("urls/:id/rollback", InternalOnly(dsClient, ScopeEdit), rollbackURLHandlerGin(dsClient))
+//
+//	    admin := internal.Group("", InternalOnly(dsClient, ScopeAdmin))
+//	    admin.GET("export", exportURLsHandlerGin(dsClient))
+//	    admin.POST("import", importURLsHandlerGin(dsClient))
+//	    admin.GET("apikeys", listAPIKeysHandlerGin(dsClient))
+//	    admin.POST("apikeys", createAPIKeyHandlerGin(dsClient))
+//	    admin.DELETE("apikeys/:name", revokeAPIKeyHandlerGin(dsClient))
+//	    admin.GET("audit", auditLogHandlerGin())
+//	    admin.GET("webhooks", listWebhooksHandlerGin(dsClient))
+//	    admin.POST("webhooks", createWebhookHandlerGin(dsClient))
+//	    admin.DELETE("webhooks/:name", deleteWebhookHandlerGin(dsClient))
+//	    admin.GET("webhooks/deliveries", listWebhookDeliveriesHandlerGin(dsClient))
+//	    admin.GET("webhooks/deliveries/:delivery", getWebhookDeliveryHandlerGin(dsClient))
+//	    admin.POST("webhooks/deliveries/:delivery/retry", retryWebhookDeliveryHandlerGin(dsClient))
+//	    admin.GET("loglevels", listLogLevelsHandlerGin())
+//	    admin.PUT("loglevels", setLogLevelHandlerGin())
+//	    admin.DELETE("loglevels", resetLogLevelsHandlerGin())
 //	}
 //
 // The RegisterHandlersGin function is the central point for configuring the routing
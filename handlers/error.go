@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+
 	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
@@ -8,6 +11,19 @@ import (
 	"go.uber.org/zap"
 )
 
+// errUnauthorized is returned when the credentials of a management request are missing or invalid.
+// It is deliberately generic so that callers cannot tell an unknown key from a revoked or expired one.
+var errUnauthorized = errors.New(constant.HeaderResponseUnauthorized)
+
+// errNotDeleted is returned when a link that is not deleted is restored.
+var errNotDeleted = errors.New(constant.HeaderResponseURLNotDeleted)
+
+// errSubscriptionDeleted is recorded on the deliveries of a webhook subscription that no longer exists.
+var errSubscriptionDeleted = errors.New(constant.HeaderResponseWebhookNotFound)
+
+// errRevisionNotFound is returned when a link is rolled back to a revision it never had.
+var errRevisionNotFound = errors.New(constant.HeaderResponseRevisionNotFound)
+
 // URLMismatchError represents an error for when the provided URL does not match
 // the expected URL in the datastore. It embeds the error message to be returned.
 type URLMismatchError struct {
@@ -51,6 +67,18 @@ func isBadRequestError(err error) bool {
 	return ok
 }
 
+// ForbiddenError represents an error when the authenticated principal is not allowed
+// to access a link, for example because it is owned by another principal.
+type ForbiddenError struct {
+	Message string
+}
+
+// Error returns the error message of a ForbiddenError.
+// This method allows ForbiddenError to satisfy the error interface.
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
 // TODO: FriendlyError represents an error that is safe to return to the client & server (middleware).
 type FriendlyError struct {
 	Message string
@@ -75,6 +103,10 @@ func handleUpdateError(c *gin.Context, id string, err error) {
 		logBadRequest(c, id, badRequestErr)
 		return
 	}
+	if _, ok := err.(*ForbiddenError); ok {
+		logForbidden(c)
+		return
+	}
 
 	// Handle other types of errors
 	logUpdateOtherError(c, id, err)
@@ -96,6 +128,10 @@ func handleDeletionError(c *gin.Context, err error) {
 		logBadRequest(c, id, badRequestErr)
 		return
 	}
+	if _, ok := err.(*ForbiddenError); ok {
+		logForbidden(c)
+		return
+	}
 
 	// Handle other errors
 	logDeletionOtherError(c, id, err)
@@ -130,7 +166,7 @@ func SynclogSpecificError(c *gin.Context, operation string, err error) {
 
 // logOtherError logs non-specific errors.
 func SynclogOtherError(c *gin.Context, operation string, err error) {
-	logFields := createLogFieldsWithErr(operation, "", err) // Use the operation name here.
+	logFields := createLogFieldsWithErr(c.Request.Context(), operation, "", err) // Use the operation name here.
 	LogInfo(constant.ErrorEmoji+"  "+constant.UrlshortenerEmoji+"  "+constant.HeaderResponseInvalidRequest, logFields...)
 }
 
@@ -144,11 +180,11 @@ func markErrorLogged(c *gin.Context) {
 
 // handleRetrievalError logs an error message for a failed retrieval attempt and returns a formatted error.
 // If the error is a 'not found' error, it logs a specific message for that case.
-func handleRetrievalError(err error, id string) error {
+func handleRetrievalError(ctx context.Context, err error, id string) error {
 	// Add an operation name as the first argument to createLogFields.
-	logFields := createLogFields(operation_retrieveURL, id)
+	logFields := createLogFields(ctx, operation_retrieveURL, id)
 	if err == datastore.ErrNotFound {
-		LogURLNotFound(id, err)      // Pass Context to the internal logging function.
+		LogURLNotFound(ctx, id, err) // Pass Context to the internal logging function.
 		return datastore.ErrNotFound // Return the original error directly
 	}
 	logmonitor.Logger.Error(constant.SosEmoji+"  "+constant.WarningEmoji+"  "+constant.FailedToRetriveURLContextLog, logFields...)
@@ -169,10 +205,17 @@ func handleError(c *gin.Context, message string, statusCode int, err error) {
 	switch {
 	case statusCode >= 500: // 5xx errors are still logged as errors
 		emoji = constant.ErrorEmoji
-		Logger.Error(emoji+"  "+message, zap.Error(err))
+		Logger.Error(emoji+"  "+message, append([]zap.Field{zap.Error(err)}, logmonitor.ContextFields(c.Request.Context())...)...)
 	}
 
-	c.AbortWithStatusJSON(statusCode, gin.H{
-		constant.HeaderResponseError: message,
-	})
+	c.AbortWithStatusJSON(statusCode, errorBody(c, message))
+}
+
+// errorBody returns the JSON body of an error response with the given message. The ID of the request
+// is included, so that a client reporting the error can point to the log lines of the request.
+func errorBody(c *gin.Context, message string) gin.H {
+	return gin.H{
+		constant.HeaderResponseError:     message,
+		constant.HeaderResponseRequestID: logmonitor.RequestIDFromContext(c.Request.Context()),
+	}
 }
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// historyURLHandlerGin returns a Gin handler function that lists the history of a link page by page,
+// oldest revision first. Only the owner of the link or an admin may see it. The history of a deleted
+// link can still be listed until the link is purged.
+func historyURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param(constant.HeaderID)
+		limit, err := parseListLimit(c.Query(QueryLimit))
+		if err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequest, http.StatusBadRequest, nil)
+			return
+		}
+
+		url, err := datastore.GetURL(c.Request.Context(), dsClient, id)
+		if err == nil {
+			err = authorizeLinkAccess(c, url)
+		}
+		if err != nil {
+			handleUpdateError(c, id, err)
+			return
+		}
+
+		page, err := datastore.ListURLRevisions(c.Request.Context(), dsClient, id, limit, c.Query(QueryCursor))
+		if err != nil {
+			LogInternalError(c.Request.Context(), operation_historyURL, id, err)
+			handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// rollbackURLHandlerGin returns a Gin handler function that restores a link to the state it had at a
+// chosen revision. Revision 0 is the state the link was created with. The rollback is recorded as a new
+// revision, so it can be rolled back in turn. The destination of the revision is checked against the
+// policy and the scanner again, as either may have changed since it was stored.
+func rollbackURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param(constant.HeaderID)
+		var req RollbackURLPayload
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, nil)
+			return
+		}
+
+		target, err := getRollbackTarget(c, dsClient, id, *req.Revision)
+		if err != nil {
+			handleRollbackError(c, id, err)
+			return
+		}
+
+		// Reject destinations that are no longer allowed, as an edit to them would be.
+		if !checkURLPolicy(c, target.Original) {
+			return
+		}
+		scan, ok := scanURL(c, target.Original)
+		if !ok {
+			return
+		}
+
+		change := datastore.Change{Actor: principalName(c), Operation: datastore.OperationRollback, RollbackTo: *req.Revision}
+		var updated *datastore.URL
+		err = datastore.MutateURL(c.Request.Context(), dsClient, id, change, auditURLChange(c, func(url *datastore.URL) error {
+			if url.IsDeleted() {
+				return datastore.ErrNotFound
+			}
+			if err := authorizeLinkAccess(c, url); err != nil {
+				return err
+			}
+			restoreRevision(url, target)
+			applyScanResult(url, scan)
+			url.CanonicalHash = canonicalHashOf(url)
+			updated = url
+			return nil
+		}))
+		if err != nil {
+			handleUpdateError(c, id, err)
+			return
+		}
+
+		LogURLRolledBack(c.Request.Context(), id, principalName(c), *req.Revision)
+		emitLinkEvent(c.Request.Context(), dsClient, webhook.EventLinkEdited, linkEventData{Link: updated})
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// getRollbackTarget returns the state of the link at the given revision, after checking that the link
+// exists, is not deleted and may be modified by the principal. The state at revision 0 is taken from
+// before the first change.
+func getRollbackTarget(c *gin.Context, dsClient *datastore.Client, id string, revision int64) (*datastore.URL, error) {
+	url, err := getCurrentURL(c, dsClient, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeLinkAccess(c, url); err != nil {
+		return nil, err
+	}
+	if revision > url.Revision {
+		return nil, errRevisionNotFound
+	}
+	// A link that was never changed is already in its initial state.
+	if revision == 0 && url.Revision == 0 {
+		return url, nil
+	}
+
+	rev, err := datastore.GetURLRevision(c.Request.Context(), dsClient, id, max(revision, 1))
+	if err == datastore.ErrNotFound {
+		return nil, errRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if revision == 0 {
+		return &rev.Before, nil
+	}
+	return &rev.After, nil
+}
+
+// restoreRevision copies the settings of the link at a past revision onto the link. The identity of the
+// link, its owner and its revision counter are kept.
+func restoreRevision(url *datastore.URL, target *datastore.URL) {
+	url.Original = target.Original
+	url.PasswordHash = target.PasswordHash
+	url.ExpiresAt = target.ExpiresAt
+	url.RedirectStatus = target.RedirectStatus
+	url.Tags = slices.Clone(target.Tags)
+	url.Disabled = target.Disabled
+}
+
+// handleRollbackError responds to a failed rollback, using the same responses as updates
+// for missing links, forbidden access and internal errors.
+func handleRollbackError(c *gin.Context, id string, err error) {
+	if err == errRevisionNotFound {
+		handleError(c, constant.HeaderResponseRevisionNotFound, http.StatusNotFound, nil)
+		return
+	}
+	handleUpdateError(c, id, err)
+}
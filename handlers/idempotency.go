@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyKeyTTL is how long the response to a request with an Idempotency-Key is replayed.
+// It is configured with IDEMPOTENCY_KEY_TTL during package initialization.
+var idempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyKeyLease is how long a request with an Idempotency-Key holds the key while it is processed.
+// If the request neither completes nor fails within the lease, for example because the instance crashed,
+// the key can be claimed again by a retry. It is configured with IDEMPOTENCY_KEY_LEASE during package initialization.
+var idempotencyKeyLease = time.Minute
+
+// Define the limits of idempotent requests.
+const (
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+// responseRecorder copies the body written by the handlers, so that it can be stored for replay.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes the data to the connection and records it.
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the string to the connection and records it.
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent creates a middleware that makes a route safe to retry. When a request carries an
+// Idempotency-Key header, its response is stored for IDEMPOTENCY_KEY_TTL, and requests with the same
+// key and the same body receive the stored response, with an Idempotent-Replayed header, instead of
+// being processed again. Reusing a key with a different body is rejected with 422 Unprocessable Entity,
+// and a retry arriving while the first request is still processed is rejected with 409 Conflict, unless
+// the first request has held the key for longer than IDEMPOTENCY_KEY_LEASE, in which case it is presumed
+// lost and the retry is processed.
+//
+// Keys are scoped to the authenticated principal, so the middleware must run after InternalOnly.
+// Server errors and rate limited responses are not stored, so such requests can be retried with the same key.
+func Idempotent(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(constant.HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		record, ok := claimIdempotencyKey(c, dsClient, key)
+		if !ok {
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		finishIdempotentRequest(c, dsClient, record, recorder)
+	}
+}
+
+// claimIdempotencyKey records the key as in progress, or answers the request from the existing record.
+// It returns false if the request has been answered and must not be processed.
+func claimIdempotencyKey(c *gin.Context, dsClient *datastore.Client, key string) (*datastore.IdempotencyRecord, bool) {
+	if len(key) > maxIdempotencyKeyLength {
+		handleError(c, constant.HeaderResponseInvalidIdempotencyKey, http.StatusBadRequest, nil)
+		return nil, false
+	}
+	requestHash, err := hashRequest(c)
+	if err != nil {
+		handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, nil)
+		return nil, false
+	}
+
+	now := time.Now()
+	record := &datastore.IdempotencyRecord{
+		Name:         principalName(c) + ":" + key,
+		RequestHash:  requestHash,
+		CreatedAt:    now,
+		PendingUntil: now.Add(idempotencyKeyLease),
+		ExpiresAt:    now.Add(idempotencyKeyTTL),
+	}
+	existing, err := datastore.ClaimIdempotencyKey(c.Request.Context(), dsClient, record)
+	if err != nil {
+		handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if existing != nil {
+		replayIdempotentResponse(c, existing, requestHash)
+		return nil, false
+	}
+	return record, true
+}
+
+// hashRequest returns the hash of the method, path and body of the request, leaving the body readable
+// for the handlers.
+func hashRequest(c *gin.Context) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// replayIdempotentResponse answers a request whose key has already been used.
+func replayIdempotentResponse(c *gin.Context, existing *datastore.IdempotencyRecord, requestHash string) {
+	switch {
+	case existing.RequestHash != requestHash:
+		LogIdempotencyKeyMismatch(c.Request.Context(), existing.Name)
+		handleError(c, constant.HeaderResponseIdempotencyKeyMismatch, http.StatusUnprocessableEntity, nil)
+	case !existing.IsCompleted():
+		handleError(c, constant.HeaderResponseIdempotencyKeyInProgress, http.StatusConflict, nil)
+	default:
+		LogIdempotentReplay(c.Request.Context(), existing.Name)
+		c.Header(constant.HeaderIdempotentReplayed, "true")
+		c.Data(existing.StatusCode, constant.ContentTypeJSON, existing.Body)
+		c.Abort()
+	}
+}
+
+// finishIdempotentRequest stores the response for replay, or releases the key if the request may be retried.
+// It uses a context that is not canceled with the request, so that the outcome is recorded even if the
+// client disconnected, which is exactly the case retries are meant for.
+func finishIdempotentRequest(c *gin.Context, dsClient *datastore.Client, record *datastore.IdempotencyRecord, recorder *responseRecorder) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		datastore.ReleaseIdempotencyKey(ctx, dsClient, record)
+		return
+	}
+
+	record.StatusCode = status
+	record.Body = recorder.body.Bytes()
+	datastore.CompleteIdempotencyKey(ctx, dsClient, record)
+}
@@ -2,13 +2,19 @@ package handlers
 
 import (
 	"context"
+	"expvar"
+	"net"
 	"os"
 	"strings"
-	"sync"
+	"time"
 
+	"github.com/H0llyW00dzZ/go-urlshortner/canonical"
 	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
-	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
+	"github.com/H0llyW00dzZ/go-urlshortner/ratelimit"
 	"github.com/H0llyW00dzZ/go-urlshortner/shortid"
+	"github.com/H0llyW00dzZ/go-urlshortner/urlpolicy"
+	"github.com/H0llyW00dzZ/go-urlshortner/webhook"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
@@ -20,19 +26,23 @@ var id string
 // It is set once during package initialization.
 var basePath string
 
-// internalSecretValue is a package-level variable that stores the secret value required by the InternalOnly middleware.
-// It is set once during package initialization.
+// internalSecretValue is a package-level variable that stores the legacy shared secret accepted by the InternalOnly middleware.
+// It is set once during package initialization and is optional now that named API keys are supported.
 var internalSecretValue string
 
+// trustedProxies is a package-level variable that stores the networks of the reverse proxies whose
+// X-Forwarded-For and X-Real-IP headers are believed. It is empty unless TRUSTED_PROXIES is set,
+// so that by default the client IP is the address of the connection and cannot be spoofed.
+var trustedProxies []string
+
 // RateLimiterStore stores the rate limiters for each client, identified by a key such as an IP address.
+// It is bounded and evicts idle limiters, so a flood of spoofed addresses cannot exhaust memory.
 //
 // Note: This var are contains filtered in docs indicates that explicit unreadable for human 🏴‍☠️
-var RateLimiterStore = struct {
-	sync.RWMutex
-	limiters map[string]*rate.Limiter
-}{limiters: make(map[string]*rate.Limiter)}
+var RateLimiterStore *ratelimit.Store
 
 func init() {
+	var err error
 
 	// Initialize the base path from an environment variable or use "/" as default.
 	basePath = os.Getenv(CUSTOM_BASE_PATH)
@@ -44,25 +54,122 @@ func init() {
 		basePath += PathObjectBasePath
 	}
 
-	// Initialize the internal secret value from an environment variable.
-	// Note: This is important and secure because it resides deep within the binary internals and should not be left unset in production.
+	// Initialize the legacy internal secret value from an environment variable.
+	// Note: When it is left unset, management requests can only be authenticated with named API keys.
 	internalSecretValue = os.Getenv(INTERNAL_SECRET_VALUE)
-	if internalSecretValue == "" {
-		panic(constant.InternelSecretEnvContextLog)
+
+	// Initialize the trusted proxies from an environment variable.
+	// Note: The entries are validated here, so that SetTrustedProxies cannot fail in RegisterHandlersGin.
+	if trustedProxies, err = parseTrustedProxies(os.Getenv(TRUSTED_PROXIES)); err != nil {
+		panic(err)
+	}
+
+	// Initialize the rate limits from environment variables, keeping the defaults for those left unset.
+	// Note: A malformed value is a deployment mistake, so it stops the service instead of being ignored.
+	if err := loadRateLimitConfig(); err != nil {
+		panic(err)
+	}
+
+	// Initialize the rate limiter store, whose size and evictions are published as the "ratelimit_store" expvar
+	// and as metrics.
+	RateLimiterStore = ratelimit.NewStore(rateLimitStoreSize, rateLimitStoreShards, rateLimitStoreTTL)
+	RateLimiterStore.Publish(RateLimitStoreVar)
+	metrics.ObserveRateLimitStore(RateLimiterStore)
+	rateLimitBackend = ratelimit.NewLocalBackend(RateLimiterStore)
+
+	// Initialize the destination URL policy from environment variables.
+	policyConfig, err := urlpolicy.NewConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	urlPolicy = urlpolicy.New(policyConfig)
+
+	// Initialize how long responses to requests with an Idempotency-Key are replayed, and how long a
+	// request holds its key while it is processed.
+	if err := parseEnvDuration(IDEMPOTENCY_KEY_TTL, &idempotencyKeyTTL); err != nil {
+		panic(err)
+	}
+	if err := parseEnvDuration(IDEMPOTENCY_KEY_LEASE, &idempotencyKeyLease); err != nil {
+		panic(err)
+	}
+
+	// Initialize how long deleted links are kept before they are purged, and how often the purge runs.
+	if err := parseEnvDuration(URL_DELETE_RETENTION, &deleteRetention); err != nil {
+		panic(err)
 	}
+	if err := parseEnvDuration(URL_PURGE_INTERVAL, &purgeInterval); err != nil {
+		panic(err)
+	}
+
+	// Initialize the canonicalization of destination URLs, which is disabled by default.
+	if canonicalConfig, err = canonical.NewConfigFromEnv(); err != nil {
+		panic(err)
+	}
+
+	// Initialize the delivery of webhooks, and the click thresholds that trigger link.clicked events.
+	if webhookConfig, err = webhook.NewConfigFromEnv(); err != nil {
+		panic(err)
+	}
+	// Every connection is checked against the URL policy in force, so that an endpoint cannot be
+	// rebound to an internal address after it passed the policy.
+	webhookSender = webhook.NewSender(webhookConfig.Timeout, func(ip net.IP) error {
+		return urlPolicy.CheckIP(ip)
+	})
 }
 
 // RegisterHandlersGin registers the HTTP handlers for the URL shortener service using the Gin
 // web framework. It sets up the routes for retrieving, creating, and updating shortened URLs.
-// The InternalOnly middleware is applied to the POST and PUT routes to protect them from public access.
+// The InternalOnly middleware is applied to the POST, PUT and DELETE routes to protect them from
+// public access, each requiring the matching scope; the internal routes require the admin scope.
+// It also restricts the proxies whose headers the router believes to TRUSTED_PROXIES.
 func RegisterHandlersGin(router *gin.Engine, datastoreClient *datastore.Client) {
+	// Gin trusts the forwarding headers of every peer by default, which would let any client choose
+	// the IP its rate limits and audit events are recorded under.
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		panic(err)
+	}
+
 	// Register handlers with the custom or default base path.
 	// For example, if CUSTOM_BASE_PATH is "/api/", the GET route will be "/api/:id",
 	// the POST route will be "/api/", and the PUT route will be "/api/:id".
 	router.GET(basePath+PathObjectID, getURLHandlerGin(datastoreClient))
-	router.POST(basePath, InternalOnly(), postURLHandlerGin(datastoreClient))
-	router.PUT(basePath+PathObjectID, InternalOnly(), editURLHandlerGin(datastoreClient))      // New PUT route for editing URLs
-	router.DELETE(basePath+PathObjectID, InternalOnly(), deleteURLHandlerGin(datastoreClient)) // New DELETE route for deleting URLs
+	router.POST(basePath+PathObjectID, unlockURLHandlerGin(datastoreClient)) // Unlock form of password-protected links
+	router.POST(basePath, InternalOnly(datastoreClient, ScopeCreate), Idempotent(datastoreClient), postURLHandlerGin(datastoreClient))
+	router.PUT(basePath+PathObjectID, InternalOnly(datastoreClient, ScopeEdit), editURLHandlerGin(datastoreClient))        // New PUT route for editing URLs
+	router.PATCH(basePath+PathObjectID, InternalOnly(datastoreClient, ScopeEdit), patchURLHandlerGin(datastoreClient))     // Partial updates with JSON Merge Patch
+	router.DELETE(basePath+PathObjectID, InternalOnly(datastoreClient, ScopeDelete), deleteURLHandlerGin(datastoreClient)) // New DELETE route for deleting URLs
+
+	// Internal routes are grouped under the base path, for example "/api/internal/export".
+	// Listing links, with their click counts, requires the read-stats scope; callers see their own links
+	// unless they are admins.
+	internal := router.Group(basePath + PathObjectInternal)
+	internal.GET(PathObjectURLs, InternalOnly(datastoreClient, ScopeReadStats), listURLsHandlerGin(datastoreClient))
+	// Deleted links can be restored by whoever may delete them, until they are purged.
+	internal.POST(PathObjectURLs+"/"+PathObjectID+"/"+PathObjectRestore, InternalOnly(datastoreClient, ScopeDelete), restoreURLHandlerGin(datastoreClient))
+	// The history of a link is visible to whoever may read the statistics of the link; rolling it back is an edit.
+	internal.GET(PathObjectURLs+"/"+PathObjectID+"/"+PathObjectHistory, InternalOnly(datastoreClient, ScopeReadStats), historyURLHandlerGin(datastoreClient))
+	internal.POST(PathObjectURLs+"/"+PathObjectID+"/"+PathObjectRollback, InternalOnly(datastoreClient, ScopeEdit), rollbackURLHandlerGin(datastoreClient))
+
+	admin := internal.Group("", InternalOnly(datastoreClient, ScopeAdmin))
+	admin.GET(PathObjectExport, exportURLsHandlerGin(datastoreClient))
+	admin.POST(PathObjectImport, importURLsHandlerGin(datastoreClient))
+	admin.GET(PathObjectAPIKeys, listAPIKeysHandlerGin(datastoreClient))
+	admin.POST(PathObjectAPIKeys, createAPIKeyHandlerGin(datastoreClient))
+	admin.DELETE(PathObjectAPIKeys+"/"+PathObjectName, revokeAPIKeyHandlerGin(datastoreClient))
+	admin.GET(PathObjectVars, gin.WrapH(expvar.Handler()))
+	admin.GET(PathObjectAudit, auditLogHandlerGin())
+	admin.GET(PathObjectWebhooks, listWebhooksHandlerGin(datastoreClient))
+	admin.POST(PathObjectWebhooks, createWebhookHandlerGin(datastoreClient))
+	admin.DELETE(PathObjectWebhooks+"/"+PathObjectName, deleteWebhookHandlerGin(datastoreClient))
+	admin.GET(PathObjectWebhooks+"/"+PathObjectDeliveries, listWebhookDeliveriesHandlerGin(datastoreClient))
+	admin.GET(PathObjectWebhooks+"/"+PathObjectDeliveries+"/"+PathObjectDelivery, getWebhookDeliveryHandlerGin(datastoreClient))
+	admin.POST(PathObjectWebhooks+"/"+PathObjectDeliveries+"/"+PathObjectDelivery+"/"+PathObjectRetry, retryWebhookDeliveryHandlerGin(datastoreClient))
+	admin.GET(PathObjectLogLevels, listLogLevelsHandlerGin())
+	admin.PUT(PathObjectLogLevels, setLogLevelHandlerGin())
+	admin.DELETE(PathObjectLogLevels, resetLogLevelsHandlerGin())
+
+	// Exports carry password hashes, and the audit log tells who did what, so reading either is audited too.
+	auditReads(admin, PathObjectExport, PathObjectAudit)
 }
 
 // generateShortID generates a unique short identifier for a URL.
@@ -84,14 +191,26 @@ func generateShortID(ctx context.Context, dsClient *datastore.Client) (string, e
 
 // NewRateLimiter creates a new rate limiter for a client if it doesn't exist, or returns the existing one.
 func NewRateLimiter(key string, r rate.Limit, b int) *rate.Limiter {
-	RateLimiterStore.Lock()
-	defer RateLimiterStore.Unlock()
-
-	limiter, exists := RateLimiterStore.limiters[key]
-	if !exists {
-		limiter = rate.NewLimiter(r, b)
-		RateLimiterStore.limiters[key] = limiter
-	}
+	return RateLimiterStore.Get(key, func() *rate.Limiter {
+		return rate.NewLimiter(r, b)
+	})
+}
 
-	return limiter
+// StartRateLimiterJanitor periodically removes idle limiters from RateLimiterStore, so that memory is
+// released even when no new clients arrive. It returns a function that stops the janitor.
+func StartRateLimiterJanitor(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				RateLimiterStore.Sweep()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
 }
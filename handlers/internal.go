@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
 	"github.com/gin-gonic/gin"
 )
@@ -14,30 +15,109 @@ func isValidURL(urlStr string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
-// InternalOnly creates a middleware that restricts access to a route to internal services only.
-// It checks for a specific header containing a secret value that should match an environment
-// variable to allow the request to proceed. If the secret does not match or is not provided,
-// the request is aborted with a 403 Forbidden status.
+// InternalOnly creates a middleware that restricts access to a route to authenticated callers only.
+// Callers authenticate with a named API key in the X-API-Key header, which is verified against its
+// stored hash in constant time and must not be expired or revoked. When a token verifier has been
+// set with SetTokenVerifier, an "Authorization: Bearer" JWT is accepted as well, and its claims are
+// mapped to scopes. For backwards compatibility, the
+// shared INTERNAL_SECRET_VALUE in the X-Internal-Secret header is still accepted when it is configured,
+// and is granted every scope. If the caller cannot be authenticated, the request is aborted with a
+// 401 Unauthorized status; if it lacks one of the required scopes, it is aborted with a 403 Forbidden status.
 //
-// Additionally, this middleware enforces rate limiting to prevent abuse.
-func InternalOnly() gin.HandlerFunc {
+// The authenticated principal is stored in the Gin context, so its name is attached to request logs
+// and to the ownership of the links it creates.
+//
+// Additionally, this middleware enforces a per-IP rate limit before authenticating the caller and the
+// management rate limit of the principal afterwards to prevent abuse,
+// and records each request that changes something in the audit log, once it has been handled.
+func InternalOnly(dsClient *datastore.Client, scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Check the request header against the expected secret value.
-		if c.GetHeader(constant.HeaderXinternalSecret) != internalSecretValue {
-			// If the header does not match the expected secret, abort the request.
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				constant.HeaderResponseError: constant.HeaderResponseForbidden,
-			})
+		// Limit each client IP before the credentials are checked, as checking an API key reads Datastore.
+		// Requests rejected here are not audited, so that a flood cannot fill the audit log; the rate
+		// limiter logs and counts them instead.
+		if !applyAuthRateLimit(c) {
+			return
+		}
+
+		// Every other outcome is audited, including requests that are rejected below.
+		defer auditRequest(c)
+
+		principal, err := authenticate(c, dsClient)
+		if err != nil {
+			handleAuthError(c, err)
+			return
+		}
+
+		if !hasScopes(principal, scopes) {
+			LogForbidden(c.Request.Context(), principal.Name, scopes)
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, constant.HeaderResponseForbidden))
 			return
 		}
+		c.Set(constant.GinContextPrincipal, principal)
+		c.Set(constant.GinContextPrincipalName, principal.Name)
 
 		// Check if the request is allowed by the rate limiter.
-		if !applyRateLimit(c) {
+		if !applyManagementRateLimit(c, principal) {
 			// If the rate limit is exceeded, we should not continue processing the request.
 			return
 		}
 
-		// If the header matches and the rate limiter allows it, proceed with the request.
+		// If the caller is authenticated, authorized and the rate limiter allows it, proceed with the request.
 		c.Next()
 	}
 }
+
+// authenticate resolves the principal making the request from its bearer token, its API key,
+// or the legacy shared secret, in that order.
+func authenticate(c *gin.Context, dsClient *datastore.Client) (*Principal, error) {
+	if token, ok := bearerToken(c); ok && tokenVerifier != nil {
+		return verifyBearerToken(c, token)
+	}
+	if apiKey := c.GetHeader(constant.HeaderXAPIKey); apiKey != "" {
+		return verifyAPIKey(c.Request.Context(), dsClient, apiKey)
+	}
+
+	secret := c.GetHeader(constant.HeaderXinternalSecret)
+	if internalSecretValue != "" && secret != "" && secretsEqual(secret, internalSecretValue) {
+		return &Principal{Name: InternalSecretPrincipal, Scopes: []string{ScopeAdmin}}, nil
+	}
+	return nil, errUnauthorized
+}
+
+// hasScopes reports whether the principal holds every one of the required scopes.
+func hasScopes(principal *Principal, scopes []string) bool {
+	for _, scope := range scopes {
+		if !principal.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleAuthError responds to a request whose credentials could not be verified.
+func handleAuthError(c *gin.Context, err error) {
+	if err != errUnauthorized {
+		LogInternalError(c.Request.Context(), operation_authenticate, "", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorBody(c, constant.HeaderResponseInternalServerError))
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, errorBody(c, constant.HeaderResponseUnauthorized))
+}
+
+// principalFromContext returns the principal stored by InternalOnly, or nil if there is none.
+func principalFromContext(c *gin.Context) *Principal {
+	if v, ok := c.Get(constant.GinContextPrincipal); ok {
+		if principal, ok := v.(*Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// principalName returns the name of the principal stored by InternalOnly, or an empty string if there is none.
+func principalName(c *gin.Context) string {
+	if principal := principalFromContext(c); principal != nil {
+		return principal.Name
+	}
+	return ""
+}
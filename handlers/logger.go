@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/scanner"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Logger is a package-level variable to access the zap logger throughout the handlers package.
@@ -31,26 +35,26 @@ func logErrorWithEmoji(emoji string, context string, fields ...zap.Field) {
 }
 
 // logAttemptToRetrieve logs an informational message indicating an attempt to retrieve the current URL by ID.
-func logAttemptToRetrieve(id string) {
-	logFields := createLogFields(operation_retrieve, id) // Provide a default operation name
+func logAttemptToRetrieve(ctx context.Context, id string) {
+	logFields := createLogFields(ctx, operation_retrieve, id) // Provide a default operation name
 	Logger.Info(constant.AlertEmoji+"  "+constant.WarningEmoji+"  "+constant.InfoAttemptingToRetrieveTheCurrentURL, logFields...)
 }
 
 // LogMismatchError logs a message indicating that there is a mismatch error.
-func LogMismatchError(id string) {
-	logFields := createLogFields(operation_mismatch_error, id)
+func LogMismatchError(ctx context.Context, id string) {
+	logFields := createLogFields(ctx, operation_mismatch_error, id)
 	Logger.Info(constant.ErrorEmoji+" "+constant.UrlshortenerEmoji+" "+constant.HeaderResponseInvalidRequestPayload, logFields...)
 }
 
 // logAttemptToUpdate logs an informational message indicating an attempt to update a URL in the datastore.
-func logAttemptToUpdate(id string) {
-	logFields := createLogFields(operation_update_attempt, id) // Provide a default operation name
+func logAttemptToUpdate(ctx context.Context, id string) {
+	logFields := createLogFields(ctx, operation_update_attempt, id) // Provide a default operation name
 	Logger.Info(constant.AlertEmoji+"  "+constant.WarningEmoji+"  "+datastore.InfoAttemptingToUpdateURLInDatastore, logFields...)
 }
 
 // logSuccessfulUpdate logs an informational message indicating a successful update of a URL in the datastore.
-func logSuccessfulUpdate(id string) {
-	logFields := createLogFields(operation_successful_update, id) // Provide a default operation name
+func logSuccessfulUpdate(ctx context.Context, id string) {
+	logFields := createLogFields(ctx, operation_successful_update, id) // Provide a default operation name
 	Logger.Info(constant.UrlshortenerEmoji+"  "+constant.UpdateEmoji+"  "+constant.SuccessEmoji+"  "+datastore.InfoUpdateSuccessful, logFields...)
 }
 
@@ -65,58 +69,385 @@ func LogError(context string, fields ...zap.Field) {
 }
 
 // LogURLNotFound logs a "URL not found" error.
-func LogURLNotFound(id string, err error) {
-	fields := createLogFieldsWithErr(operation_getURL, id, err)
+func LogURLNotFound(ctx context.Context, id string, err error) {
+	fields := createLogFieldsWithErr(ctx, operation_getURL, id, err)
 	logInfoWithEmoji(constant.GetBackEmoji+" "+constant.UrlshortenerEmoji, constant.URLnotfoundContextLog, fields...)
 }
 
 // LogInternalError logs an internal server error.
-func LogInternalError(context string, id string, err error) {
-	fields := createLogFieldsWithErr(context, id, err)
+func LogInternalError(ctx context.Context, context string, id string, err error) {
+	fields := createLogFieldsWithErr(ctx, context, id, err)
 	logErrorWithEmoji(constant.SosEmoji+" "+constant.WarningEmoji, constant.FailedToGetURLContextLog, fields...)
 }
 
 // LogURLRetrievalSuccess logs a successful URL retrieval.
-func LogURLRetrievalSuccess(id string) {
-	logFields := createLogFields(operation_getURL, id) // Now correctly using two arguments
+func LogURLRetrievalSuccess(ctx context.Context, id string) {
+	logFields := createLogFields(ctx, operation_getURL, id) // Now correctly using two arguments
 	Logger.Info(constant.UrlshortenerEmoji+"  "+constant.RedirectEmoji+"  "+constant.SuccessEmoji+"  "+constant.URLRetriveContextLog, logFields...)
 }
 
 // LogInvalidURLFormat logs a message indicating that the URL format is invalid.
-func LogInvalidURLFormat(url string) {
+func LogInvalidURLFormat(ctx context.Context, url string) {
 	Logger.Info(constant.ErrorEmoji+"  "+constant.UrlshortenerEmoji+"  "+constant.HeaderResponseInvalidURLFormat,
 		// for the rest of the constant, just leave this as it is, don't use the constant
-		zap.String("url", url),
+		append([]zap.Field{zap.String("url", url)}, logmonitor.ContextFields(ctx)...)...,
 	)
 }
 
 // LogBadRequestError logs a message indicating a bad request error.
-func LogBadRequestError(context string, err error) {
-	fields := createLogFieldsWithErr(context, "", err) // Assuming an empty ID for general bad requests
+func LogBadRequestError(ctx context.Context, context string, err error) {
+	fields := createLogFieldsWithErr(ctx, context, "", err) // Assuming an empty ID for general bad requests
 	logInfoWithEmoji(constant.ErrorEmoji+" "+constant.UrlshortenerEmoji, constant.HeaderResponseInvalidRequestPayload, fields...)
 }
 
-// LogURLShortened logs a message indicating that a URL has been successfully shortened.
-func LogURLShortened(id string) {
-	logFields := createLogFields(operation_shorten_url, id)
+// LogURLShortened logs a message indicating that a URL has been successfully shortened by the given owner.
+func LogURLShortened(ctx context.Context, id string, owner string) {
+	logFields := append(createLogFields(ctx, operation_shorten_url, id), zap.String("owner", owner))
 	Logger.Info(constant.UrlshortenerEmoji+"  "+constant.SuccessEmoji+"  "+constant.URLShorteneredContextLog, logFields...)
 }
 
+// LogURLReused logs a message indicating that an existing link was returned instead of creating a new one.
+func LogURLReused(ctx context.Context, id string, owner string) {
+	logFields := append(createLogFields(ctx, operation_shorten_url, id), zap.String("owner", owner))
+	Logger.Info(constant.UrlshortenerEmoji+"  "+constant.SuccessEmoji+"  "+constant.URLReusedContextLog, logFields...)
+}
+
+// LogIdempotentReplay logs a message indicating that a stored response was replayed for an idempotency key.
+func LogIdempotentReplay(ctx context.Context, name string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_idempotency,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("idempotency_key", name)),
+	)
+	logInfoWithEmoji(constant.InfoEmoji, constant.IdempotentReplayContextLog, logFields...)
+}
+
+// LogIdempotencyKeyMismatch logs a message indicating that an idempotency key was reused with a different request.
+func LogIdempotencyKeyMismatch(ctx context.Context, name string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_idempotency,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("idempotency_key", name)),
+	)
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.IdempotencyKeyMismatchContextLog, logFields...)
+}
+
+// LogURLPatched logs a message indicating that a link was partially updated.
+func LogURLPatched(ctx context.Context, id string, principal string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_patchURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+		logmonitor.WithPrincipal(principal),
+	)
+	logInfoWithEmoji(constant.SuccessEmoji, constant.URLPatchedContextLog, logFields...)
+}
+
+// LogLinkUnavailable logs a message indicating that a visitor followed an expired or disabled link.
+func LogLinkUnavailable(ctx context.Context, id string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_getURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+	)
+	logInfoWithEmoji(constant.AlertEmoji+"  "+constant.WarningEmoji, constant.LinkUnavailableContextLog, logFields...)
+}
+
+// LogURLRestored logs a message indicating that a deleted link was restored.
+func LogURLRestored(ctx context.Context, id string, principal string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_restoreURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+		logmonitor.WithPrincipal(principal),
+	)
+	logInfoWithEmoji(constant.SuccessEmoji, constant.URLRestoredContextLog, logFields...)
+}
+
+// LogURLRolledBack logs a message indicating that a link was rolled back to a previous revision.
+func LogURLRolledBack(ctx context.Context, id string, principal string, revision int64) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_rollbackURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+		logmonitor.WithPrincipal(principal),
+		logmonitor.WithAnyZapField(zap.Int64("revision", revision)),
+	)
+	logInfoWithEmoji(constant.SuccessEmoji, constant.URLRolledBackContextLog, logFields...)
+}
+
+// LogAuditWriteFailed logs a message indicating that the audit event of a request could not be stored.
+func LogAuditWriteFailed(ctx context.Context, operation string, id string, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_audit,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+		logmonitor.WithAnyZapField(zap.String("request", operation)),
+		logmonitor.WithError(err),
+	)
+	logErrorWithEmoji(constant.SosEmoji+" "+constant.WarningEmoji, constant.AuditWriteFailedContextLog, logFields...)
+}
+
+// LogWebhookCreated logs a message indicating that a webhook subscription has been created.
+func LogWebhookCreated(ctx context.Context, name string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_webhook,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("subscription", name)),
+	)
+	logInfoWithEmoji(constant.NewEmoji+"  "+constant.SuccessEmoji, constant.WebhookCreatedContextLog, logFields...)
+}
+
+// LogWebhookDeleted logs a message indicating that a webhook subscription has been deleted.
+func LogWebhookDeleted(ctx context.Context, name string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_webhook,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("subscription", name)),
+	)
+	logInfoWithEmoji(constant.DeleteEmoji+"  "+constant.SuccessEmoji, constant.WebhookDeletedContextLog, logFields...)
+}
+
+// LogLevelChanged logs a message indicating that the log level of a package has been changed for a while.
+// It is logged at the warn level, so that it is written whatever the new level is.
+func LogLevelChanged(ctx context.Context, principal string, pkg string, level zapcore.Level, duration time.Duration) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_logLevel,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithPrincipal(principal),
+		logmonitor.WithAnyZapField(zap.String("package", pkg)),
+		logmonitor.WithAnyZapField(zap.Stringer("level", level)),
+		logmonitor.WithAnyZapField(zap.Duration("revert_after", duration)),
+	)
+	Logger.Warn(constant.AlertEmoji+"  "+constant.UpdateEmoji+"  "+constant.LogLevelChangedContextLog, logFields...)
+}
+
+// LogLevelsReset logs a message indicating that every package has been set back to the configured log level.
+func LogLevelsReset(ctx context.Context, principal string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_logLevel,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithPrincipal(principal),
+	)
+	Logger.Warn(constant.AlertEmoji+"  "+constant.UpdateEmoji+"  "+constant.LogLevelsResetContextLog, logFields...)
+}
+
+// LogWebhookEnqueueFailed logs a message indicating that the deliveries of a link event could not be queued.
+func LogWebhookEnqueueFailed(ctx context.Context, eventType string, id string, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_webhook,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+		logmonitor.WithAnyZapField(zap.String("event", eventType)),
+		logmonitor.WithError(err),
+	)
+	logErrorWithEmoji(constant.ErrorEmoji, constant.WebhookEnqueueFailedContextLog, logFields...)
+}
+
+// LogWebhookAttemptFailed logs a message indicating that an attempt of a webhook delivery failed and will be retried.
+func LogWebhookAttemptFailed(ctx context.Context, id string, subscription string, attempts int, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_webhook,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("delivery", id)),
+		logmonitor.WithAnyZapField(zap.String("subscription", subscription)),
+		logmonitor.WithAnyZapField(zap.Int("attempts", attempts)),
+		logmonitor.WithError(err),
+	)
+	logInfoWithEmoji(constant.WarningEmoji, constant.WebhookAttemptFailedContextLog, logFields...)
+}
+
+// LogWebhookDeadLettered logs a message indicating that a webhook delivery was given up on.
+func LogWebhookDeadLettered(ctx context.Context, id string, subscription string, attempts int, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_webhook,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("delivery", id)),
+		logmonitor.WithAnyZapField(zap.String("subscription", subscription)),
+		logmonitor.WithAnyZapField(zap.Int("attempts", attempts)),
+		logmonitor.WithError(err),
+	)
+	logErrorWithEmoji(constant.SosEmoji+" "+constant.WarningEmoji, constant.WebhookDeadLetteredContextLog, logFields...)
+}
+
+// LogClickFlushFailed logs a message indicating that the accumulated clicks of a link could not be stored.
+func LogClickFlushFailed(ctx context.Context, id string, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_clicks,
+		logmonitor.WithComponent(constant.ComponentNoSQL),
+		logmonitor.WithID(id),
+		logmonitor.WithError(err),
+	)
+	logErrorWithEmoji(constant.ErrorEmoji, constant.ClickFlushFailedContextLog, logFields...)
+}
+
+// LogDeletedURLsPurged logs the outcome of a purge of deleted links. Runs that purge nothing are not logged.
+func LogDeletedURLsPurged(ctx context.Context, purged int, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_purgeURLs,
+		logmonitor.WithComponent(constant.ComponentNoSQL),
+		logmonitor.WithAnyZapField(zap.Int("purged", purged)),
+	)
+	if err != nil {
+		logErrorWithEmoji(constant.ErrorEmoji, constant.FailedToPurgeURLsContextLog, append(logFields, zap.Error(err))...)
+		return
+	}
+	if purged > 0 {
+		logInfoWithEmoji(constant.InfoEmoji, constant.DeletedURLsPurgedContextLog, logFields...)
+	}
+}
+
 // LogDeletionError logs a message indicating that there was an error during deletion.
-func LogDeletionError(id string, err error) {
-	logFields := createLogFieldsWithErr(operation_delete_url, id, err)
+func LogDeletionError(ctx context.Context, id string, err error) {
+	logFields := createLogFieldsWithErr(ctx, operation_delete_url, id, err)
 	LogInfo(constant.ErrorEmoji+"  "+constant.UrlshortenerEmoji+"  "+constant.ErrorDuringDeletionContextLog, logFields...)
 }
 
 // LogURLDeletionSuccess logs a message indicating that a URL has been successfully deleted.
-func LogURLDeletionSuccess(id string) {
-	logFields := createLogFields(operation_delete_url, id)
+func LogURLDeletionSuccess(ctx context.Context, id string) {
+	logFields := createLogFields(ctx, operation_delete_url, id)
 	Logger.Info(constant.UrlshortenerEmoji+"  "+constant.SuccessEmoji+"  "+constant.URLDeletedSuccessfullyContextLog, logFields...)
 }
 
+// LogURLsExported logs a message indicating that the URL entities have been exported.
+func LogURLsExported(ctx context.Context, format string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_exportURL,
+		logmonitor.WithComponent(constant.ComponentNoSQL),
+		logmonitor.WithAnyZapField(zap.String("format", format)),
+	)
+	Logger.Info(constant.UrlshortenerEmoji+"  "+constant.SuccessEmoji+"  "+constant.URLsExportedContextLog, logFields...)
+}
+
+// LogURLsImported logs a message summarizing an import run.
+func LogURLsImported(ctx context.Context, total int, imported int, dryRun bool) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_importURL,
+		logmonitor.WithComponent(constant.ComponentNoSQL),
+		logmonitor.WithAnyZapField(zap.Int("total", total)),
+		logmonitor.WithAnyZapField(zap.Int("imported", imported)),
+		logmonitor.WithAnyZapField(zap.Bool("dry_run", dryRun)),
+	)
+	Logger.Info(constant.UrlshortenerEmoji+"  "+constant.NewEmoji+"  "+constant.URLsImportedContextLog, logFields...)
+}
+
+// LogTransferError logs an error that occurred while importing or exporting URL entities.
+func LogTransferError(ctx context.Context, operation string, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation,
+		logmonitor.WithComponent(constant.ComponentNoSQL),
+		logmonitor.WithError(err),
+	)
+	logErrorWithEmoji(constant.SosEmoji+"  "+constant.WarningEmoji, constant.FailedToTransferURLsContextLog, logFields...)
+}
+
+// LogForbidden logs a message indicating that a principal lacks the scopes required by a route.
+func LogForbidden(ctx context.Context, name string, scopes []string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_authorize,
+		logmonitor.WithComponent(constant.ComponentAuth),
+		logmonitor.WithPrincipal(name),
+		logmonitor.WithAnyZapField(zap.Strings("required_scopes", scopes)),
+	)
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.ForbiddenContextLog, logFields...)
+}
+
+// LogOwnershipDenied logs a message indicating that a principal tried to access links it does not own.
+func LogOwnershipDenied(ctx context.Context, id string, name string, owner string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_authorize,
+		logmonitor.WithComponent(constant.ComponentAuth),
+		logmonitor.WithID(id),
+		logmonitor.WithPrincipal(name),
+		logmonitor.WithAnyZapField(zap.String("owner", owner)),
+	)
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.OwnershipDeniedContextLog, logFields...)
+}
+
+// LogIncorrectLinkPassword logs a message indicating that a wrong password was submitted for a protected link.
+func LogIncorrectLinkPassword(ctx context.Context, id string, clientIP string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_unlockURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+		logmonitor.WithAnyZapField(zap.String("client_ip", clientIP)),
+	)
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.IncorrectLinkPasswordContextLog, logFields...)
+}
+
+// LogUnlockRateLimited logs a message indicating that a client exhausted its unlock attempts for a protected link.
+func LogUnlockRateLimited(ctx context.Context, id string, clientIP string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_unlockURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+		logmonitor.WithAnyZapField(zap.String("client_ip", clientIP)),
+	)
+	logInfoWithEmoji(constant.AlertEmoji+"  "+constant.WarningEmoji, constant.UnlockRateLimitedContextLog, logFields...)
+}
+
+// LogRateLimited logs a message indicating that a client exceeded the rate limit of a route group.
+func LogRateLimited(ctx context.Context, group string, client string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_rateLimit,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("group", group)),
+		logmonitor.WithAnyZapField(zap.String("client", client)),
+	)
+	logInfoWithEmoji(constant.AlertEmoji+"  "+constant.WarningEmoji, constant.RateLimitedContextLog, logFields...)
+}
+
+// LogURLPolicyViolation logs a message indicating that a destination URL was rejected by the URL policy.
+func LogURLPolicyViolation(ctx context.Context, url string, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_validateURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("url", url)),
+		logmonitor.WithError(err),
+	)
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.URLPolicyViolationContextLog, logFields...)
+}
+
+// LogURLScanned logs the verdict of the URL scanner on a destination URL.
+// Allowed URLs are logged at the info level; denied and quarantined URLs as warnings.
+func LogURLScanned(ctx context.Context, url string, result scanner.Result) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_scanURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("url", url)),
+		logmonitor.WithAnyZapField(zap.String("verdict", string(result.Verdict))),
+		logmonitor.WithAnyZapField(zap.String("reason", result.Reason)),
+	)
+	if result.Verdict == scanner.Allow {
+		logInfoWithEmoji(constant.InfoEmoji, constant.URLScannedContextLog, logFields...)
+		return
+	}
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.URLScannedContextLog, logFields...)
+}
+
+// LogURLScanFailed logs a message indicating that the URL scanner could not return a verdict.
+func LogURLScanFailed(ctx context.Context, url string, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_scanURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithAnyZapField(zap.String("url", url)),
+		logmonitor.WithError(err),
+	)
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.URLScanFailedContextLog, logFields...)
+}
+
+// LogQuarantinedURLServed logs a message indicating that a visitor was shown the warning page of a quarantined link.
+func LogQuarantinedURLServed(ctx context.Context, id string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_getURL,
+		logmonitor.WithComponent(constant.ComponentGopher),
+		logmonitor.WithID(id),
+	)
+	logInfoWithEmoji(constant.AlertEmoji+"  "+constant.WarningEmoji, constant.QuarantinedURLServedContextLog, logFields...)
+}
+
+// LogInvalidBearerToken logs a message indicating that a bearer token failed verification.
+func LogInvalidBearerToken(ctx context.Context, err error) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_authenticate,
+		logmonitor.WithComponent(constant.ComponentAuth),
+		logmonitor.WithError(err),
+	)
+	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.InvalidBearerTokenContextLog, logFields...)
+}
+
+// LogAPIKeyCreated logs a message indicating that an API key has been created.
+func LogAPIKeyCreated(ctx context.Context, name string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_apiKey,
+		logmonitor.WithComponent(constant.ComponentAuth),
+		logmonitor.WithPrincipal(name),
+	)
+	logInfoWithEmoji(constant.NewEmoji+"  "+constant.SuccessEmoji, constant.APIKeyCreatedContextLog, logFields...)
+}
+
+// LogAPIKeyRevoked logs a message indicating that an API key has been revoked.
+func LogAPIKeyRevoked(ctx context.Context, name string) {
+	logFields := logmonitor.CreateLogFieldsContext(ctx, operation_apiKey,
+		logmonitor.WithComponent(constant.ComponentAuth),
+		logmonitor.WithPrincipal(name),
+	)
+	logInfoWithEmoji(constant.DeleteEmoji+"  "+constant.SuccessEmoji, constant.APIKeyRevokedContextLog, logFields...)
+}
+
 // Use the centralized logging function from logmonitor package
-func createDeletionLogFields(id string, err error) []zap.Field {
-	return logmonitor.CreateLogFields(operation_deleteURL,
+func createDeletionLogFields(ctx context.Context, id string, err error) []zap.Field {
+	return logmonitor.CreateLogFieldsContext(ctx, operation_deleteURL,
 		logmonitor.WithComponent(constant.ComponentGopher),
 		logmonitor.WithID(id),
 		logmonitor.WithError(err),
@@ -125,11 +456,9 @@ func createDeletionLogFields(id string, err error) []zap.Field {
 
 // logNotFound handles logging and response for a "not found" situation.
 func logNotFound(c *gin.Context, id string) {
-	fields := createLogFields(operation_deleteURL, id)
+	fields := createLogFields(c.Request.Context(), operation_deleteURL, id)
 	logInfoWithEmoji(constant.AlertEmoji+"  "+constant.WarningEmoji, constant.NoURLIDContextLog, fields...)
-	c.JSON(http.StatusNotFound, gin.H{
-		constant.HeaderResponseError: constant.HeaderResponseIDandURLNotFound,
-	})
+	c.JSON(http.StatusNotFound, errorBody(c, constant.HeaderResponseIDandURLNotFound))
 }
 
 // isMismatchError checks if the error is a "mismatch error" situation.
@@ -139,63 +468,59 @@ func isMismatchError(err error) bool {
 
 // logMismatchError handles logging and response for a "mismatch error" situation.
 func logMismatchError(c *gin.Context, id string) {
-	fields := createLogFields(operation_deleteURL, id)
+	fields := createLogFields(c.Request.Context(), operation_deleteURL, id)
 	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.URLmismatchContextLog, fields...)
-	c.JSON(http.StatusBadRequest, gin.H{
-		constant.HeaderResponseError: constant.PathIDandPayloadIDDoesnotMatchContextLog,
-	})
+	c.JSON(http.StatusBadRequest, errorBody(c, constant.PathIDandPayloadIDDoesnotMatchContextLog))
 }
 
 // logURLMismatchError logs the URL mismatch error with appropriate emojis and sends a
 // 400 Bad Request response with the URL mismatch error message.
 func logURLMismatchError(c *gin.Context, id string, err error) {
 	// Create log fields to include additional metadata in the log entry.
-	fields := createLogFields(operation_url_mismatch_error, id)
+	fields := createLogFields(c.Request.Context(), operation_url_mismatch_error, id)
 
 	// Log the error with an information level log entry, including emojis for visibility.
 	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.URLmismatchContextLog, fields...)
 
 	// Respond to the client with a 400 Bad Request status code and include the error message.
 	// This indicates that the server cannot process the request due to a client error (mismatched URL).
-	c.JSON(http.StatusBadRequest, gin.H{
-		constant.HeaderResponseError: constant.URLmismatchContextLog,
-	})
+	c.JSON(http.StatusBadRequest, errorBody(c, constant.URLmismatchContextLog))
+}
+
+// logForbidden handles the response for a principal that does not own the link.
+// The denial itself is logged by authorizeLinkAccess, which knows the principal and the owner.
+func logForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, errorBody(c, constant.HeaderResponseForbidden))
 }
 
 // logBadRequest handles logging and response for a "bad request" situation.
 func logBadRequest(c *gin.Context, id string, err *BadRequestError) {
-	fields := createLogFields(operation_deleteURL, id)
+	fields := createLogFields(c.Request.Context(), operation_deleteURL, id)
 	logInfoWithEmoji(constant.ErrorEmoji+"  "+constant.WarningEmoji, constant.HeaderResponseInvalidRequestJSONBinding, fields...)
-	c.JSON(http.StatusBadRequest, gin.H{
-		constant.HeaderResponseError: constant.HeaderResponseInvalidRequestPayload,
-	})
+	c.JSON(http.StatusBadRequest, errorBody(c, constant.HeaderResponseInvalidRequestPayload))
 }
 
 // logDeletionOtherError logs and responds for other deletion errors.
 func logDeletionOtherError(c *gin.Context, id string, err error) {
-	logFields := createDeletionLogFields(id, err)
+	logFields := createDeletionLogFields(c.Request.Context(), id, err)
 	Logger.Error(constant.ErrorEmoji+"  "+constant.UrlshortenerEmoji, logFields...)
-	c.JSON(http.StatusInternalServerError, gin.H{
-		constant.HeaderResponseError: err.Error(),
-	})
+	c.JSON(http.StatusInternalServerError, errorBody(c, err.Error()))
 }
 
 // logUpdateOtherError logs and responds for other update errors.
 func logUpdateOtherError(c *gin.Context, id string, err error) {
-	logFields := logmonitor.CreateLogFields(operation_editURL,
+	logFields := logmonitor.CreateLogFieldsContext(c.Request.Context(), operation_editURL,
 		logmonitor.WithComponent(constant.ComponentGopher),
 		logmonitor.WithID(id),
 		logmonitor.WithError(err),
 	)
 	logmonitor.Logger.Error(constant.AlertEmoji+"  "+constant.WarningEmoji+"  "+constant.FailedToUpdateURLContextLog, logFields...)
-	c.JSON(http.StatusInternalServerError, gin.H{
-		constant.HeaderResponseError: constant.HeaderResponseInternalServerError,
-	})
+	c.JSON(http.StatusInternalServerError, errorBody(c, constant.HeaderResponseInternalServerError))
 }
 
 // createLogFieldsWithErr is a helper to create log fields including an error.
-func createLogFieldsWithErr(operation string, id string, err error) []zap.Field {
-	return logmonitor.CreateLogFields(operation,
+func createLogFieldsWithErr(ctx context.Context, operation string, id string, err error) []zap.Field {
+	return logmonitor.CreateLogFieldsContext(ctx, operation,
 		logmonitor.WithComponent(constant.ComponentNoSQL),
 		logmonitor.WithID(id),
 		logmonitor.WithError(err),
@@ -203,10 +528,6 @@ func createLogFieldsWithErr(operation string, id string, err error) []zap.Field
 }
 
 // createLogFields creates a slice of zap.Field with the operation and ID.
-func createLogFields(operation, id string) []zap.Field {
-	return []zap.Field{
-		// for the rest of the constant, just leave this as it is, don't use the constant
-		zap.String("operation", operation),
-		zap.String("id", id),
-	}
+func createLogFields(ctx context.Context, operation, id string) []zap.Field {
+	return logmonitor.CreateLogFieldsContext(ctx, operation, logmonitor.WithID(id))
 }
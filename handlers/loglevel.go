@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxLogLevelDuration is the longest time a changed log level is kept before it reverts.
+const maxLogLevelDuration = 24 * time.Hour
+
+// SetLogLevelPayload defines the structure for the JSON payload when changing a log level.
+// Package is one of logmonitor.Packages, or empty for every package. Duration is how long the level
+// is kept, such as "30m"; it defaults to logmonitor.DefaultLevelDuration.
+type SetLogLevelPayload struct {
+	Package  string `json:"package"`
+	Level    string `json:"level" binding:"required"`
+	Duration string `json:"duration"`
+}
+
+// listLogLevelsHandlerGin returns a Gin handler function that lists the current log level of each package.
+func listLogLevelsHandlerGin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{constant.HeaderResponseLevels: logmonitor.Levels()})
+	}
+}
+
+// setLogLevelHandlerGin returns a Gin handler function that changes the log level of one or every package
+// for a while, after which it reverts to the configured level on its own.
+func setLogLevelHandlerGin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SetLogLevelPayload
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, nil)
+			return
+		}
+
+		level, err := zapcore.ParseLevel(req.Level)
+		if err != nil || level > zapcore.ErrorLevel {
+			handleError(c, constant.HeaderResponseInvalidLogLevel, http.StatusBadRequest, nil)
+			return
+		}
+		duration := logmonitor.DefaultLevelDuration
+		if req.Duration != "" {
+			duration, err = time.ParseDuration(req.Duration)
+			if err != nil || duration <= 0 || duration > maxLogLevelDuration {
+				handleError(c, constant.HeaderResponseInvalidDuration, http.StatusBadRequest, nil)
+				return
+			}
+		}
+
+		if err := logmonitor.SetLevel(req.Package, level, duration); err != nil {
+			handleError(c, constant.HeaderResponseUnknownPackage, http.StatusBadRequest, nil)
+			return
+		}
+
+		LogLevelChanged(c.Request.Context(), principalName(c), req.Package, level, duration)
+		c.JSON(http.StatusOK, gin.H{constant.HeaderResponseLevels: logmonitor.Levels()})
+	}
+}
+
+// resetLogLevelsHandlerGin returns a Gin handler function that sets every package back to the configured log level.
+func resetLogLevelsHandlerGin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logmonitor.ResetLevels()
+		LogLevelsReset(c.Request.Context(), principalName(c))
+		c.JSON(http.StatusOK, gin.H{constant.HeaderResponseLevels: logmonitor.Levels()})
+	}
+}
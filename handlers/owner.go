@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+)
+
+// Define the page size limits for the list endpoint.
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// isAdmin reports whether the principal of the request holds the admin scope.
+func isAdmin(c *gin.Context) bool {
+	principal := principalFromContext(c)
+	return principal != nil && principal.HasScope(ScopeAdmin)
+}
+
+// authorizeLinkAccess checks that the principal of the request may modify the given link.
+// Only the owner of a link or an admin may edit or delete it. Links created before ownership
+// was recorded have no owner and can therefore only be modified by an admin.
+func authorizeLinkAccess(c *gin.Context, url *datastore.URL) error {
+	if isAdmin(c) {
+		return nil
+	}
+	if name := principalName(c); name != "" && name == url.Owner {
+		return nil
+	}
+	LogOwnershipDenied(c.Request.Context(), url.ID, principalName(c), url.Owner)
+	return &ForbiddenError{Message: constant.HeaderResponseForbidden}
+}
+
+// listURLsHandlerGin returns a Gin handler function that lists shortened URLs page by page.
+// By default only the caller's own links are returned. Admins may list another principal's
+// links with the "owner" query parameter, or every link with "all=true".
+func listURLsHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := parseListLimit(c.Query(QueryLimit))
+		if err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequest, http.StatusBadRequest, nil)
+			return
+		}
+
+		owner, all, err := resolveListScope(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, constant.HeaderResponseForbidden))
+			return
+		}
+
+		page, err := listURLs(c, dsClient, owner, all, limit, c.Query(QueryCursor), c.Query(QueryIncludeDeleted) == "true")
+		if err != nil {
+			LogInternalError(c.Request.Context(), operation_listURL, owner, err)
+			handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// resolveListScope determines whose links the caller is listing.
+// Non-admins can only list their own links.
+func resolveListScope(c *gin.Context) (owner string, all bool, err error) {
+	owner = c.DefaultQuery(QueryOwner, principalName(c))
+	all = c.Query(QueryAll) == "true"
+	if (all || owner != principalName(c)) && !isAdmin(c) {
+		LogOwnershipDenied(c.Request.Context(), "", principalName(c), owner)
+		return "", false, &ForbiddenError{Message: constant.HeaderResponseForbidden}
+	}
+	return owner, all, nil
+}
+
+// listURLs retrieves a page of links for one owner, or of every link when all is set.
+// Deleted links are only included when includeDeleted is set.
+func listURLs(c *gin.Context, dsClient *datastore.Client, owner string, all bool, limit int, cursor string, includeDeleted bool) (*datastore.URLPage, error) {
+	if all {
+		return datastore.ListURLsPage(c.Request.Context(), dsClient, limit, cursor, includeDeleted)
+	}
+	return datastore.ListURLsByOwner(c.Request.Context(), dsClient, owner, limit, cursor, includeDeleted)
+}
+
+// parseListLimit parses the page size, applying the default and the upper bound.
+func parseListLimit(s string) (int, error) {
+	if s == "" {
+		return defaultListLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit <= 0 {
+		return 0, &BadRequestError{Message: constant.HeaderResponseInvalidRequest}
+	}
+	return min(limit, maxListLimit), nil
+}
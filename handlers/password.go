@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+)
+
+// unlockRateLimit is the rate at which unlock attempts are allowed for each pair of link ID and client.
+// A client gets 5 attempts at once, then one more every 12 seconds.
+var unlockRateLimit = RateLimitPolicy{Rate: rate.Every(12 * time.Second), Burst: 5}
+
+// unlockLinkRateLimit is the rate at which unlock attempts are allowed for each link, whatever the
+// client. It bounds the guesses of attackers that spread over many addresses, and the bcrypt work
+// they can cause, at the cost of locking out visitors of a link that is under attack.
+var unlockLinkRateLimit = RateLimitPolicy{Rate: 0.5, Burst: 30}
+
+// unlockFormHTML is the page served in place of the redirect for password-protected links.
+// The form posts back to the same path, so it works with any base path.
+const unlockFormHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Protected link</title>
+</head>
+<body>
+<main>
+<h1>This link is password protected</h1>
+{{if .Message}}<p role="alert">{{.Message}}</p>{{end}}
+<form method="post">
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</main>
+</body>
+</html>
+`
+
+// unlockFormTemplate is the parsed unlock form. html/template escapes the message automatically.
+var unlockFormTemplate = template.Must(template.New("unlock").Parse(unlockFormHTML))
+
+// maxPasswordLength is the longest link password, in bytes, as bcrypt refuses anything longer.
+const maxPasswordLength = 72
+
+// isValidLinkPassword reports whether a link password can be hashed. Its length is counted in bytes,
+// not characters, so multibyte passwords are limited to fewer than 72 characters.
+func isValidLinkPassword(password string) bool {
+	return len(password) <= maxPasswordLength
+}
+
+// isValidPasswordHash reports whether hash is a bcrypt hash, such as one restored from an export.
+func isValidPasswordHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+// hashLinkPassword returns the bcrypt hash of a link password, or an empty string if no password is set.
+func hashLinkPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// unlockURLHandlerGin returns a Gin handler function that checks the password submitted through
+// the unlock form and, if it is correct, redirects to the original URL of a protected link.
+// Attempts are rate limited per link ID and client IP, and per link ID alone, to slow down guessing.
+func unlockURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param(constant.HeaderID)
+		if !applyUnlockRateLimit(c, id) {
+			return
+		}
+
+		url, err := datastore.GetURL(c.Request.Context(), dsClient, id)
+		if err != nil {
+			handleGetURLError(c, id, err)
+			return
+		}
+
+		if !checkLinkAvailable(c, url) {
+			return
+		}
+
+		// A link without a password is simply followed, as it would be by getURLHandlerGin.
+		if url.IsProtected() && !linkPasswordMatches(url, c.PostForm(FormPassword)) {
+			LogIncorrectLinkPassword(c.Request.Context(), id, c.ClientIP())
+			renderUnlockForm(c, http.StatusUnauthorized, constant.HeaderResponseIncorrectPassword)
+			return
+		}
+
+		// 303 See Other makes the browser follow the redirect with a GET rather than re-posting the form.
+		followURL(c, url, http.StatusSeeOther)
+	}
+}
+
+// linkPasswordMatches reports whether the password matches the bcrypt hash stored on the link.
+func linkPasswordMatches(url *datastore.URL, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(password)) == nil
+}
+
+// applyUnlockRateLimit checks if the unlock attempts for a link have been exhausted by the client,
+// then by every client together. The client budget is checked first, so that a client that is already
+// turned away does not use up the budget of the link. It renders the unlock form with HTTP 429 if either
+// rate limit is exceeded. The attempts are counted by the same backend as the other rate limits, so
+// they are shared across replicas when Redis is used.
+func applyUnlockRateLimit(c *gin.Context, id string) bool {
+	if unlockAttemptAllowed(c, RateLimitGroupUnlock+":"+id+":"+c.ClientIP(), unlockRateLimit) &&
+		unlockAttemptAllowed(c, RateLimitGroupUnlock+":"+id, unlockLinkRateLimit) {
+		return true
+	}
+	LogUnlockRateLimited(c.Request.Context(), id, c.ClientIP())
+	renderUnlockForm(c, http.StatusTooManyRequests, constant.HeaderResponseRateLimitExceeded)
+	return false
+}
+
+// unlockAttemptAllowed takes one attempt from the budget under key. Like the other rate limits,
+// it lets the attempt through if the backend fails.
+func unlockAttemptAllowed(c *gin.Context, key string, policy RateLimitPolicy) bool {
+	result, ok := checkRateLimit(c, key, policy)
+	return !ok || result.Allowed
+}
+
+// renderUnlockForm writes the unlock form with the given status code and an optional message.
+// The page must never be cached or framed by another site.
+func renderUnlockForm(c *gin.Context, statusCode int, message string) {
+	c.Header(constant.HeaderCacheControl, constant.CacheControlNoStore)
+	c.Header(constant.HeaderXFrameOptions, constant.FrameOptionsDeny)
+	c.Header(constant.HeaderContentType, constant.ContentTypeHTML)
+	c.Status(statusCode)
+	if err := unlockFormTemplate.Execute(c.Writer, struct{ Message string }{message}); err != nil {
+		LogInternalError(c.Request.Context(), operation_unlockURL, c.Param(constant.HeaderID), err)
+	}
+	c.Abort()
+}
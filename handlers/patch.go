@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
+	"github.com/H0llyW00dzZ/go-urlshortner/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// Define the limits of the fields that can be patched.
+const (
+	maxTags      = 20
+	maxTagLength = 64
+)
+
+// allowedRedirectStatuses are the redirect status codes a link may use.
+var allowedRedirectStatuses = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// urlMutation is one validated change of a link, applied inside the update transaction.
+type urlMutation func(url *datastore.URL)
+
+// patchFieldParsers validate the value of each mutable field of a merge patch. A JSON null resets
+// the field to its default, as defined by JSON Merge Patch (RFC 7386). The destination is handled
+// separately by patchURLHandlerGin, as it has to pass the policy and the scanner.
+var patchFieldParsers = map[string]func(value json.RawMessage) (urlMutation, error){
+	PatchFieldExpiresAt:      parseExpiresAtPatch,
+	PatchFieldRedirectStatus: parseRedirectStatusPatch,
+	PatchFieldTags:           parseTagsPatch,
+	PatchFieldPassword:       parsePasswordPatch,
+	PatchFieldEnabled:        parseEnabledPatch,
+}
+
+// patchURLHandlerGin returns a Gin handler function that partially updates a shortened URL with a
+// JSON Merge Patch document. Only the fields present in the document are changed. Every field is
+// validated before anything is written, and the changes are applied within one transaction, so that
+// either all of them are stored or none is. The updated link is returned in the response.
+func patchURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param(constant.HeaderID)
+		patch, ok := bindMergePatch(c)
+		if !ok {
+			return
+		}
+
+		mutations, ok := parseLinkPatch(c, patch)
+		if !ok {
+			return
+		}
+
+		url, err := applyLinkPatch(c, dsClient, id, mutations)
+		if err != nil {
+			handleUpdateError(c, id, err)
+			return
+		}
+
+		LogURLPatched(c.Request.Context(), id, principalName(c))
+		emitLinkEvent(c.Request.Context(), dsClient, webhook.EventLinkEdited, linkEventData{Link: url})
+		c.JSON(http.StatusOK, url)
+	}
+}
+
+// bindMergePatch reads the merge patch document from the request body. It responds with HTTP 415
+// if the content type is not a JSON type, or HTTP 400 if the body is not a JSON object.
+func bindMergePatch(c *gin.Context) (map[string]json.RawMessage, bool) {
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+	if mediaType != constant.MediaTypeMergePatch && mediaType != constant.MediaTypeJSON {
+		handleError(c, constant.HeaderResponseUnsupportedMediaType, http.StatusUnsupportedMediaType, nil)
+		return nil, false
+	}
+
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil || patch == nil {
+		handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, nil)
+		return nil, false
+	}
+	return patch, true
+}
+
+// parseLinkPatch validates every field of the patch and turns it into mutations. A new destination is
+// canonicalized and checked against the policy and the scanner like in editURLHandlerGin. If a field is
+// unknown or invalid, it responds with HTTP 400 naming the field and returns false.
+func parseLinkPatch(c *gin.Context, patch map[string]json.RawMessage) ([]urlMutation, bool) {
+	var mutations []urlMutation
+	for field, value := range patch {
+		if field == PatchFieldURL {
+			continue
+		}
+		parse, known := patchFieldParsers[field]
+		if !known {
+			handleError(c, fmt.Sprintf(ErrMsgUnknownPatchField, field), http.StatusBadRequest, nil)
+			return nil, false
+		}
+		mutation, err := parse(value)
+		if err != nil {
+			handleError(c, err.Error(), http.StatusBadRequest, nil)
+			return nil, false
+		}
+		mutations = append(mutations, mutation)
+	}
+
+	if value, ok := patch[PatchFieldURL]; ok {
+		mutation, ok := parseDestinationPatch(c, value)
+		if !ok {
+			return nil, false
+		}
+		mutations = append(mutations, mutation)
+	}
+
+	// The canonical hash depends on the destination and on the password, so it is recomputed last.
+	return append(mutations, func(url *datastore.URL) { url.CanonicalHash = canonicalHashOf(url) }), true
+}
+
+// applyLinkPatch applies the mutations to the link within a transaction, after checking that the
+// principal may modify it, and returns the updated link.
+func applyLinkPatch(c *gin.Context, dsClient *datastore.Client, id string, mutations []urlMutation) (*datastore.URL, error) {
+	var updated *datastore.URL
+	err := datastore.MutateURL(c.Request.Context(), dsClient, id, datastore.Change{Actor: principalName(c), Operation: datastore.OperationPatch}, auditURLChange(c, func(url *datastore.URL) error {
+		if url.IsDeleted() {
+			return datastore.ErrNotFound
+		}
+		if err := authorizeLinkAccess(c, url); err != nil {
+			return err
+		}
+		for _, mutate := range mutations {
+			mutate(url)
+		}
+		updated = url
+		return nil
+	}))
+	return updated, err
+}
+
+// parseDestinationPatch validates a new destination. The destination cannot be removed.
+func parseDestinationPatch(c *gin.Context, value json.RawMessage) (urlMutation, bool) {
+	var rawURL string
+	if isJSONNull(value) || json.Unmarshal(value, &rawURL) != nil || !isValidURL(rawURL) {
+		handleError(c, fmt.Sprintf(ErrMsgInvalidPatchField, PatchFieldURL), http.StatusBadRequest, nil)
+		return nil, false
+	}
+
+	rawURL, scan, ok := checkDestination(c, rawURL)
+	if !ok {
+		return nil, false
+	}
+	return func(url *datastore.URL) {
+		url.Original = rawURL
+		applyScanResult(url, scan)
+	}, true
+}
+
+// parseExpiresAtPatch validates an expiry given as an RFC 3339 timestamp. Null removes the expiry.
+func parseExpiresAtPatch(value json.RawMessage) (urlMutation, error) {
+	var expiresAt time.Time
+	if !isJSONNull(value) {
+		if err := json.Unmarshal(value, &expiresAt); err != nil {
+			return nil, fmt.Errorf(ErrMsgInvalidPatchField, PatchFieldExpiresAt)
+		}
+	}
+	return func(url *datastore.URL) { url.ExpiresAt = expiresAt }, nil
+}
+
+// parseRedirectStatusPatch validates a redirect status code. Null restores the default 302 Found.
+func parseRedirectStatusPatch(value json.RawMessage) (urlMutation, error) {
+	var status int
+	if !isJSONNull(value) {
+		if err := json.Unmarshal(value, &status); err != nil || !allowedRedirectStatuses[status] {
+			return nil, fmt.Errorf(ErrMsgInvalidPatchField, PatchFieldRedirectStatus)
+		}
+	}
+	return func(url *datastore.URL) { url.RedirectStatus = status }, nil
+}
+
+// parseTagsPatch validates the list of tags, which replaces the current one. Null removes every tag.
+func parseTagsPatch(value json.RawMessage) (urlMutation, error) {
+	var tags []string
+	if err := json.Unmarshal(value, &tags); err != nil || !validTags(tags) {
+		return nil, fmt.Errorf(ErrMsgInvalidPatchField, PatchFieldTags)
+	}
+	return func(url *datastore.URL) { url.Tags = tags }, nil
+}
+
+// validTags reports whether the tags are within the limits, non-empty and unique.
+func validTags(tags []string) bool {
+	if len(tags) > maxTags {
+		return false
+	}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag == "" || len(tag) > maxTagLength || seen[tag] {
+			return false
+		}
+		seen[tag] = true
+	}
+	return true
+}
+
+// parsePasswordPatch validates a new password and hashes it. Null or an empty string removes the password.
+func parsePasswordPatch(value json.RawMessage) (urlMutation, error) {
+	var password string
+	if !isJSONNull(value) {
+		if err := json.Unmarshal(value, &password); err != nil || !isValidLinkPassword(password) {
+			return nil, fmt.Errorf(ErrMsgInvalidPatchField, PatchFieldPassword)
+		}
+	}
+	passwordHash, err := hashLinkPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return func(url *datastore.URL) { url.PasswordHash = passwordHash }, nil
+}
+
+// parseEnabledPatch validates the enabled flag. Null enables the link.
+func parseEnabledPatch(value json.RawMessage) (urlMutation, error) {
+	enabled := true
+	if !isJSONNull(value) {
+		if err := json.Unmarshal(value, &enabled); err != nil {
+			return nil, fmt.Errorf(ErrMsgInvalidPatchField, PatchFieldEnabled)
+		}
+	}
+	return func(url *datastore.URL) { url.Disabled = !enabled }, nil
+}
+
+// checkLinkAvailable responds with HTTP 410 Gone and returns false if the link was deleted, disabled or has expired.
+func checkLinkAvailable(c *gin.Context, url *datastore.URL) bool {
+	var message string
+	switch {
+	case url.IsDeleted():
+		message = constant.HeaderResponseLinkDeleted
+	case url.Disabled:
+		message = constant.HeaderResponseLinkDisabled
+	case url.IsExpired(time.Now()):
+		message = constant.HeaderResponseLinkExpired
+	default:
+		return true
+	}
+	LogLinkUnavailable(c.Request.Context(), url.ID)
+	metrics.Redirects.Inc(metrics.RedirectGone)
+	handleError(c, message, http.StatusGone, nil)
+	return false
+}
+
+// redirectStatusOf returns the redirect status code configured for the link, or 302 Found by default.
+func redirectStatusOf(url *datastore.URL) int {
+	if url.RedirectStatus == 0 {
+		return http.StatusFound
+	}
+	return url.RedirectStatus
+}
+
+// isJSONNull reports whether the raw JSON value is null.
+func isJSONNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
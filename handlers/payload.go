@@ -8,9 +8,12 @@ import (
 )
 
 // CreateURLPayload defines the structure for the JSON payload when creating a new URL.
-// It contains a single field, URL, which is the original URL to be shortened.
+// URL is the original URL to be shortened. Password is optional; when set, visitors must
+// enter it in an unlock form before they are redirected. It is limited to 72 bytes by extractURL,
+// as the validator would count runes.
 type CreateURLPayload struct {
-	URL string `json:"url" binding:"required,url"`
+	URL      string `json:"url" binding:"required,url"`
+	Password string `json:"password,omitempty"`
 }
 
 // UpdateURLPayload defines the structure for the JSON payload when updating an existing URL.
@@ -28,6 +31,12 @@ type DeleteURLPayload struct {
 	URL string `json:"url" binding:"required,url"`
 }
 
+// RollbackURLPayload defines the structure for the JSON payload when rolling a link back.
+// Revision is the revision to restore, where 0 is the state the link was created with.
+type RollbackURLPayload struct {
+	Revision *int64 `json:"revision" binding:"required,min=0"`
+}
+
 // bindUpdatePayload binds the JSON payload to the UpdateURLPayload struct and validates the new URL format.
 func bindUpdatePayload(c *gin.Context) (UpdateURLPayload, error) {
 	var req UpdateURLPayload
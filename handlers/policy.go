@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/urlpolicy"
+	"github.com/gin-gonic/gin"
+)
+
+// urlPolicy decides which destination URLs may be shortened. It is configured from the
+// URL_* environment variables during package initialization.
+var urlPolicy *urlpolicy.Policy
+
+// SetURLPolicy replaces the policy applied to destination URLs when links are created or edited.
+func SetURLPolicy(policy *urlpolicy.Policy) {
+	urlPolicy = policy
+}
+
+// checkURLPolicy checks the destination URL against the policy, treating the host of the request as
+// this shortener. If the URL is rejected, it responds with HTTP 400 and the reason, and returns false.
+func checkURLPolicy(c *gin.Context, rawURL string) bool {
+	if err := urlPolicy.Check(c.Request.Context(), rawURL, c.Request.Host); err != nil {
+		LogURLPolicyViolation(c.Request.Context(), rawURL, err)
+		handleError(c, err.Error(), http.StatusBadRequest, nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
+	"github.com/H0llyW00dzZ/go-urlshortner/ratelimit"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitPolicy describes how many requests a client may make: Rate requests per second
+// on average, with bursts of up to Burst requests.
+type RateLimitPolicy = ratelimit.Policy
+
+// rateLimitBackend enforces the rate limits. It uses the local RateLimiterStore unless a shared
+// backend is configured with SetRateLimitBackend.
+var rateLimitBackend ratelimit.Backend
+
+// SetRateLimitBackend replaces the backend that enforces the rate limits, for example with a Redis
+// backend shared by every replica so that limits do not multiply with the replica count.
+func SetRateLimitBackend(backend ratelimit.Backend) {
+	rateLimitBackend = backend
+}
+
+// Define the route groups that are rate limited separately.
+const (
+	RateLimitGroupRedirect   = "redirect"
+	RateLimitGroupManagement = "management"
+	RateLimitGroupAuth       = "auth"
+	RateLimitGroupUnlock     = "unlock"
+)
+
+// redirectRateLimit is the policy applied per client IP to the public redirect route.
+var redirectRateLimit = RateLimitPolicy{Rate: 1, Burst: 10}
+
+// managementRateLimit is the policy applied per principal to the management routes,
+// unless the principal has its own policy in apiKeyRateLimits.
+var managementRateLimit = RateLimitPolicy{Rate: 5, Burst: 20}
+
+// authRateLimit is the policy applied per client IP to the management routes before the caller is
+// authenticated, so that a flood of requests with bad credentials cannot drive unlimited key lookups.
+// It is looser than managementRateLimit, as several principals may share an IP behind a NAT.
+var authRateLimit = RateLimitPolicy{Rate: 20, Burst: 100}
+
+// apiKeyRateLimits holds the policies of principals that need a different management limit,
+// such as a CI pipeline that creates links in bulk. It is indexed by principal name, so the
+// key names of RATE_LIMIT_API_KEYS are stored with their prefix.
+var apiKeyRateLimits = map[string]RateLimitPolicy{}
+
+// rateLimitAllowlist holds the trusted networks whose clients are never rate limited.
+var rateLimitAllowlist []*net.IPNet
+
+// Define the bounds of RateLimiterStore. The idle TTL must be longer than the time a limiter
+// needs to refill its burst, otherwise evicting a limiter would reset a client's budget early.
+var (
+	rateLimitStoreSize   = 100000
+	rateLimitStoreShards = 32
+	rateLimitStoreTTL    = 10 * time.Minute
+)
+
+// loadRateLimitConfig reads the RATE_LIMIT_* environment variables.
+// Unset variables keep their defaults.
+func loadRateLimitConfig() error {
+	if err := parseRateLimitEnv(RATE_LIMIT_REDIRECT, &redirectRateLimit); err != nil {
+		return err
+	}
+	if err := parseRateLimitEnv(RATE_LIMIT_MANAGEMENT, &managementRateLimit); err != nil {
+		return err
+	}
+	if err := parseRateLimitEnv(RATE_LIMIT_AUTH, &authRateLimit); err != nil {
+		return err
+	}
+	if err := parseRateLimitEnv(RATE_LIMIT_UNLOCK_LINK, &unlockLinkRateLimit); err != nil {
+		return err
+	}
+
+	policies, err := ParseRateLimitPolicies(os.Getenv(RATE_LIMIT_API_KEYS))
+	if err != nil {
+		return fmt.Errorf("%s: %w", RATE_LIMIT_API_KEYS, err)
+	}
+	apiKeyRateLimits = make(map[string]RateLimitPolicy, len(policies))
+	for name, policy := range policies {
+		apiKeyRateLimits[apiKeyPrincipalName(name)] = policy
+	}
+
+	allowlist, err := ParseAllowlist(os.Getenv(RATE_LIMIT_ALLOWLIST))
+	if err != nil {
+		return fmt.Errorf("%s: %w", RATE_LIMIT_ALLOWLIST, err)
+	}
+	rateLimitAllowlist = allowlist
+	return loadRateLimitStoreConfig()
+}
+
+// loadRateLimitStoreConfig reads the bounds of RateLimiterStore from the environment.
+func loadRateLimitStoreConfig() error {
+	if err := parseEnvInt(RATE_LIMIT_STORE_SIZE, &rateLimitStoreSize); err != nil {
+		return err
+	}
+	if err := parseEnvInt(RATE_LIMIT_STORE_SHARDS, &rateLimitStoreShards); err != nil {
+		return err
+	}
+	return parseEnvDuration(RATE_LIMIT_STORE_TTL, &rateLimitStoreTTL)
+}
+
+// parseEnvDuration parses the environment variable as a duration into dst if it is set.
+func parseEnvDuration(name string, dst *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	*dst = d
+	return nil
+}
+
+// parseEnvInt parses the environment variable as a positive integer into dst if it is set.
+func parseEnvInt(name string, dst *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fmt.Errorf(ErrMsgInvalidPositiveInt, name, value)
+	}
+	*dst = n
+	return nil
+}
+
+// parseRateLimitEnv parses the environment variable as a policy into dst if it is set.
+func parseRateLimitEnv(name string, dst *RateLimitPolicy) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	policy, err := ParseRateLimitPolicy(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	*dst = policy
+	return nil
+}
+
+// ParseRateLimitPolicy parses a policy of the form "rate:burst", for example "0.5:5" for
+// one request every two seconds with bursts of up to five requests.
+func ParseRateLimitPolicy(s string) (RateLimitPolicy, error) {
+	rateStr, burstStr, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return RateLimitPolicy{}, fmt.Errorf(ErrMsgInvalidRateLimit, s)
+	}
+	r, errRate := strconv.ParseFloat(rateStr, 64)
+	burst, errBurst := strconv.Atoi(burstStr)
+	if errRate != nil || errBurst != nil || r <= 0 || burst < 1 {
+		return RateLimitPolicy{}, fmt.Errorf(ErrMsgInvalidRateLimit, s)
+	}
+	return RateLimitPolicy{Rate: rate.Limit(r), Burst: burst}, nil
+}
+
+// ParseRateLimitPolicies parses per-principal policies of the form "name=rate:burst,other=rate:burst".
+func ParseRateLimitPolicies(s string) (map[string]RateLimitPolicy, error) {
+	policies := make(map[string]RateLimitPolicy)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf(ErrMsgInvalidRateLimit, entry)
+		}
+		policy, err := ParseRateLimitPolicy(value)
+		if err != nil {
+			return nil, err
+		}
+		policies[name] = policy
+	}
+	return policies, nil
+}
+
+// ParseAllowlist parses a comma-separated list of CIDR blocks or single IP addresses.
+func ParseAllowlist(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			entry += singleHostPrefix(entry)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// parseTrustedProxies parses the TRUSTED_PROXIES list, which has the same format as RATE_LIMIT_ALLOWLIST,
+// into the CIDR blocks expected by gin.Engine.SetTrustedProxies.
+func parseTrustedProxies(s string) ([]string, error) {
+	networks, err := ParseAllowlist(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", TRUSTED_PROXIES, err)
+	}
+	proxies := make([]string, 0, len(networks))
+	for _, network := range networks {
+		proxies = append(proxies, network.String())
+	}
+	return proxies, nil
+}
+
+// singleHostPrefix returns the prefix length that matches exactly one IPv4 or IPv6 address.
+func singleHostPrefix(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "/128"
+	}
+	return "/32"
+}
+
+// isAllowlisted reports whether the remote IP belongs to one of the trusted networks.
+// It is given the address of the connection rather than the client IP, so that forwarding
+// headers, even from a trusted proxy, can never exempt a request from the limits.
+func isAllowlisted(remoteIP string) bool {
+	ip := net.ParseIP(remoteIP)
+	for _, network := range rateLimitAllowlist {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRateLimit checks if the redirect rate limit has been exceeded by the client IP.
+// It writes a 429 Too Many Requests response if the rate limit is exceeded.
+func applyRateLimit(c *gin.Context) bool {
+	return enforceRateLimit(c, RateLimitGroupRedirect, c.ClientIP(), redirectRateLimit)
+}
+
+// applyAuthRateLimit checks if the client IP has exceeded the limit of management requests it may make
+// before being authenticated. It writes a 429 Too Many Requests response if the rate limit is exceeded.
+func applyAuthRateLimit(c *gin.Context) bool {
+	return enforceRateLimit(c, RateLimitGroupAuth, c.ClientIP(), authRateLimit)
+}
+
+// applyManagementRateLimit checks if the management rate limit has been exceeded by the principal.
+// Principals are limited by name rather than by IP, so a key shared by several machines gets one budget.
+func applyManagementRateLimit(c *gin.Context, principal *Principal) bool {
+	policy, ok := apiKeyRateLimits[principal.Name]
+	if !ok {
+		policy = managementRateLimit
+	}
+	return enforceRateLimit(c, RateLimitGroupManagement, principal.Name, policy)
+}
+
+// enforceRateLimit takes one request from the budget of the client in the given route group and
+// sets the RateLimit-* headers. If the budget is exhausted, it aborts the request with a 429 response
+// and a Retry-After header. Connections from allowlisted networks are never limited.
+func enforceRateLimit(c *gin.Context, group string, client string, policy RateLimitPolicy) bool {
+	if isAllowlisted(c.RemoteIP()) {
+		return true
+	}
+
+	result, ok := checkRateLimit(c, group+":"+client, policy)
+	if !ok {
+		return true
+	}
+	setRateLimitHeaders(c, result)
+	if !result.Allowed {
+		c.Header(constant.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		LogRateLimited(c.Request.Context(), group, client)
+		metrics.RateLimitRejections.Inc(group)
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, errorBody(c, constant.HeaderResponseRateLimitExceeded))
+		return false
+	}
+	return true
+}
+
+// checkRateLimit asks the backend whether the client identified by key may proceed.
+// It reports false if the backend failed, in which case the request is let through rather than
+// rejected, as an outage of the rate limiter must not take the whole service down with it.
+func checkRateLimit(c *gin.Context, key string, policy RateLimitPolicy) (ratelimit.Result, bool) {
+	result, err := rateLimitBackend.Allow(c.Request.Context(), key, policy)
+	if err != nil {
+		LogInternalError(c.Request.Context(), operation_rateLimit, key, err)
+		return result, false
+	}
+	return result, true
+}
+
+// setRateLimitHeaders describes the budget of the client with the RateLimit-Limit, RateLimit-Remaining
+// and RateLimit-Reset headers, where the reset is the number of seconds until the burst is fully refilled.
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	c.Header(constant.HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+	c.Header(constant.HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+	c.Header(constant.HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+// ceilSeconds rounds a duration up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
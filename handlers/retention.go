@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// deleteRetention is how long deleted links are kept, and can be restored, before they are purged.
+// purgeInterval is how often the purge job runs. Both are configured from environment variables during
+// package initialization.
+var (
+	deleteRetention = 30 * 24 * time.Hour
+	purgeInterval   = time.Hour
+)
+
+// restoreURLHandlerGin returns a Gin handler function that restores a deleted link, provided it has
+// not been purged yet. Only the owner of the link or an admin may restore it. Restoring a link that
+// is not deleted is rejected with HTTP 409 Conflict.
+func restoreURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param(constant.HeaderID)
+		var restored *datastore.URL
+		err := datastore.MutateURL(c.Request.Context(), dsClient, id, datastore.Change{Actor: principalName(c), Operation: datastore.OperationRestore}, auditURLChange(c, func(url *datastore.URL) error {
+			if err := authorizeLinkAccess(c, url); err != nil {
+				return err
+			}
+			if !url.IsDeleted() {
+				return errNotDeleted
+			}
+			url.DeletedAt = time.Time{}
+			url.CanonicalHash = canonicalHashOf(url)
+			restored = url
+			return nil
+		}))
+		if err != nil {
+			handleRestoreError(c, id, err)
+			return
+		}
+
+		LogURLRestored(c.Request.Context(), id, principalName(c))
+		emitLinkEvent(c.Request.Context(), dsClient, webhook.EventLinkRestored, linkEventData{Link: restored})
+		c.JSON(http.StatusOK, gin.H{
+			constant.HeaderID:             id,
+			constant.HeaderResponseStatus: constant.HeaderResponseURLRestored,
+		})
+	}
+}
+
+// handleRestoreError responds to a failed restore, using the same responses as updates
+// for missing links, forbidden access and internal errors.
+func handleRestoreError(c *gin.Context, id string, err error) {
+	if err == errNotDeleted {
+		handleError(c, constant.HeaderResponseURLNotDeleted, http.StatusConflict, nil)
+		return
+	}
+	handleUpdateError(c, id, err)
+}
+
+// StartPurgeJob periodically and permanently deletes the links that were deleted longer ago than the
+// retention period. It returns a function that stops the job. Running it on every replica is harmless,
+// as purging the same links twice has no effect.
+func StartPurgeJob(dsClient *datastore.Client) (stop func()) {
+	ticker := time.NewTicker(purgeInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				purgeDeletedURLs(dsClient)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// purgeDeletedURLs permanently deletes the links whose retention period is over and logs how many were purged.
+func purgeDeletedURLs(dsClient *datastore.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), purgeInterval)
+	defer cancel()
+	purged, err := datastore.PurgeDeletedURLs(ctx, dsClient, time.Now().Add(-deleteRetention))
+	LogDeletedURLsPurged(ctx, purged, err)
+}
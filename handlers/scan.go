@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
+	"github.com/H0llyW00dzZ/go-urlshortner/scanner"
+	"github.com/gin-gonic/gin"
+)
+
+// urlScanner checks destination URLs for malware and phishing before links are stored.
+// Scanning is disabled while it is nil, which is the default.
+var urlScanner scanner.URLScanner
+
+// SetURLScanner installs the scanner consulted when links are created or edited.
+// Passing nil disables scanning.
+func SetURLScanner(s scanner.URLScanner) {
+	urlScanner = s
+}
+
+// interstitialHTML is the warning page served in place of the redirect for quarantined links.
+// The visitor has to follow the link explicitly to continue to the destination.
+const interstitialHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Warning: suspicious link</title>
+</head>
+<body>
+<main>
+<h1>This link may be unsafe</h1>
+<p>The destination of this short link was flagged as suspicious. It may try to steal your
+information or install harmful software.</p>
+<p>Destination: <code>{{.URL}}</code></p>
+<p><a href="{{.URL}}" rel="noopener noreferrer nofollow">Continue anyway</a></p>
+</main>
+</body>
+</html>
+`
+
+// interstitialTemplate is the parsed warning page. html/template escapes the URL and neutralizes
+// unsafe schemes in the link automatically.
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(interstitialHTML))
+
+// scanURL asks the scanner, if one is installed, for a verdict on the destination URL.
+// It responds with HTTP 400 if the URL is denied, or HTTP 503 if the scanner fails, and returns false.
+// A quarantine verdict is returned to the caller, which stores it with the link.
+func scanURL(c *gin.Context, rawURL string) (scanner.Result, bool) {
+	if urlScanner == nil {
+		return scanner.Result{Verdict: scanner.Allow}, true
+	}
+
+	result, err := urlScanner.Scan(c.Request.Context(), rawURL)
+	if err != nil {
+		LogURLScanFailed(c.Request.Context(), rawURL, err)
+		handleError(c, constant.HeaderResponseScannerUnavailable, http.StatusServiceUnavailable, err)
+		return result, false
+	}
+
+	LogURLScanned(c.Request.Context(), rawURL, result)
+	if result.Verdict == scanner.Deny {
+		handleError(c, constant.HeaderResponseURLFlaggedUnsafe, http.StatusBadRequest, nil)
+		return result, false
+	}
+	return result, true
+}
+
+// applyScanResult records the verdict of the scanner on the link.
+func applyScanResult(url *datastore.URL, result scanner.Result) {
+	url.Quarantined = result.Verdict == scanner.Quarantine
+	url.QuarantineReason = ""
+	if url.Quarantined {
+		url.QuarantineReason = result.Reason
+	}
+}
+
+// followURL sends the visitor on to the original URL with the given redirect status code,
+// unless the link is quarantined, in which case the warning page is served instead.
+func followURL(c *gin.Context, url *datastore.URL, statusCode int) {
+	if url.Quarantined {
+		LogQuarantinedURLServed(c.Request.Context(), url.ID)
+		renderInterstitial(c, url)
+		return
+	}
+
+	LogURLRetrievalSuccess(c.Request.Context(), url.ID)
+	countClick(url.ID)
+	metrics.Redirects.Inc(metrics.RedirectHit)
+	c.Redirect(statusCode, url.Original)
+}
+
+// renderInterstitial writes the warning page for a quarantined link.
+// Like the unlock form, the page must never be cached or framed by another site.
+func renderInterstitial(c *gin.Context, url *datastore.URL) {
+	c.Header(constant.HeaderCacheControl, constant.CacheControlNoStore)
+	c.Header(constant.HeaderXFrameOptions, constant.FrameOptionsDeny)
+	c.Header(constant.HeaderContentType, constant.ContentTypeHTML)
+	c.Status(http.StatusOK)
+	if err := interstitialTemplate.Execute(c.Writer, struct{ URL string }{url.Original}); err != nil {
+		LogInternalError(c.Request.Context(), operation_getURL, url.ID, err)
+	}
+	c.Abort()
+}
@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/scanner"
+	"github.com/gin-gonic/gin"
+)
+
+// Define the supported transfer formats for importing and exporting URL entities.
+const (
+	TransferFormatCSV   = "csv"
+	TransferFormatJSONL = "jsonl"
+)
+
+// transferCSVHeader is the header row written to and expected from CSV transfers.
+// Files without the password hash column, written by earlier versions, are accepted as well.
+var transferCSVHeader = []string{constant.HeaderID, constant.HeaderURL, constant.HeaderPasswordHash}
+
+// transferURL is the form of a URL entity in JSON Lines transfers. Unlike API responses, it carries
+// the password hash, so that restoring an export keeps protected links protected. Exports are only
+// available to admins.
+type transferURL struct {
+	*datastore.URL
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// MarshalJSON encodes the link as datastore.URL.MarshalJSON does and appends the password hash.
+// Without it, the promoted URL.MarshalJSON would encode the embedded URL alone and drop the hash.
+func (t transferURL) MarshalJSON() ([]byte, error) {
+	link, err := json.Marshal(t.URL)
+	if err != nil || t.PasswordHash == "" {
+		return link, err
+	}
+	hash, err := json.Marshal(t.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	// The link is always a non-empty JSON object, so the hash is added before its closing brace.
+	link = append(link[:len(link)-1], `,"password_hash":`...)
+	return append(append(link, hash...), '}'), nil
+}
+
+// maxImportLineSize is the longest JSON Lines row accepted by ImportURLs, in bytes.
+// Longer rows are reported as invalid without aborting the import.
+const maxImportLineSize = 1 << 20
+
+// maxOwnerLength is the longest owner accepted by ImportURLs, in bytes.
+const maxOwnerLength = 255
+
+// ImportReport summarizes the outcome of an import run.
+// In dry-run mode, Imported counts the rows that would have been written.
+type ImportReport struct {
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Imported  int              `json:"imported"`
+	Conflicts []string         `json:"conflicts,omitempty"`
+	Invalid   []ImportRowError `json:"invalid,omitempty"`
+}
+
+// ImportRowError describes a row that was rejected during an import run.
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// importRow is a single decoded row from an import source, along with its line number.
+// err is set instead of url when the row could not be read, so that it is reported as invalid.
+type importRow struct {
+	line int
+	url  datastore.URL
+	err  error
+}
+
+// IsValidTransferFormat reports whether the given format is supported by ExportURLs and ImportURLs.
+func IsValidTransferFormat(format string) bool {
+	return format == TransferFormatCSV || format == TransferFormatJSONL
+}
+
+// TransferFormatFromPath infers the transfer format from a file name, defaulting to JSON Lines.
+func TransferFormatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), "."+TransferFormatCSV) {
+		return TransferFormatCSV
+	}
+	return TransferFormatJSONL
+}
+
+// ExportURLs streams every URL entity from the datastore to w in the given format.
+// Entities are written as they are read, so exports of any size use constant memory.
+func ExportURLs(ctx context.Context, w io.Writer, dsClient *datastore.Client, format string) error {
+	switch format {
+	case TransferFormatCSV:
+		return exportCSV(ctx, w, dsClient)
+	case TransferFormatJSONL:
+		return exportJSONL(ctx, w, dsClient)
+	}
+	return &BadRequestError{Message: constant.HeaderResponseUnsupportedFormat}
+}
+
+// exportCSV writes every URL entity as a CSV row preceded by a header row.
+func exportCSV(ctx context.Context, w io.Writer, dsClient *datastore.Client) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(transferCSVHeader); err != nil {
+		return err
+	}
+	err := datastore.ListURLs(ctx, dsClient, func(url *datastore.URL) error {
+		return cw.Write([]string{url.ID, url.Original, url.PasswordHash})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportJSONL writes every URL entity as a JSON object on its own line.
+func exportJSONL(ctx context.Context, w io.Writer, dsClient *datastore.Client) error {
+	enc := json.NewEncoder(w)
+	return datastore.ListURLs(ctx, dsClient, func(url *datastore.URL) error {
+		return enc.Encode(transferURL{URL: url, PasswordHash: url.PasswordHash})
+	})
+}
+
+// ImportURLs reads URL entities from r in the given format and saves them to the datastore,
+// preserving their IDs and password hashes. Each row is validated with isValidURL, and its destination
+// goes through the URL policy and the scanner like the destination of a new link; selfHosts are the
+// hosts of this shortener, rejected to prevent redirect loops. Rows whose ID already
+// exists in the datastore (or earlier in the same source) are reported as conflicts instead
+// of being overwritten. When dryRun is true, nothing is written to the datastore.
+func ImportURLs(ctx context.Context, r io.Reader, dsClient *datastore.Client, format string, dryRun bool, selfHosts ...string) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun}
+	seen := make(map[string]bool)
+	err := forEachImportRow(r, format, func(row importRow) error {
+		report.Total++
+		if err := processImportRow(ctx, dsClient, row, seen, dryRun, selfHosts); err != nil {
+			if recordImportError(report, row, err) {
+				return nil
+			}
+			return err
+		}
+		report.Imported++
+		return nil
+	})
+
+	return report, err
+}
+
+// processImportRow validates a single row and, unless dryRun is set, inserts it into the datastore.
+func processImportRow(ctx context.Context, dsClient *datastore.Client, row importRow, seen map[string]bool, dryRun bool, selfHosts []string) error {
+	if row.err != nil {
+		return row.err
+	}
+	if err := validateImportRow(row); err != nil {
+		return err
+	}
+	if seen[row.url.ID] {
+		return datastore.ErrAlreadyExists
+	}
+	seen[row.url.ID] = true
+
+	url := row.url
+	if err := checkImportDestination(ctx, &url, selfHosts); err != nil {
+		return err
+	}
+	if dryRun {
+		return checkImportConflict(ctx, dsClient, url.ID)
+	}
+	normalizeImportedURL(&url)
+	return datastore.InsertURL(ctx, dsClient, &url)
+}
+
+// checkImportConflict reports datastore.ErrAlreadyExists if the ID is already taken, without writing anything.
+func checkImportConflict(ctx context.Context, dsClient *datastore.Client, id string) error {
+	_, err := datastore.GetURL(ctx, dsClient, id)
+	if err == nil {
+		return datastore.ErrAlreadyExists
+	}
+	if err == datastore.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// validateImportRow checks that a row carries a usable ID, a valid original URL and, if the link is
+// protected, a bcrypt password hash, and that its other fields hold values the handlers could have set.
+func validateImportRow(row importRow) error {
+	if row.url.ID == "" || strings.ContainsAny(row.url.ID, "/ \t") {
+		return &BadRequestError{Message: constant.HeaderResponseInvalidID}
+	}
+	if !isValidURL(row.url.Original) {
+		return &BadRequestError{Message: constant.HeaderResponseInvalidURLFormat}
+	}
+	if row.url.IsProtected() && !isValidPasswordHash(row.url.PasswordHash) {
+		return &BadRequestError{Message: constant.HeaderResponseInvalidPasswordHash}
+	}
+	if row.url.RedirectStatus != 0 && !allowedRedirectStatuses[row.url.RedirectStatus] {
+		return &BadRequestError{Message: fmt.Sprintf(ErrMsgInvalidPatchField, PatchFieldRedirectStatus)}
+	}
+	if !validTags(row.url.Tags) {
+		return &BadRequestError{Message: fmt.Sprintf(ErrMsgInvalidPatchField, PatchFieldTags)}
+	}
+	if !isValidOwner(row.url.Owner) {
+		return &BadRequestError{Message: fmt.Sprintf(ErrMsgInvalidPatchField, ImportFieldOwner)}
+	}
+	if row.url.DeletedAt.After(time.Now()) {
+		return &BadRequestError{Message: fmt.Sprintf(ErrMsgInvalidPatchField, ImportFieldDeletedAt)}
+	}
+	return nil
+}
+
+// isValidOwner reports whether owner can be the name of a principal: empty for links without an owner,
+// or printable UTF-8 text within maxOwnerLength bytes.
+func isValidOwner(owner string) bool {
+	if len(owner) > maxOwnerLength || !utf8.ValidString(owner) {
+		return false
+	}
+	return !strings.ContainsFunc(owner, func(r rune) bool { return !unicode.IsPrint(r) })
+}
+
+// normalizeImportedURL resets the fields of an imported link that only make sense in the datastore it was
+// exported from. The revision history is not exported, so the link starts over at revision zero, and a
+// quarantine reason is only kept along with the quarantine itself. Deleted links stay deleted, so that
+// they are purged after the retention period and their IDs are not handed out in the meantime.
+func normalizeImportedURL(url *datastore.URL) {
+	url.Revision = 0
+	if !url.Quarantined {
+		url.QuarantineReason = ""
+	}
+	url.CanonicalHash = canonicalHashOf(url)
+}
+
+// checkImportDestination checks the destination of an imported link against the URL policy and the scanner,
+// as checkDestination does for a new link, and records a quarantine verdict on the link. Rejected or
+// unscanned destinations are returned as a BadRequestError, so that the row is reported as invalid.
+func checkImportDestination(ctx context.Context, url *datastore.URL, selfHosts []string) error {
+	if err := urlPolicy.Check(ctx, url.Original, selfHosts...); err != nil {
+		LogURLPolicyViolation(ctx, url.Original, err)
+		return &BadRequestError{Message: err.Error()}
+	}
+	if urlScanner == nil {
+		return nil
+	}
+
+	result, err := urlScanner.Scan(ctx, url.Original)
+	if err != nil {
+		LogURLScanFailed(ctx, url.Original, err)
+		return &BadRequestError{Message: constant.HeaderResponseScannerUnavailable}
+	}
+	LogURLScanned(ctx, url.Original, result)
+	if result.Verdict == scanner.Deny {
+		return &BadRequestError{Message: constant.HeaderResponseURLFlaggedUnsafe}
+	}
+	applyScanResult(url, result)
+	return nil
+}
+
+// recordImportError adds a row-level failure to the report.
+// It returns false if the error is not row-specific and the import should be aborted.
+func recordImportError(report *ImportReport, row importRow, err error) bool {
+	if err == datastore.ErrAlreadyExists {
+		report.Conflicts = append(report.Conflicts, row.url.ID)
+		return true
+	}
+	if isBadRequestError(err) {
+		report.Invalid = append(report.Invalid, ImportRowError{Line: row.line, ID: row.url.ID, Error: err.Error()})
+		return true
+	}
+	return false
+}
+
+// forEachImportRow decodes rows from r in the given format and passes them to fn as they are read.
+func forEachImportRow(r io.Reader, format string, fn func(importRow) error) error {
+	switch format {
+	case TransferFormatCSV:
+		return forEachCSVRow(r, fn)
+	case TransferFormatJSONL:
+		return forEachJSONLRow(r, fn)
+	}
+	return &BadRequestError{Message: constant.HeaderResponseUnsupportedFormat}
+}
+
+// forEachCSVRow decodes CSV rows one at a time, skipping the header row if present.
+func forEachCSVRow(r io.Reader, fn func(importRow) error) error {
+	cr := csv.NewReader(r)
+	// Every row must have as many fields as the first one, which is either 2 or 3.
+	cr.FieldsPerRecord = 0
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil && len(record) != len(transferCSVHeader) && len(record) != len(transferCSVHeader)-1 {
+			err = csv.ErrFieldCount
+		}
+		if err != nil {
+			return &BadRequestError{Message: fmt.Sprintf(constant.HeaderResponseMalformedImport+": %v", err)}
+		}
+		if line == 1 && record[0] == transferCSVHeader[0] {
+			continue
+		}
+		url := datastore.URL{ID: record[0], Original: record[1]}
+		if len(record) == len(transferCSVHeader) {
+			url.PasswordHash = record[2]
+		}
+		if err := fn(importRow{line: line, url: url}); err != nil {
+			return err
+		}
+	}
+}
+
+// forEachJSONLRow decodes one JSON object per line, skipping blank lines. Lines longer than
+// maxImportLineSize are passed to fn as invalid rows.
+func forEachJSONLRow(r io.Reader, fn func(importRow) error) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, tooLong, err := readImportLine(br, maxImportLineSize)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if tooLong {
+			if err := fn(importRow{line: line, err: &BadRequestError{Message: constant.HeaderResponseImportLineTooLong}}); err != nil {
+				return err
+			}
+		} else if text := bytes.TrimSpace(data); len(text) > 0 {
+			decoded := transferURL{URL: &datastore.URL{}}
+			if err := json.Unmarshal(text, &decoded); err != nil {
+				return &BadRequestError{Message: fmt.Sprintf(constant.HeaderResponseMalformedImport+" at line %d: %v", line, err)}
+			}
+			url := *decoded.URL
+			url.PasswordHash = decoded.PasswordHash
+			if err := fn(importRow{line: line, url: url}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// readImportLine reads one line from br without its line terminator. If the line is longer than
+// limit bytes, the rest of it is skipped and tooLong is set instead.
+func readImportLine(br *bufio.Reader, limit int) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, isPrefix, err := br.ReadLine()
+		if !tooLong && len(line)+len(chunk) > limit {
+			line, tooLong = nil, true
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err != nil || !isPrefix {
+			return line, tooLong, err
+		}
+	}
+}
+
+// exportURLsHandlerGin returns a Gin handler function that streams every URL entity to the client
+// in the format selected by the "format" query parameter (csv or jsonl, defaulting to jsonl).
+func exportURLsHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery(QueryFormat, TransferFormatJSONL)
+		if !IsValidTransferFormat(format) {
+			handleError(c, constant.HeaderResponseUnsupportedFormat, http.StatusBadRequest, nil)
+			return
+		}
+
+		c.Header(constant.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", DataTransferFileName+"."+format))
+		c.Header(constant.HeaderContentType, transferContentType(format))
+		c.Status(http.StatusOK)
+
+		if err := ExportURLs(c.Request.Context(), c.Writer, dsClient, format); err != nil {
+			// The response has already started streaming, so the failure can only be logged.
+			LogTransferError(c.Request.Context(), operation_exportURL, err)
+			c.Abort()
+			return
+		}
+
+		LogURLsExported(c.Request.Context(), format)
+	}
+}
+
+// importURLsHandlerGin returns a Gin handler function that imports URL entities from the request body.
+// The format is selected by the "format" query parameter, and "dry_run=true" validates the
+// payload and reports conflicts without writing anything to the datastore.
+func importURLsHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery(QueryFormat, TransferFormatJSONL)
+		dryRun := c.Query(QueryDryRun) == "true"
+
+		report, err := ImportURLs(c.Request.Context(), c.Request.Body, dsClient, format, dryRun, c.Request.Host)
+		if err != nil {
+			handleImportError(c, report, err)
+			return
+		}
+
+		LogURLsImported(c.Request.Context(), report.Total, report.Imported, dryRun)
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// handleImportError responds to a failed import, including the partial report when one is available.
+func handleImportError(c *gin.Context, report *ImportReport, err error) {
+	LogTransferError(c.Request.Context(), operation_importURL, err)
+
+	status := http.StatusInternalServerError
+	message := constant.HeaderResponseInternalServerError
+	if badRequestErr, ok := err.(*BadRequestError); ok {
+		status = http.StatusBadRequest
+		message = badRequestErr.Message
+	}
+
+	c.AbortWithStatusJSON(status, gin.H{
+		constant.HeaderResponseError:     message,
+		constant.HeaderResponseRequestID: logmonitor.RequestIDFromContext(c.Request.Context()),
+		constant.HeaderResponseReport:    report,
+	})
+}
+
+// transferContentType returns the response content type for a transfer format.
+func transferContentType(format string) string {
+	if format == TransferFormatCSV {
+		return constant.ContentTypeCSV
+	}
+	return constant.ContentTypeNDJSON
+}
@@ -3,11 +3,14 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
 	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
+	"github.com/H0llyW00dzZ/go-urlshortner/scanner"
+	"github.com/H0llyW00dzZ/go-urlshortner/webhook"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
 // getURLHandlerGin returns a Gin handler function that retrieves and redirects to the original
@@ -31,66 +34,74 @@ func getURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
 
 		if url == nil {
 			// It's usually better to log the internal error inside the LogInternalError function
-			LogInternalError(operation_getURL, id, err) // Assuming this is a function that logs the error
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{constant.HeaderResponseError: constant.HeaderResponseInternalServerError})
+			LogInternalError(c.Request.Context(), operation_getURL, id, err) // Assuming this is a function that logs the error
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorBody(c, constant.HeaderResponseInternalServerError))
 			return
 		}
 
-		LogURLRetrievalSuccess(id) // Assuming this is a function that logs the success
-		c.Redirect(http.StatusFound, url.Original)
+		// Expired and disabled links no longer redirect.
+		if !checkLinkAvailable(c, url) {
+			return
+		}
+
+		// Protected links serve an unlock form instead of redirecting straight away.
+		if url.IsProtected() {
+			renderUnlockForm(c, http.StatusOK, "")
+			return
+		}
+
+		// Quarantined links serve a warning page instead of redirecting.
+		followURL(c, url, redirectStatusOf(url))
 	}
 }
 
 // handleGetURLError centralizes the error handling for the getURLHandlerGin function.
 func handleGetURLError(c *gin.Context, id string, err error) {
 	if err == datastore.ErrNotFound {
-		LogURLNotFound(id, err)
+		LogURLNotFound(c.Request.Context(), id, err)
+		metrics.Redirects.Inc(metrics.RedirectMiss)
 		// Respond with 404 Not Found, as this is the correct status for a missing resource.
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			constant.HeaderResponseError: constant.URLnotfoundContextLog,
-		})
+		c.AbortWithStatusJSON(http.StatusNotFound, errorBody(c, constant.URLnotfoundContextLog))
 		// For any other errors, log the internal error event and return a 500 Internal Server Error response.
 	} else {
-		LogInternalError(operation_getURL, id, err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			constant.HeaderResponseError: constant.HeaderResponseInternalServerError,
-		})
+		LogInternalError(c.Request.Context(), operation_getURL, id, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorBody(c, constant.HeaderResponseInternalServerError))
 	}
 }
 
-// applyRateLimit checks if the rate limit has been exceeded for a given identifier.
-// It writes the appropriate response if the rate limit is exceeded.
-//
-// Note: Better keep set it as response 404 not found for this limiter since it's public access,
-// for indicate that client/user are bad requesting, not the server.
-func applyRateLimit(c *gin.Context) bool {
-	key := c.ClientIP()
-	id := c.Param(constant.HeaderID) // Use a string directly if it's not a constant that changes.
-	limiter := NewRateLimiter(key, rate.Limit(1), 10)
-	if !limiter.Allow() {
-		// Log with an emoji to indicate rate limiting has occurred.
-		logAttemptToRetrieve(id) // Pass Context Log
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			constant.HeaderResponseError: constant.URLnotfoundContextLog,
-		})
-		return false
-	}
-	return true
-}
-
 // postURLHandlerGin returns a Gin handler function that handles the creation of a new shortened
 // URL. It expects a JSON payload with the original URL, generates a short identifier, and stores
 // the mapping in the datastore. If successful, it returns the generated identifier and
 // the shortened URL; otherwise, it responds with an error.
 func postURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Extract and validate the original URL from the request body.
-		url, err := extractURL(c)
+		// Extract and validate the original URL and the optional password from the request body.
+		req, err := extractURL(c)
 		if err != nil {
 			handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, err)
 			return
 		}
 
+		// Canonicalize the destination, then check it against the policy and the URL scanner.
+		var scan scanner.Result
+		var ok bool
+		if req.URL, scan, ok = checkDestination(c, req.URL); !ok {
+			return
+		}
+
+		// Reuse the caller's existing link for the same destination, if canonicalization is enabled.
+		existing, err := findReusableURL(c, dsClient, req)
+		if err != nil {
+			handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+			return
+		}
+		if existing != nil {
+			LogURLReused(c.Request.Context(), existing.ID, principalName(c))
+			setAuditChange(c, existing.ID, nil, nil)
+			respondWithShortenedURL(c, existing.ID, existing.Quarantined, true)
+			return
+		}
+
 		// Generate a short identifier for the URL.
 		id, err := generateShortID(c.Request.Context(), dsClient)
 		if err != nil {
@@ -99,22 +110,50 @@ func postURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
 		}
 
 		// Save the URL with the generated identifier into the datastore.
-		if err := saveURL(c, dsClient, id, url); err != nil {
+		url, err := saveURL(c, dsClient, id, req, scan)
+		if err != nil {
 			handleError(c, constant.HeaderResponseFailedtoSaveURL, http.StatusInternalServerError, err)
 			return
 		}
 
 		// Use the centralized logging function to log the successful shortening of the URL.
-		LogURLShortened(id)
+		LogURLShortened(c.Request.Context(), id, principalName(c))
+		emitLinkEvent(c.Request.Context(), dsClient, webhook.EventLinkCreated, linkEventData{Link: url})
 
-		// Construct the full shortened URL and return it in the response.
-		fullShortenedURL := constructFullShortenedURL(c, id)
-		c.JSON(http.StatusOK, gin.H{
-			constant.HeaderID: id, constant.HeaderResponseshortened_url: fullShortenedURL,
-		})
+		respondWithShortenedURL(c, id, scan.Verdict == scanner.Quarantine, false)
 	}
 }
 
+// checkDestination canonicalizes the destination URL if canonicalization is enabled, and checks it against
+// the URL policy and the URL scanner. It returns the URL to store and the verdict of the scanner, or responds
+// with an error and returns false.
+func checkDestination(c *gin.Context, rawURL string) (string, scanner.Result, bool) {
+	rawURL, ok := canonicalizeURL(c, rawURL)
+	if !ok {
+		return rawURL, scanner.Result{}, false
+	}
+
+	// Reject destinations such as internal addresses, blocked domains or this shortener itself.
+	if !checkURLPolicy(c, rawURL) {
+		return rawURL, scanner.Result{}, false
+	}
+
+	// Ask the URL scanner, if any, whether the destination is malicious.
+	scan, ok := scanURL(c, rawURL)
+	return rawURL, scan, ok
+}
+
+// respondWithShortenedURL constructs the full shortened URL and returns it in the response,
+// indicating whether the link is quarantined and whether an existing link was reused.
+func respondWithShortenedURL(c *gin.Context, id string, quarantined bool, reused bool) {
+	fullShortenedURL := constructFullShortenedURL(c, id)
+	c.JSON(http.StatusOK, gin.H{
+		constant.HeaderID: id, constant.HeaderResponseshortened_url: fullShortenedURL,
+		constant.HeaderResponseQuarantined: quarantined,
+		constant.HeaderResponseReused:      reused,
+	})
+}
+
 // editURLHandlerGin returns a Gin handler function that handles the updating of an existing shortened URL.
 func editURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -125,12 +164,19 @@ func editURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
 			return
 		}
 
-		err = updateURL(c, dsClient, pathID, req)
+		var scan scanner.Result
+		var ok bool
+		if req.NewURL, scan, ok = checkDestination(c, req.NewURL); !ok {
+			return
+		}
+
+		url, err := updateURL(c, dsClient, pathID, req, scan)
 		if err != nil {
 			handleUpdateError(c, pathID, err)
 			return
 		}
 
+		emitLinkEvent(c.Request.Context(), dsClient, webhook.EventLinkEdited, linkEventData{Link: url})
 		respondWithUpdatedURL(c, pathID)
 	}
 }
@@ -149,7 +195,7 @@ func validateUpdateRequest(c *gin.Context) (pathID string, req UpdateURLPayload,
 	if pathID != req.ID {
 		err := fmt.Errorf(constant.PathIDandPayloadIDDoesnotMatchContextLog)
 		// Use the centralized logging function to log the mismatch error.
-		LogMismatchError(pathID)
+		LogMismatchError(c.Request.Context(), pathID)
 		return "", req, err
 	}
 
@@ -157,32 +203,50 @@ func validateUpdateRequest(c *gin.Context) (pathID string, req UpdateURLPayload,
 }
 
 // updateURL retrieves the current URL, verifies it against the provided old URL, and updates it with the new URL.
-// It returns an error with a message suitable for HTTP response if any step fails.
-func updateURL(c *gin.Context, dsClient *datastore.Client, id string, req UpdateURLPayload) error {
-	logAttemptToRetrieve(id)
+// The verdict of the URL scanner on the new URL is stored with it, so a link can be quarantined or released by an edit.
+// It returns the updated URL, or an error with a message suitable for HTTP response if any step fails.
+func updateURL(c *gin.Context, dsClient *datastore.Client, id string, req UpdateURLPayload, scan scanner.Result) (*datastore.URL, error) {
+	logAttemptToRetrieve(c.Request.Context(), id)
 
 	currentURL, err := datastore.GetURL(c, dsClient, id)
 	if err != nil {
 		// Instead of handling the error here, we return it to the caller to handle.
-		return handleRetrievalError(err, id)
+		return nil, handleRetrievalError(c.Request.Context(), err, id)
+	}
+
+	// Deleted links can only be restored, not edited.
+	if currentURL.IsDeleted() {
+		return nil, datastore.ErrNotFound
 	}
 
-	if currentURL.Original != req.OldURL {
+	if err := authorizeLinkAccess(c, currentURL); err != nil {
+		return nil, err
+	}
+
+	if !sameDestination(currentURL.Original, req.OldURL) {
 		// Return a URLMismatchError which can be handled specifically by the caller.
-		return &URLMismatchError{Message: constant.URLmismatchContextLog}
+		return nil, &URLMismatchError{Message: constant.URLmismatchContextLog}
 	}
 
-	logAttemptToUpdate(id)
+	logAttemptToUpdate(c.Request.Context(), id)
 
 	// Update the URL in the datastore with the new URL.
-	if err := datastore.UpdateURL(c, dsClient, id, req.NewURL); err != nil {
+	var updated *datastore.URL
+	err = datastore.MutateURL(c, dsClient, id, datastore.Change{Actor: principalName(c), Operation: datastore.OperationEdit}, auditURLChange(c, func(url *datastore.URL) error {
+		url.Original = req.NewURL
+		applyScanResult(url, scan)
+		url.CanonicalHash = canonicalHashOf(url)
+		updated = url
+		return nil
+	}))
+	if err != nil {
 		// Return the error to the caller to handle.
-		return err
+		return nil, err
 	}
 
-	logSuccessfulUpdate(id)
+	logSuccessfulUpdate(c.Request.Context(), id)
 
-	return nil
+	return updated, nil
 }
 
 // respondWithUpdatedURL constructs and sends a JSON response with the updated URL information.
@@ -195,37 +259,43 @@ func respondWithUpdatedURL(c *gin.Context, id string) {
 	})
 }
 
-// extractURL extracts the original URL from the JSON payload in the request.
-func extractURL(c *gin.Context) (string, error) {
+// extractURL extracts the creation payload, including the original URL, from the JSON body of the request.
+func extractURL(c *gin.Context) (CreateURLPayload, error) {
 	var req CreateURLPayload
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Replace the direct logger call with a centralized logging function
 		// LogBadRequestError("extractURL", err)
 		SynclogError(c, operation_extractURL, err) // Replaced with centralized logging function
-		return "", err
+		return req, err
 	}
 
 	// Check if the URL is in a valid format.
 	if req.URL == "" || !isValidURL(req.URL) {
 		// Replace the direct logger call with a centralized logging function
-		LogInvalidURLFormat(req.URL)
-		return "", fmt.Errorf(constant.HeaderResponseInvalidURLFormat)
+		LogInvalidURLFormat(c.Request.Context(), req.URL)
+		return req, fmt.Errorf(constant.HeaderResponseInvalidURLFormat)
+	}
+
+	// bcrypt refuses passwords longer than 72 bytes, which a multibyte password can reach within 72 characters.
+	if !isValidLinkPassword(req.Password) {
+		return req, fmt.Errorf(constant.HeaderResponsePasswordTooLong)
 	}
 
-	return req.URL, nil
+	return req, nil
 }
 
 // deleteURLHandlerGin returns a Gin handler function that handles the deletion of an existing shortened URL.
 func deleteURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param(constant.HeaderID)
-		if err := validateAndDeleteURL(c, dsClient); err != nil {
+		if url, err := validateAndDeleteURL(c, dsClient); err != nil {
 			// Use the centralized logging function to log the deletion error.
-			LogDeletionError(id, err)
+			LogDeletionError(c.Request.Context(), id, err)
 			handleDeletionError(c, err)
 		} else {
 			// Use the centralized logging function to log the successful deletion.
-			LogURLDeletionSuccess(id)
+			LogURLDeletionSuccess(c.Request.Context(), id)
+			emitLinkEvent(c.Request.Context(), dsClient, webhook.EventLinkDeleted, linkEventData{Link: url})
 			c.JSON(http.StatusOK, gin.H{
 				constant.HeaderMessage: constant.HeaderResponseURLDeleted,
 			})
@@ -234,26 +304,27 @@ func deleteURLHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
 }
 
 // validateAndDeleteURL validates the ID and URL and performs the deletion if they are correct.
-func validateAndDeleteURL(c *gin.Context, dsClient *datastore.Client) error {
+// It returns the link as it was before the deletion.
+func validateAndDeleteURL(c *gin.Context, dsClient *datastore.Client) (*datastore.URL, error) {
 	idFromPath := c.Param(constant.HeaderID) // Extract the ID from the URL path
 
 	// Bind the JSON payload to the DeleteURLPayload struct.
 	var req DeleteURLPayload
 	if err := c.ShouldBindJSON(&req); err != nil {
 		SynclogError(c, idFromPath, err) // Log the bad request error
-		return &BadRequestError{Message: constant.HeaderResponseInvalidRequestPayload}
+		return nil, &BadRequestError{Message: constant.HeaderResponseInvalidRequestPayload}
 	}
 
 	// Check if the IDs match
 	if idFromPath != req.ID {
-		LogMismatchError(idFromPath) // Log the mismatch error
-		return &URLMismatchError{Message: constant.URLmismatchContextLog}
+		LogMismatchError(c.Request.Context(), idFromPath) // Log the mismatch error
+		return nil, &URLMismatchError{Message: constant.URLmismatchContextLog}
 	}
 
 	// Validate the URL format.
 	if !isValidURL(req.URL) {
-		LogInvalidURLFormat(req.URL) // Log the invalid URL format error
-		return &BadRequestError{Message: constant.HeaderResponseInvalidURLFormat}
+		LogInvalidURLFormat(c.Request.Context(), req.URL) // Log the invalid URL format error
+		return nil, &BadRequestError{Message: constant.HeaderResponseInvalidURLFormat}
 	}
 
 	// Perform the delete operation.
@@ -261,23 +332,33 @@ func validateAndDeleteURL(c *gin.Context, dsClient *datastore.Client) error {
 }
 
 // deleteURL verifies the provided ID and URL against the stored URL entity, and if they match, deletes the URL entity.
-func deleteURL(c *gin.Context, dsClient *datastore.Client, id string, providedURL string) error {
+// It returns the URL entity as it was before the deletion.
+func deleteURL(c *gin.Context, dsClient *datastore.Client, id string, providedURL string) (*datastore.URL, error) {
 	// Retrieve the current URL from the datastore.
 	currentURL, err := getCurrentURL(c, dsClient, id)
 	if err != nil {
 		// If an error occurs, return it. getCurrentURL will return a formatted error or datastore.ErrNotFound.
-		return err
+		return nil, err
 	}
 
-	// Check if the current URL matches the provided URL.
-	if currentURL.Original != providedURL {
+	// Only the owner of the link or an admin may delete it.
+	if err := authorizeLinkAccess(c, currentURL); err != nil {
+		return nil, err
+	}
+
+	// Check if the current URL matches the provided URL, in any spelling that canonicalizes to it.
+	if !sameDestination(currentURL.Original, providedURL) {
 		// If they do not match, return a custom URLMismatchError instead of a generic error (known as default standart library error/fmt error),
 		// which is bad for host machine and datastore when using generic error, it literally break the machine (can't imagine if there is no recovery mode lol).
-		return &URLMismatchError{Message: constant.URLmismatchContextLog}
+		return nil, &URLMismatchError{Message: constant.URLmismatchContextLog}
 	}
 
 	// If the URLs match, perform the deletion operation.
-	return performDelete(c, dsClient, id)
+	if err := performDelete(c, dsClient, id); err != nil {
+		return nil, err
+	}
+	setAuditChange(c, id, currentURL, nil)
+	return currentURL, nil
 }
 
 // getCurrentURL retrieves the current URL from the datastore and checks for errors.
@@ -289,22 +370,42 @@ func getCurrentURL(c *gin.Context, dsClient *datastore.Client, id string) (*data
 		}
 		return nil, fmt.Errorf(constant.FailedToRetriveURLContextLog+": %v", err)
 	}
+	// A link that is already deleted is treated as missing.
+	if currentURL.IsDeleted() {
+		return nil, datastore.ErrNotFound
+	}
 	return currentURL, nil
 }
 
-// performDelete deletes the URL entity from the datastore.
+// performDelete soft deletes the URL entity in the datastore.
 func performDelete(c *gin.Context, dsClient *datastore.Client, id string) error {
-	if err := datastore.DeleteURL(c, dsClient, id); err != nil {
+	// The link is only marked as deleted; it can be restored until it is purged after the retention period.
+	if err := datastore.SoftDeleteURL(c, dsClient, id, principalName(c), time.Now()); err != nil {
 		return fmt.Errorf(constant.FailedToDeletedURLContextLog+": %v", err)
 	}
 	return nil
 }
 
 // saveURL saves the URL and its identifier to the datastore.
-func saveURL(c *gin.Context, dsClient *datastore.Client, id string, originalURL string) error {
+// If the payload carries a password, only its bcrypt hash is stored. A quarantine verdict of the scanner is stored with the link.
+func saveURL(c *gin.Context, dsClient *datastore.Client, id string, req CreateURLPayload, scan scanner.Result) (*datastore.URL, error) {
+	passwordHash, err := hashLinkPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	url := &datastore.URL{
-		Original: originalURL,
-		ID:       id,
+		Original:     req.URL,
+		ID:           id,
+		Owner:        principalName(c),
+		PasswordHash: passwordHash,
+	}
+	applyScanResult(url, scan)
+	url.CanonicalHash = canonicalHashOf(url)
+	// InsertURL refuses to overwrite an existing entity, including a deleted link that has not been purged yet.
+	if err := datastore.InsertURL(c, dsClient, url); err != nil {
+		return nil, err
 	}
-	return datastore.SaveURL(c, dsClient, url)
+	setAuditChange(c, id, nil, url)
+	return url, nil
 }
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/H0llyW00dzZ/go-urlshortner/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// webhookConfig holds the webhook delivery settings, configured from environment variables during
+// package initialization. webhookSender delivers the events with the configured timeout.
+var (
+	webhookConfig webhook.Config
+	webhookSender *webhook.Sender
+)
+
+// webhookWake wakes the dispatcher up when new deliveries are queued, so that they are sent without
+// waiting for the next poll. It holds at most one signal, as one wake-up sends every due delivery.
+var webhookWake = make(chan struct{}, 1)
+
+// linkEventData is the data of link events sent to webhook subscribers.
+type linkEventData struct {
+	Link   *datastore.URL `json:"link"`
+	Clicks int64          `json:"clicks,omitempty"` // The threshold passed, for link.clicked events.
+}
+
+// CreateWebhookPayload defines the structure for the JSON payload when creating a webhook subscription.
+type CreateWebhookPayload struct {
+	Name   string   `json:"name" binding:"required"`
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+// emitLinkEvent queues a delivery of the event to every subscription of its type. Failures are logged
+// rather than returned, so that a webhook problem never fails the operation that caused the event.
+func emitLinkEvent(ctx context.Context, dsClient *datastore.Client, eventType string, data linkEventData) {
+	if err := enqueueLinkEvent(ctx, dsClient, eventType, data); err != nil {
+		LogWebhookEnqueueFailed(ctx, eventType, data.Link.ID, err)
+	}
+}
+
+// enqueueLinkEvent queues a delivery of the event for each webhook subscribed to its type, and wakes the
+// dispatcher. It returns the error of the first step that failed, so that the caller can try again.
+func enqueueLinkEvent(ctx context.Context, dsClient *datastore.Client, eventType string, data linkEventData) error {
+	// The deliveries are queued even if the client that caused the event has gone away.
+	ctx = context.WithoutCancel(ctx)
+	subs, err := datastore.ListWebhookSubscriptions(ctx, dsClient, eventType)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(webhook.NewEvent(eventType, data))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]*datastore.WebhookDelivery, len(subs))
+	for i, sub := range subs {
+		deliveries[i] = &datastore.WebhookDelivery{
+			ID:            webhook.NewDeliveryID(now),
+			Subscription:  sub.Name,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        datastore.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+	}
+	if err := datastore.EnqueueWebhookDeliveries(ctx, dsClient, deliveries); err != nil {
+		return err
+	}
+
+	wakeWebhookDispatcher()
+	return nil
+}
+
+// wakeWebhookDispatcher signals the dispatcher that deliveries are due, unless a signal is already pending.
+func wakeWebhookDispatcher() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// createWebhookHandlerGin returns a Gin handler function that creates a webhook subscription. The
+// endpoint must pass the destination policy, so that webhooks cannot reach internal services. The
+// signing secret is generated and returned once, in the response.
+func createWebhookHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateWebhookPayload
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequestPayload, http.StatusBadRequest, err)
+			return
+		}
+		if !apiKeyNamePattern.MatchString(req.Name) {
+			handleError(c, constant.HeaderResponseInvalidWebhookName, http.StatusBadRequest, nil)
+			return
+		}
+		if err := webhook.ValidateEventTypes(req.Events); err != nil {
+			handleError(c, err.Error(), http.StatusBadRequest, nil)
+			return
+		}
+		if !checkURLPolicy(c, req.URL) {
+			return
+		}
+
+		sub := &datastore.WebhookSubscription{
+			Name:      req.Name,
+			URL:       req.URL,
+			Secret:    webhook.NewSecret(),
+			Events:    req.Events,
+			CreatedAt: time.Now(),
+		}
+		if err := datastore.InsertWebhookSubscription(c.Request.Context(), dsClient, sub); err != nil {
+			handleWebhookError(c, req.Name, err)
+			return
+		}
+
+		setAuditChange(c, sub.Name, nil, sub)
+		LogWebhookCreated(c.Request.Context(), sub.Name)
+		c.JSON(http.StatusCreated, gin.H{
+			constant.HeaderResponseSecret:       sub.Secret,
+			constant.HeaderResponseSubscription: sub,
+		})
+	}
+}
+
+// listWebhooksHandlerGin returns a Gin handler function that lists every webhook subscription without their secrets.
+func listWebhooksHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subs, err := datastore.ListWebhookSubscriptions(c.Request.Context(), dsClient, "")
+		if err != nil {
+			handleWebhookError(c, "", err)
+			return
+		}
+		c.JSON(http.StatusOK, subs)
+	}
+}
+
+// deleteWebhookHandlerGin returns a Gin handler function that deletes a webhook subscription.
+func deleteWebhookHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param(constant.HeaderName)
+		if err := datastore.DeleteWebhookSubscription(c.Request.Context(), dsClient, name); err != nil {
+			handleWebhookError(c, name, err)
+			return
+		}
+
+		LogWebhookDeleted(c.Request.Context(), name)
+		c.JSON(http.StatusOK, gin.H{constant.HeaderMessage: constant.HeaderResponseWebhookDeleted})
+	}
+}
+
+// listWebhookDeliveriesHandlerGin returns a Gin handler function that lists webhook deliveries page by
+// page, oldest first. The "subscription" and "status" query parameters narrow the list down, for
+// example to the dead-lettered deliveries of one subscription.
+func listWebhookDeliveriesHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := parseListLimit(c.Query(QueryLimit))
+		if err != nil {
+			handleError(c, constant.HeaderResponseInvalidRequest, http.StatusBadRequest, nil)
+			return
+		}
+
+		page, err := datastore.ListWebhookDeliveries(c.Request.Context(), dsClient,
+			c.Query(QuerySubscription), c.Query(QueryStatus), limit, c.Query(QueryCursor))
+		if err != nil {
+			handleWebhookError(c, "", err)
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// getWebhookDeliveryHandlerGin returns a Gin handler function that shows one webhook delivery,
+// including its payload and the outcome of its last attempt.
+func getWebhookDeliveryHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery, err := datastore.GetWebhookDelivery(c.Request.Context(), dsClient, c.Param(constant.HeaderDelivery))
+		if err != nil {
+			handleWebhookDeliveryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// retryWebhookDeliveryHandlerGin returns a Gin handler function that queues a dead-lettered delivery
+// again, with a fresh set of attempts. Deliveries that are not dead are rejected with HTTP 409.
+func retryWebhookDeliveryHandlerGin(dsClient *datastore.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery, err := datastore.GetWebhookDelivery(c.Request.Context(), dsClient, c.Param(constant.HeaderDelivery))
+		if err != nil {
+			handleWebhookDeliveryError(c, err)
+			return
+		}
+		if delivery.Status != datastore.DeliveryDead {
+			handleError(c, constant.HeaderResponseDeliveryNotDead, http.StatusConflict, nil)
+			return
+		}
+
+		before := *delivery
+		delivery.Status = datastore.DeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+		if err := datastore.SaveWebhookDelivery(c.Request.Context(), dsClient, delivery); err != nil {
+			handleWebhookDeliveryError(c, err)
+			return
+		}
+
+		setAuditChange(c, delivery.ID, &before, delivery)
+		wakeWebhookDispatcher()
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// handleWebhookError maps webhook subscription errors to HTTP responses.
+func handleWebhookError(c *gin.Context, name string, err error) {
+	switch err {
+	case datastore.ErrAlreadyExists:
+		handleError(c, constant.HeaderResponseWebhookExists, http.StatusConflict, err)
+	case datastore.ErrNotFound:
+		handleError(c, constant.HeaderResponseWebhookNotFound, http.StatusNotFound, err)
+	default:
+		LogInternalError(c.Request.Context(), operation_webhook, name, err)
+		handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+	}
+}
+
+// handleWebhookDeliveryError maps webhook delivery errors to HTTP responses.
+func handleWebhookDeliveryError(c *gin.Context, err error) {
+	if err == datastore.ErrNotFound {
+		handleError(c, constant.HeaderResponseDeliveryNotFound, http.StatusNotFound, err)
+		return
+	}
+	LogInternalError(c.Request.Context(), operation_webhook, c.Param(constant.HeaderDelivery), err)
+	handleError(c, constant.HeaderResponseInternalServerError, http.StatusInternalServerError, err)
+}
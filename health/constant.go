@@ -0,0 +1,53 @@
+package health
+
+import "time"
+
+// Define environment variables used to configure the probes.
+const (
+	// PROBE_ADDR is the address of a listener serving the probes apart from the public port.
+	PROBE_ADDR = "PROBE_ADDR"
+	// SHUTDOWN_DRAIN_DELAY is how long the server keeps serving after readiness starts to fail on shutdown.
+	SHUTDOWN_DRAIN_DELAY = "SHUTDOWN_DRAIN_DELAY"
+)
+
+// Define the paths of the probe endpoints.
+const (
+	PathHealthz = "/healthz" // Liveness: the process is up and serving requests.
+	PathReadyz  = "/readyz"  // Readiness: the service can handle traffic.
+)
+
+// Define the status of a report and of each check.
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Define the names of the checks that every readiness report includes.
+const (
+	// CheckShutdown fails once the server has started to shut down, so that traffic is sent elsewhere.
+	CheckShutdown = "shutdown"
+)
+
+// Define the names of the checks registered by the service.
+const (
+	CheckDatastore = "datastore" // Datastore answers reads.
+	CheckJWKS      = "jwks"      // The signing keys of bearer tokens are loaded, when tokens are accepted.
+)
+
+// Define the defaults of the readiness checks.
+const (
+	// DefaultCheckTimeout bounds how long a readiness check may take before it is reported as failing.
+	DefaultCheckTimeout = 2 * time.Second
+	// DefaultCacheTTL is how long the results of the readiness checks are reused.
+	DefaultCacheTTL = 2 * time.Second
+	// DefaultDrainDelay is how long the server keeps serving after Drain, long enough for a few failed
+	// readiness probes at the usual periods of load balancers and Kubernetes.
+	DefaultDrainDelay = 5 * time.Second
+)
+
+// Define error messages for health checks.
+const (
+	ErrMsgDraining    = "health: the server is shutting down"
+	ErrMsgCheckExists = "health: check %q is already registered"
+	ErrMsgDrainDelay  = "health: invalid %s %q"
+)
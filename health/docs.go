@@ -0,0 +1,46 @@
+// Package health serves the liveness and readiness probes of the URL shortener service, as used by
+// Kubernetes and load balancers.
+//
+// The liveness endpoint, PathHealthz, answers 200 OK whenever the process can serve a request. It runs
+// no check, so that an outage of a dependency does not get every replica restarted.
+//
+// The readiness endpoint, PathReadyz, runs the registered checks concurrently, each bounded by
+// DefaultCheckTimeout, and answers 200 OK if they all pass, or 503 Service Unavailable otherwise. Its
+// body reports the status of each check:
+//
+//	{"status":"failing","checks":{"datastore":{"status":"ok","duration":"4.1ms"},
+//	 "shutdown":{"status":"failing","duration":"0s"}}}
+//
+// The results are reused for DefaultCacheTTL, so that frequent probes do not each query the
+// dependencies, and the errors of failing checks are logged with the logger set by SetLogger rather
+// than served, as they may reveal internal details. The shutdown check is always included and never
+// cached: it fails as soon as Drain is called, when the server starts to shut down, so that no new
+// traffic is routed to it while in-flight requests complete.
+//
+// On shutdown, the service calls Drain and keeps serving for the delay returned by DrainDelayFromEnv
+// before it stops accepting connections, as load balancers only stop routing traffic to it after a
+// few failed probes. The delay is taken out of the time allowed for the shutdown.
+//
+// The service serves the probes on the public router by default, or on a listener of their own at
+// PROBE_ADDR, so that they are only reachable from where the orchestrator runs.
+//
+// # Example Usage
+//
+//	health.Register(health.CheckDatastore, func(ctx context.Context) error {
+//	    _, err := datastore.GetURL(ctx, client, "health_check")
+//	    if err == datastore.ErrNotFound {
+//	        return nil
+//	    }
+//	    return err
+//	})
+//	health.SetLogger(logger)
+//	mux := http.NewServeMux()
+//	mux.Handle(health.PathHealthz, health.LivenessHandler())
+//	mux.Handle(health.PathReadyz, health.ReadinessHandler())
+//	go http.ListenAndServe(os.Getenv(health.PROBE_ADDR), mux)
+//	// On SIGTERM:
+//	health.Drain()
+//	time.Sleep(drainDelay) // From health.DrainDelayFromEnv, read at startup.
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package health
@@ -0,0 +1,258 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor"
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"go.uber.org/zap"
+)
+
+// CheckFunc reports whether a dependency of the service is usable. It should return promptly once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of one readiness check. The error is logged rather than served, as the
+// probes may be reachable by anyone and errors of dependencies can reveal internal details.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"-"`
+	Duration string `json:"duration"`
+}
+
+// Report is the body of the probe endpoints.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs the readiness checks of the service and tracks whether it is shutting down.
+// It is safe for concurrent use by multiple goroutines.
+type Checker struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	logger   atomic.Pointer[zap.Logger]
+	mu       sync.RWMutex
+	names    []string
+	checks   map[string]CheckFunc
+	draining atomic.Bool
+
+	// runMu serializes the runs of the checks, so that concurrent probes share one run.
+	runMu     sync.Mutex
+	results   map[string]CheckResult
+	checkedAt time.Time
+}
+
+// Default is the checker of the service, served by LivenessHandler and ReadinessHandler.
+var Default = NewChecker(DefaultCheckTimeout, DefaultCacheTTL)
+
+// NewChecker creates a checker without checks, whose checks each get at most timeout to complete.
+// Their results are reused for cacheTTL, so that frequent probes do not each query the dependencies;
+// a cacheTTL of zero runs the checks for every report.
+func NewChecker(timeout, cacheTTL time.Duration) *Checker {
+	c := &Checker{timeout: timeout, cacheTTL: cacheTTL, checks: make(map[string]CheckFunc)}
+	c.logger.Store(zap.NewNop())
+	return c
+}
+
+// SetLogger sets the logger that failed checks are logged with. Until it is called, they are not logged.
+func (c *Checker) SetLogger(logger *zap.Logger) {
+	c.logger.Store(logger)
+}
+
+// Register adds a readiness check under name. It panics if a check is already registered under that name,
+// as that is a programming error.
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.checks[name]; ok || name == CheckShutdown {
+		panic(fmt.Sprintf(ErrMsgCheckExists, name))
+	}
+	c.names = append(c.names, name)
+	c.checks[name] = check
+
+	// The cached results lack the new check, so the next report runs them all again.
+	c.runMu.Lock()
+	c.results = nil
+	c.runMu.Unlock()
+}
+
+// Drain makes readiness fail from now on, as the server is shutting down. It cannot be undone.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called.
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Readiness reports the results of the checks, running them again if the cached results are older than
+// the cache TTL of the checker. The report is failing if any check fails or if the server is shutting down;
+// the shutdown check is never cached, so that draining takes effect immediately.
+func (c *Checker) Readiness(ctx context.Context) Report {
+	results := c.cachedResults(ctx)
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(results)+1)}
+	shutdown := CheckResult{Status: StatusOK, Duration: time.Duration(0).String()}
+	if c.Draining() {
+		shutdown.Status, shutdown.Error = StatusFailing, ErrMsgDraining
+	}
+	report.Checks[CheckShutdown] = shutdown
+	for name, result := range results {
+		report.Checks[name] = result
+	}
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	return report
+}
+
+// cachedResults returns the results of the last run of the checks, or runs them if those are older than
+// the cache TTL. Probes arriving during a run wait for it and share its results.
+func (c *Checker) cachedResults(ctx context.Context) map[string]CheckResult {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+	if c.results != nil && time.Since(c.checkedAt) < c.cacheTTL {
+		return c.results
+	}
+	// The results are shared, so the run is not canceled with the probe that started it.
+	c.results, c.checkedAt = c.runChecks(context.WithoutCancel(ctx)), time.Now()
+	return c.results
+}
+
+// runChecks runs every check concurrently and returns their results by name. Failures are logged.
+func (c *Checker) runChecks(ctx context.Context) map[string]CheckResult {
+	c.mu.RLock()
+	names := slices.Clone(c.names)
+	checks := make([]CheckFunc, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	byName := make(map[string]CheckResult, len(names))
+	for i, name := range names {
+		byName[name] = results[i]
+		if results[i].Status != StatusOK {
+			c.logger.Load().Warn(constant.WarningEmoji+"  "+constant.ReadinessCheckFailedContextLog,
+				append([]zap.Field{zap.String("check", name), zap.String("error", results[i].Error)}, logmonitor.ContextFields(ctx)...)...)
+		}
+	}
+	return byName
+}
+
+// run runs one check within the timeout of the checker. A check that does not return in time is
+// reported as failing, and is left to finish in the background.
+func (c *Checker) run(ctx context.Context, check CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", c.timeout)
+		}
+		result.Status, result.Error = StatusFailing, err.Error()
+	}
+	return result
+}
+
+// LivenessHandler returns an HTTP handler that reports the process as alive whenever it can serve a request.
+// It runs no check, so that a dependency outage does not get the process restarted.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler returns an HTTP handler that serves the readiness report, with the status
+// 503 Service Unavailable when it is failing.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Readiness(r.Context()))
+	})
+}
+
+// writeReport writes the report as JSON, with a status code matching its status.
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Register adds a readiness check to the Default checker.
+func Register(name string, check CheckFunc) {
+	Default.Register(name, check)
+}
+
+// SetLogger sets the logger that failed checks of the Default checker are logged with.
+func SetLogger(logger *zap.Logger) {
+	Default.SetLogger(logger)
+}
+
+// Drain makes the readiness of the Default checker fail, as the server is shutting down.
+func Drain() {
+	Default.Drain()
+}
+
+// DrainDelayFromEnv returns how long the server should keep serving after Drain before it shuts down,
+// so that load balancers see readiness fail and stop routing traffic to it first. It reads
+// SHUTDOWN_DRAIN_DELAY, which may be "0s" to shut down right away, and defaults to DefaultDrainDelay.
+func DrainDelayFromEnv() (time.Duration, error) {
+	value := os.Getenv(SHUTDOWN_DRAIN_DELAY)
+	if value == "" {
+		return DefaultDrainDelay, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf(ErrMsgDrainDelay, SHUTDOWN_DRAIN_DELAY, value)
+	}
+	return d, nil
+}
+
+// LivenessHandler returns the liveness handler of the Default checker.
+func LivenessHandler() http.Handler {
+	return Default.LivenessHandler()
+}
+
+// ReadinessHandler returns the readiness handler of the Default checker.
+func ReadinessHandler() http.Handler {
+	return Default.ReadinessHandler()
+}
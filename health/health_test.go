@@ -0,0 +1,107 @@
+// Gopher Unit Testing was here
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serveReport serves one request to handler and decodes the report it returns.
+func serveReport(t *testing.T, handler http.Handler) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PathReadyz, nil))
+	if strings.Contains(rec.Body.String(), "error") {
+		t.Errorf("body %q exposes the error of a check", rec.Body.String())
+	}
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, report
+}
+
+// TestReadiness checks that readiness reports each check, fails when one of them fails or times out,
+// and fails once the checker is draining, while liveness keeps passing.
+func TestReadiness(t *testing.T) {
+	checker := NewChecker(50*time.Millisecond, 0)
+	var storeErr error
+	checker.Register(CheckDatastore, func(ctx context.Context) error { return storeErr })
+
+	if code, report := serveReport(t, checker.ReadinessHandler()); code != http.StatusOK || report.Status != StatusOK || len(report.Checks) != 2 {
+		t.Errorf("readiness = %d %+v, want 200 with the datastore and shutdown checks passing", code, report)
+	}
+
+	storeErr = errors.New("unavailable")
+	code, report := serveReport(t, checker.ReadinessHandler())
+	if code != http.StatusServiceUnavailable || report.Checks[CheckDatastore].Status != StatusFailing || report.Checks[CheckShutdown].Status != StatusOK {
+		t.Errorf("readiness = %d %+v, want 503 with only the datastore check failing", code, report)
+	}
+
+	storeErr = nil
+	checker.Register(CheckJWKS, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if _, report := serveReport(t, checker.ReadinessHandler()); report.Checks[CheckJWKS].Status != StatusFailing {
+		t.Errorf("readiness = %+v, want the check that does not return in time failing", report)
+	}
+
+	checker.Drain()
+	if code, report := serveReport(t, checker.ReadinessHandler()); code != http.StatusServiceUnavailable || report.Checks[CheckShutdown].Status != StatusFailing {
+		t.Errorf("readiness = %d %+v, want 503 while draining", code, report)
+	}
+	if code, report := serveReport(t, checker.LivenessHandler()); code != http.StatusOK || report.Status != StatusOK {
+		t.Errorf("liveness = %d %+v, want 200 while draining", code, report)
+	}
+}
+
+// TestReadinessCache checks that the results of the checks are reused within the cache TTL, and that
+// draining takes effect without waiting for the cache to expire.
+func TestReadinessCache(t *testing.T) {
+	checker := NewChecker(50*time.Millisecond, time.Hour)
+	var runs atomic.Int32
+	checker.Register(CheckDatastore, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	for range 3 {
+		if code, _ := serveReport(t, checker.ReadinessHandler()); code != http.StatusOK {
+			t.Errorf("readiness = %d, want 200", code)
+		}
+	}
+	if got := runs.Load(); got != 1 {
+		t.Errorf("the check ran %d times, want 1", got)
+	}
+
+	checker.Drain()
+	if code, _ := serveReport(t, checker.ReadinessHandler()); code != http.StatusServiceUnavailable {
+		t.Errorf("readiness = %d, want 503 as soon as the checker is draining", code)
+	}
+}
+
+// TestDrainDelayFromEnv checks the default, a valid value and the rejected values of SHUTDOWN_DRAIN_DELAY.
+func TestDrainDelayFromEnv(t *testing.T) {
+	t.Setenv(SHUTDOWN_DRAIN_DELAY, "")
+	if d, err := DrainDelayFromEnv(); err != nil || d != DefaultDrainDelay {
+		t.Errorf("DrainDelayFromEnv() without %s = %v, %v, want %v", SHUTDOWN_DRAIN_DELAY, d, err, DefaultDrainDelay)
+	}
+	t.Setenv(SHUTDOWN_DRAIN_DELAY, "0s")
+	if d, err := DrainDelayFromEnv(); err != nil || d != 0 {
+		t.Errorf("DrainDelayFromEnv() with 0s = %v, %v, want 0", d, err)
+	}
+	for _, value := range []string{"soon", "-1s"} {
+		t.Setenv(SHUTDOWN_DRAIN_DELAY, value)
+		if _, err := DrainDelayFromEnv(); err == nil {
+			t.Errorf("DrainDelayFromEnv() with %q should return an error", value)
+		}
+	}
+}
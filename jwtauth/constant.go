@@ -0,0 +1,46 @@
+package jwtauth
+
+import "time"
+
+// Define environment variables used to configure bearer token authentication.
+//
+// Note: Bearer token authentication is enabled only when JWT_JWKS_URL or JWT_JWKS_FILE is set.
+const (
+	JWT_JWKS_URL       = "JWT_JWKS_URL"
+	JWT_JWKS_FILE      = "JWT_JWKS_FILE"
+	JWT_ISSUER         = "JWT_ISSUER"
+	JWT_AUDIENCE       = "JWT_AUDIENCE"
+	JWT_PRINCIPAL      = "JWT_PRINCIPAL_CLAIM"
+	JWT_SCOPE_CLAIM    = "JWT_SCOPE_CLAIM"
+	JWT_SCOPE_MAPPING  = "JWT_SCOPE_MAPPING"
+	JWT_JWKS_REFRESH   = "JWT_JWKS_REFRESH"
+	JWT_CLOCK_LEEWAY   = "JWT_CLOCK_LEEWAY"
+	defaultScopeClaim  = "scope"
+	defaultPrincipal   = "sub"
+	defaultJWKSRefresh = 15 * time.Minute
+	defaultLeeway      = 30 * time.Second
+)
+
+// Define error messages for token verification.
+const (
+	ErrMsgNoJWKSSource       = "jwtauth: either a JWKS URL or a JWKS file must be configured"
+	ErrMsgUnknownKeyID       = "jwtauth: unknown key ID %q"
+	ErrMsgUnsupportedKeyType = "jwtauth: unsupported key type %q"
+	ErrMsgInvalidKey         = "jwtauth: invalid %s key %q: %v"
+	ErrMsgFetchJWKS          = "jwtauth: failed to fetch JWKS: %w"
+	ErrMsgUnexpectedStatus   = "jwtauth: unexpected JWKS status %d"
+	ErrMsgMissingPrincipal   = "jwtauth: token has no %q claim"
+	ErrMsgInvalidMapping     = "jwtauth: invalid scope mapping %q"
+	ErrMsgNoKeys             = "jwtauth: no signing keys are loaded"
+	ErrMsgNoUsableKeys       = "jwtauth: JWKS has no usable signing key: %w"
+)
+
+// Define JSON Web Key types and the supported signing algorithms.
+const (
+	keyTypeRSA = "RSA"
+	keyTypeEC  = "EC"
+)
+
+// supportedAlgorithms lists the asymmetric algorithms accepted in token headers.
+// Symmetric algorithms are rejected so that a public key can never be used as an HMAC secret.
+var supportedAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
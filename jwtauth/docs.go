@@ -0,0 +1,38 @@
+// Package jwtauth verifies JWT bearer tokens issued by an OpenID Connect or OAuth 2.0 identity provider.
+// It validates the token signature against a JSON Web Key Set (JWKS) loaded from a URL or a file,
+// checks the issuer, audience and expiry, and maps the token claims to the scopes used by the
+// management API of the URL shortener service.
+//
+// # Types
+//
+//   - Config: Holds the JWKS source, the expected issuer and audience, and the claim mapping.
+//   - Verifier: Validates tokens and caches the JWK Set, refreshing a remote set periodically
+//     and whenever a token is signed with an unknown key ID.
+//   - Identity: The verified principal name and scopes carried by a token.
+//
+// # Functions
+//
+//   - NewConfigFromEnv: Builds a Config from the JWT_* environment variables, or returns nil if
+//     bearer token authentication is not configured.
+//   - ParseScopeMapping: Parses a "claimValue=scope1,scope2;otherValue=scope3" mapping.
+//   - NewVerifier: Creates a Verifier and loads the initial JWK Set.
+//   - Verifier.Check: Reports whether signing keys are loaded, for readiness probes.
+//
+// Only asymmetric signing algorithms (RSA, RSA-PSS and ECDSA) are accepted, so a public key
+// can never be abused as an HMAC secret. Keys of other types or curves in the JWK Set (for example
+// OKP or secp256k1) are ignored; the set is rejected only if none of its keys is usable.
+//
+// # Example Usage
+//
+//	config, err := jwtauth.NewConfigFromEnv()
+//	if err != nil || config == nil {
+//	    // Handle the error, or leave bearer token authentication disabled.
+//	}
+//	verifier, err := jwtauth.NewVerifier(ctx, config)
+//	if err != nil {
+//	    // Handle error
+//	}
+//	identity, err := verifier.Verify(ctx, bearerToken)
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package jwtauth
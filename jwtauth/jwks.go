@@ -0,0 +1,154 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+)
+
+// jsonWebKey is the subset of RFC 7517 fields needed to build RSA and EC public keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jsonWebKeySet is an RFC 7517 JWK Set.
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// loadJWKS reads a JWK Set from a URL or, if the URL is empty, from a file.
+// It returns the public signing keys indexed by key ID.
+func loadJWKS(ctx context.Context, httpClient *http.Client, url string, file string) (map[string]crypto.PublicKey, error) {
+	var data []byte
+	var err error
+	if url != "" {
+		data, err = fetchJWKS(ctx, httpClient, url)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+// fetchJWKS downloads a JWK Set document.
+func fetchJWKS(ctx context.Context, httpClient *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf(ErrMsgFetchJWKS, err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(ErrMsgFetchJWKS, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(ErrMsgUnexpectedStatus, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// parseJWKS decodes a JWK Set into public keys indexed by key ID.
+// Keys that are explicitly marked for encryption are skipped, and so are keys of a type or curve
+// this package cannot verify with, as identity providers often publish those alongside the signing keys.
+// It fails only when the set has keys but none of them is usable.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	var rejected error
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			rejected = firstError(rejected, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 && rejected != nil {
+		return nil, fmt.Errorf(ErrMsgNoUsableKeys, rejected)
+	}
+	return keys, nil
+}
+
+// publicKey builds the public key described by the JWK.
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case keyTypeRSA:
+		return k.rsaPublicKey()
+	case keyTypeEC:
+		return k.ecPublicKey()
+	}
+	return nil, fmt.Errorf(ErrMsgUnsupportedKeyType, k.Kty)
+}
+
+// rsaPublicKey builds an RSA public key from the modulus and exponent.
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, errN := decodeBigInt(k.N)
+	e, errE := decodeBigInt(k.E)
+	if errN != nil || errE != nil || !e.IsInt64() {
+		return nil, fmt.Errorf(ErrMsgInvalidKey, keyTypeRSA, k.Kid, firstError(errN, errE))
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+// ecPublicKey builds an ECDSA public key from the curve name and coordinates.
+func (k jsonWebKey) ecPublicKey() (*ecdsa.PublicKey, error) {
+	curve, ok := map[string]elliptic.Curve{
+		"P-256": elliptic.P256(),
+		"P-384": elliptic.P384(),
+		"P-521": elliptic.P521(),
+	}[k.Crv]
+	if !ok {
+		return nil, fmt.Errorf(ErrMsgUnsupportedKeyType, k.Kty+"/"+k.Crv)
+	}
+	x, errX := decodeBigInt(k.X)
+	y, errY := decodeBigInt(k.Y)
+	if errX != nil || errY != nil {
+		return nil, fmt.Errorf(ErrMsgInvalidKey, keyTypeEC, k.Kid, firstError(errX, errY))
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// decodeBigInt decodes an unpadded base64url big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// firstError returns the first non-nil error.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config holds the settings used to verify bearer tokens issued by an identity provider.
+type Config struct {
+	JWKSURL         string              // The URL of the JWK Set, refreshed periodically. Takes precedence over JWKSFile.
+	JWKSFile        string              // The path of a JWK Set file, read once at startup.
+	Issuer          string              // The expected "iss" claim; not checked if empty.
+	Audience        string              // The expected "aud" claim; not checked if empty.
+	PrincipalClaim  string              // The claim used as the principal name, "sub" by default.
+	ScopeClaim      string              // The claim holding the caller's permissions, "scope" by default.
+	ScopeMapping    map[string][]string // Maps claim values to scopes; if empty, claim values are used as scopes directly.
+	RefreshInterval time.Duration       // How often a JWKS URL is re-fetched.
+	Leeway          time.Duration       // The clock skew tolerated when checking "exp", "nbf" and "iat".
+	HTTPClient      *http.Client        // The client used to fetch the JWKS URL.
+}
+
+// Identity is the verified identity carried by a bearer token.
+type Identity struct {
+	Issuer  string
+	Subject string
+	Scopes  []string
+}
+
+// Verifier validates bearer tokens against a JWK Set and the configured issuer and audience.
+// It is safe for concurrent use by multiple goroutines.
+type Verifier struct {
+	config    *Config
+	parser    *jwt.Parser
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewConfigFromEnv builds a Config from the JWT_* environment variables.
+// It returns nil without an error if neither JWT_JWKS_URL nor JWT_JWKS_FILE is set,
+// which means bearer token authentication is disabled.
+func NewConfigFromEnv() (*Config, error) {
+	config := &Config{
+		JWKSURL:         os.Getenv(JWT_JWKS_URL),
+		JWKSFile:        os.Getenv(JWT_JWKS_FILE),
+		Issuer:          os.Getenv(JWT_ISSUER),
+		Audience:        os.Getenv(JWT_AUDIENCE),
+		PrincipalClaim:  envOrDefault(JWT_PRINCIPAL, defaultPrincipal),
+		ScopeClaim:      envOrDefault(JWT_SCOPE_CLAIM, defaultScopeClaim),
+		RefreshInterval: defaultJWKSRefresh,
+		Leeway:          defaultLeeway,
+	}
+	if config.JWKSURL == "" && config.JWKSFile == "" {
+		return nil, nil
+	}
+
+	mapping, err := ParseScopeMapping(os.Getenv(JWT_SCOPE_MAPPING))
+	if err != nil {
+		return nil, err
+	}
+	config.ScopeMapping = mapping
+
+	if err := parseEnvDuration(JWT_JWKS_REFRESH, &config.RefreshInterval); err != nil {
+		return nil, err
+	}
+	return config, parseEnvDuration(JWT_CLOCK_LEEWAY, &config.Leeway)
+}
+
+// ParseScopeMapping parses a mapping of the form "claimValue=scope1,scope2;otherValue=scope3".
+func ParseScopeMapping(s string) (map[string][]string, error) {
+	mapping := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		value, scopes, ok := strings.Cut(entry, "=")
+		if !ok || value == "" || scopes == "" {
+			return nil, fmt.Errorf(ErrMsgInvalidMapping, entry)
+		}
+		mapping[value] = strings.Split(scopes, ",")
+	}
+	return mapping, nil
+}
+
+// NewVerifier creates a Verifier and loads the initial JWK Set.
+func NewVerifier(ctx context.Context, config *Config) (*Verifier, error) {
+	if config.JWKSURL == "" && config.JWKSFile == "" {
+		return nil, fmt.Errorf(ErrMsgNoJWKSSource)
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(supportedAlgorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	v := &Verifier{config: config, parser: jwt.NewParser(options...)}
+	if err := v.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Verify checks the token's signature, issuer, audience and expiry, and returns the identity it carries.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc(ctx)); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims[v.config.PrincipalClaim].(string)
+	if subject == "" {
+		return nil, fmt.Errorf(ErrMsgMissingPrincipal, v.config.PrincipalClaim)
+	}
+	issuer, _ := claims["iss"].(string)
+	return &Identity{Issuer: issuer, Subject: subject, Scopes: v.mapScopes(claimValues(claims[v.config.ScopeClaim]))}, nil
+}
+
+// keyFunc returns a jwt.Keyfunc that resolves the signing key by key ID,
+// refreshing the JWK Set when it is stale or the key ID is unknown.
+func (v *Verifier) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := v.lookup(kid)
+		if !ok || v.stale() {
+			// A failed refresh is only fatal if the key is not already known.
+			if err := v.refreshIfRemote(ctx); err != nil && !ok {
+				return nil, err
+			}
+			key, ok = v.lookup(kid)
+		}
+		if !ok {
+			return nil, fmt.Errorf(ErrMsgUnknownKeyID, kid)
+		}
+		return key, nil
+	}
+}
+
+// lookup returns the public key with the given ID.
+func (v *Verifier) lookup(kid string) (crypto.PublicKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+// stale reports whether a remote JWK Set is due for a refresh.
+func (v *Verifier) stale() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.config.JWKSURL != "" && time.Since(v.fetchedAt) > v.config.RefreshInterval
+}
+
+// refreshIfRemote re-fetches the JWK Set from its URL, at most once every few seconds.
+// File-based key sets are static and are never refreshed.
+func (v *Verifier) refreshIfRemote(ctx context.Context) error {
+	v.mu.RLock()
+	recent := time.Since(v.fetchedAt) < 5*time.Second
+	v.mu.RUnlock()
+	if v.config.JWKSURL == "" || recent {
+		return nil
+	}
+	return v.refresh(ctx)
+}
+
+// refresh loads the JWK Set and replaces the cached keys.
+func (v *Verifier) refresh(ctx context.Context) error {
+	keys, err := loadJWKS(ctx, v.config.HTTPClient, v.config.JWKSURL, v.config.JWKSFile)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	return nil
+}
+
+// Check reports whether signing keys are loaded, as no bearer token can be verified otherwise.
+// It suits readiness probes: a failed refresh of a remote JWK Set keeps the previous keys, so it does not fail.
+func (v *Verifier) Check(ctx context.Context) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if len(v.keys) == 0 {
+		return errors.New(ErrMsgNoKeys)
+	}
+	return nil
+}
+
+// mapScopes translates claim values into scopes using the configured mapping.
+func (v *Verifier) mapScopes(values []string) []string {
+	if len(v.config.ScopeMapping) == 0 {
+		return values
+	}
+	var scopes []string
+	for _, value := range values {
+		scopes = append(scopes, v.config.ScopeMapping[value]...)
+	}
+	return scopes
+}
+
+// claimValues normalizes a claim that is either a space-delimited string (as in OAuth 2.0 "scope")
+// or an array of strings (as in "scp", "roles" or "groups").
+func claimValues(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []interface{}:
+		values := make([]string, 0, len(c))
+		for _, item := range c {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// envOrDefault returns the value of the environment variable, or the fallback if it is unset.
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// parseEnvDuration parses the environment variable as a duration into dst if it is set.
+func parseEnvDuration(name string, dst *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	*dst = d
+	return nil
+}
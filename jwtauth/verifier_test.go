@@ -0,0 +1,202 @@
+// Gopher Unit Testing was here
+package jwtauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testKeyID    = "test-key"
+	testIssuer   = "https://idp.example.internal"
+	testAudience = "go-urlshortner"
+)
+
+// newTestJWKSServer starts a local JWKS server that serves the public half of key.
+func newTestJWKSServer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	set := jsonWebKeySet{Keys: []jsonWebKey{{
+		Kty: keyTypeRSA,
+		Kid: testKeyID,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// signTestToken signs the claims with key, using the given key ID.
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString returned an unexpected error: %v", err)
+	}
+	return signed
+}
+
+// newTestVerifier creates a Verifier backed by a local JWKS server.
+func newTestVerifier(t *testing.T, key *rsa.PrivateKey, mapping map[string][]string) *Verifier {
+	t.Helper()
+	server := newTestJWKSServer(t, key)
+	verifier, err := NewVerifier(context.Background(), &Config{
+		JWKSURL:         server.URL,
+		Issuer:          testIssuer,
+		Audience:        testAudience,
+		PrincipalClaim:  defaultPrincipal,
+		ScopeClaim:      defaultScopeClaim,
+		ScopeMapping:    mapping,
+		RefreshInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier returned an unexpected error: %v", err)
+	}
+	return verifier
+}
+
+// validClaims returns claims that satisfy the test verifier.
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "deploy-bot",
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "create edit",
+	}
+}
+
+// TestVerify checks that a valid token is accepted and its claims are mapped to an identity.
+func TestVerify(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	verifier := newTestVerifier(t, key, nil)
+
+	identity, err := verifier.Verify(context.Background(), signTestToken(t, key, testKeyID, validClaims()))
+	if err != nil {
+		t.Fatalf("Verify returned an unexpected error: %v", err)
+	}
+	if identity.Subject != "deploy-bot" || identity.Issuer != testIssuer {
+		t.Errorf("Verify returned subject %q from %q, want %q from %q", identity.Subject, identity.Issuer, "deploy-bot", testIssuer)
+	}
+	if len(identity.Scopes) != 2 || identity.Scopes[0] != "create" || identity.Scopes[1] != "edit" {
+		t.Errorf("Verify returned scopes %v, want [create edit]", identity.Scopes)
+	}
+}
+
+// TestVerify_ScopeMapping checks that claim values are translated through the configured mapping.
+func TestVerify_ScopeMapping(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	verifier := newTestVerifier(t, key, map[string][]string{"links.admin": {"admin"}})
+
+	claims := validClaims()
+	claims["scope"] = []interface{}{"links.admin", "unmapped"}
+	identity, err := verifier.Verify(context.Background(), signTestToken(t, key, testKeyID, claims))
+	if err != nil {
+		t.Fatalf("Verify returned an unexpected error: %v", err)
+	}
+	if len(identity.Scopes) != 1 || identity.Scopes[0] != "admin" {
+		t.Errorf("Verify returned scopes %v, want [admin]", identity.Scopes)
+	}
+}
+
+// TestVerify_Rejects checks that tokens failing any of the checks are rejected.
+func TestVerify_Rejects(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	verifier := newTestVerifier(t, key, nil)
+
+	testCases := map[string]func() string{
+		"expired": func() string {
+			claims := validClaims()
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			return signTestToken(t, key, testKeyID, claims)
+		},
+		"wrong issuer": func() string {
+			claims := validClaims()
+			claims["iss"] = "https://evil.example"
+			return signTestToken(t, key, testKeyID, claims)
+		},
+		"wrong audience": func() string {
+			claims := validClaims()
+			claims["aud"] = "someone-else"
+			return signTestToken(t, key, testKeyID, claims)
+		},
+		"wrong signature": func() string {
+			return signTestToken(t, otherKey, testKeyID, validClaims())
+		},
+		"unknown key ID": func() string {
+			return signTestToken(t, key, "rotated-away", validClaims())
+		},
+		"symmetric algorithm": func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+			token.Header["kid"] = testKeyID
+			signed, _ := token.SignedString([]byte("secret"))
+			return signed
+		},
+	}
+
+	for name, tokenFn := range testCases {
+		if _, err := verifier.Verify(context.Background(), tokenFn()); err == nil {
+			t.Errorf("Verify accepted a token with %s", name)
+		}
+	}
+}
+
+// TestParseScopeMapping checks the parsing of the JWT_SCOPE_MAPPING format.
+func TestParseScopeMapping(t *testing.T) {
+	mapping, err := ParseScopeMapping("links.write=create,edit; links.admin=admin")
+	if err != nil {
+		t.Fatalf("ParseScopeMapping returned an unexpected error: %v", err)
+	}
+	if got := mapping["links.write"]; len(got) != 2 || got[1] != "edit" {
+		t.Errorf("ParseScopeMapping returned %v for links.write, want [create edit]", got)
+	}
+	if _, err := ParseScopeMapping("missing-equals"); err == nil {
+		t.Error("ParseScopeMapping should return an error for an entry without '='")
+	}
+}
+
+// TestParseJWKS_SkipsUnsupportedKeys checks that keys this package cannot use do not reject the whole set.
+func TestParseJWKS_SkipsUnsupportedKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned an unexpected error: %v", err)
+	}
+	rsaKey := jsonWebKey{
+		Kty: keyTypeRSA,
+		Kid: testKeyID,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+	okpKey := jsonWebKey{Kty: "OKP", Kid: "ed25519", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	secp256k1Key := jsonWebKey{Kty: keyTypeEC, Kid: "secp256k1", Crv: "secp256k1"}
+
+	data, _ := json.Marshal(jsonWebKeySet{Keys: []jsonWebKey{okpKey, rsaKey, secp256k1Key}})
+	keys, err := parseJWKS(data)
+	if err != nil {
+		t.Fatalf("parseJWKS returned an unexpected error: %v", err)
+	}
+	if len(keys) != 1 || keys[testKeyID] == nil {
+		t.Errorf("parseJWKS returned keys %v, want only %q", keys, testKeyID)
+	}
+
+	data, _ = json.Marshal(jsonWebKeySet{Keys: []jsonWebKey{okpKey, secp256k1Key}})
+	if _, err := parseJWKS(data); err == nil {
+		t.Error("parseJWKS should return an error when no key in the set is usable")
+	}
+}
@@ -0,0 +1,202 @@
+package logmonitor
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessLogConfig holds the configuration of the request log written by RequestLoggerWithConfig.
+type AccessLogConfig struct {
+	Format        string           // AccessLogJSON for structured fields, or AccessLogCombined for Apache combined lines.
+	Fields        []string         // The optional AccessLogField* fields written with each request.
+	Headers       []string         // The request headers written with each request, under 'headers'.
+	RedactPaths   []*regexp.Regexp // Parts of the path that are replaced with RedactedValue.
+	RedactHeaders []string         // Headers redacted in addition to AlwaysRedactedHeaders.
+}
+
+// DefaultAccessLogConfig returns the configuration used by RequestLogger: structured fields, with the
+// principal and the request ID of each request.
+func DefaultAccessLogConfig() *AccessLogConfig {
+	return &AccessLogConfig{
+		Format: AccessLogJSON,
+		Fields: []string{AccessLogFieldPrincipal, AccessLogFieldRequestID},
+	}
+}
+
+// NewAccessLogConfigFromEnv builds an AccessLogConfig from the ACCESS_LOG_* environment variables,
+// starting from DefaultAccessLogConfig.
+func NewAccessLogConfigFromEnv() (*AccessLogConfig, error) {
+	config := DefaultAccessLogConfig()
+	if value := os.Getenv(ACCESS_LOG_FORMAT); value != "" {
+		if value != AccessLogJSON && value != AccessLogCombined {
+			return nil, fmt.Errorf(ErrMsgUnknownAccessLogFormat, ACCESS_LOG_FORMAT, value)
+		}
+		config.Format = value
+	}
+	if value, ok := os.LookupEnv(ACCESS_LOG_FIELDS); ok {
+		config.Fields = splitList(value)
+		for _, field := range config.Fields {
+			if !slices.Contains(AccessLogFields, field) {
+				return nil, fmt.Errorf(ErrMsgUnknownAccessLogField, ACCESS_LOG_FIELDS, field)
+			}
+		}
+	}
+	config.Headers = splitList(os.Getenv(ACCESS_LOG_HEADERS))
+	config.RedactHeaders = splitList(os.Getenv(ACCESS_LOG_REDACT_HEADERS))
+	for _, pattern := range splitList(os.Getenv(ACCESS_LOG_REDACT_PATHS)) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf(ErrMsgInvalidRedactPath, ACCESS_LOG_REDACT_PATHS, pattern, err)
+		}
+		config.RedactPaths = append(config.RedactPaths, re)
+	}
+	return config, nil
+}
+
+// splitList splits a comma-separated list, ignoring spaces and empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// accessLogger writes the request log of one middleware.
+type accessLogger struct {
+	logger  *zap.Logger
+	config  *AccessLogConfig
+	fields  map[string]bool
+	redact  map[string]bool // The canonical names of the redacted headers.
+	headers []string        // The canonical names of the logged headers.
+}
+
+// newAccessLogger prepares the configuration for use on every request.
+func newAccessLogger(logger *zap.Logger, config *AccessLogConfig) *accessLogger {
+	a := &accessLogger{
+		logger: logger,
+		config: config,
+		fields: make(map[string]bool, len(config.Fields)),
+		redact: make(map[string]bool),
+	}
+	for _, field := range config.Fields {
+		a.fields[field] = true
+	}
+	for _, name := range append(slices.Clone(AlwaysRedactedHeaders), config.RedactHeaders...) {
+		a.redact[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range config.Headers {
+		a.headers = append(a.headers, http.CanonicalHeaderKey(name))
+	}
+	return a
+}
+
+// redactPath replaces the parts of the path matched by the redaction rules.
+func (a *accessLogger) redactPath(path string) string {
+	for _, re := range a.config.RedactPaths {
+		path = re.ReplaceAllString(path, RedactedValue)
+	}
+	return path
+}
+
+// requestFields returns the fields of the request log entry, in the order the original middleware wrote them.
+func (a *accessLogger) requestFields(c *gin.Context, start time.Time, duration time.Duration) []zap.Field {
+	fields := []zap.Field{
+		zap.String("hostmachine_start_time", start.Format(constant.TimestampFormat)),
+		zap.Int("status", c.Writer.Status()),
+		zap.String("method", c.Request.Method),
+		zap.String("path", a.redactPath(c.Request.URL.Path)),
+		zap.Duration("duration", duration),
+	}
+	if a.fields[AccessLogFieldRoute] {
+		fields = append(fields, zap.String(AccessLogFieldRoute, c.FullPath()))
+	}
+	if id := c.Param(constant.HeaderID); a.fields[AccessLogFieldID] && id != "" {
+		fields = append(fields, WithID(id)())
+	}
+	if a.fields[AccessLogFieldClientIP] {
+		fields = append(fields, zap.String(AccessLogFieldClientIP, c.ClientIP()))
+	}
+	if a.fields[AccessLogFieldUserAgent] {
+		fields = append(fields, zap.String(AccessLogFieldUserAgent, c.Request.UserAgent()))
+	}
+	if a.fields[AccessLogFieldReferer] {
+		fields = append(fields, zap.String(AccessLogFieldReferer, c.Request.Referer()))
+	}
+	if a.fields[AccessLogFieldResponseSize] {
+		fields = append(fields, zap.Int(AccessLogFieldResponseSize, responseSize(c)))
+	}
+	if principal := c.GetString(constant.GinContextPrincipalName); a.fields[AccessLogFieldPrincipal] && principal != "" {
+		fields = append(fields, WithPrincipal(principal)())
+	}
+	if len(a.headers) > 0 {
+		fields = append(fields, zap.Object("headers", a.requestHeaders(c.Request.Header)))
+	}
+	return append(fields, a.correlationFields(c)...)
+}
+
+// correlationFields returns the request ID, if enabled, and the trace of the request.
+func (a *accessLogger) correlationFields(c *gin.Context) []zap.Field {
+	var fields []zap.Field
+	if id := RequestIDFromContext(c.Request.Context()); a.fields[AccessLogFieldRequestID] && id != "" {
+		fields = append(fields, WithRequestID(id)())
+	}
+	return append(fields, traceFields(c.Request.Context())...)
+}
+
+// requestHeaders returns the logged headers of the request, with the sensitive ones redacted.
+func (a *accessLogger) requestHeaders(header http.Header) zapcore.ObjectMarshaler {
+	return zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		for _, name := range a.headers {
+			value := header.Get(name)
+			if value == "" {
+				continue
+			}
+			if a.redact[name] {
+				value = RedactedValue
+			}
+			enc.AddString(name, value)
+		}
+		return nil
+	})
+}
+
+// combinedLine formats the request in the Apache combined log format. The query string is left out,
+// as it may carry secrets, and the user is the authenticated principal.
+func (a *accessLogger) combinedLine(c *gin.Context, start time.Time) string {
+	user := c.GetString(constant.GinContextPrincipalName)
+	if user == "" {
+		user = "-"
+	}
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %d %q %q`,
+		c.ClientIP(), user, start.Format(combinedTimeFormat),
+		c.Request.Method, a.redactPath(c.Request.URL.Path), c.Request.Proto,
+		c.Writer.Status(), responseSize(c),
+		orDash(c.Request.Referer()), orDash(c.Request.UserAgent()),
+	)
+}
+
+// responseSize returns the number of bytes of the response body.
+func responseSize(c *gin.Context) int {
+	return max(c.Writer.Size(), 0)
+}
+
+// orDash returns value, or "-" if it is empty, as in the Apache log formats.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,79 @@
+// Gopher Unit Testing was here
+package logmonitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// serveLogged serves one request through RequestLoggerWithConfig and returns the request log entry.
+func serveLogged(t *testing.T, config *AccessLogConfig, req *http.Request) observer.LoggedEntry {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+	router := gin.New()
+	router.Use(RequestID(), RequestLoggerWithConfig(zap.New(core), config))
+	router.GET("/internal/apikeys/:id", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if logs.Len() != 1 {
+		t.Fatalf("logged %d entries, want 1", logs.Len())
+	}
+	return logs.All()[0]
+}
+
+// TestRequestLoggerWithConfig checks the optional fields and that secrets are redacted from the path and headers.
+func TestRequestLoggerWithConfig(t *testing.T) {
+	config := &AccessLogConfig{
+		Format:      AccessLogJSON,
+		Fields:      []string{AccessLogFieldRoute, AccessLogFieldID, AccessLogFieldUserAgent, AccessLogFieldResponseSize, AccessLogFieldRequestID},
+		Headers:     []string{"x-internal-secret", "Accept"},
+		RedactPaths: []*regexp.Regexp{regexp.MustCompile(`apikeys/[^/]+`)},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/internal/apikeys/ci.s3cr3t?token=abc", nil)
+	req.Header.Set("X-Internal-Secret", "hunter2")
+	req.Header.Set("Accept", "text/plain")
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set("X-Request-ID", "req-1")
+
+	fields := serveLogged(t, config, req).ContextMap()
+	want := map[string]any{
+		"path":          "/internal/[REDACTED]",
+		"route":         "/internal/apikeys/:id",
+		"id":            "ci.s3cr3t",
+		"user_agent":    "curl/8.0",
+		"response_size": int64(5),
+		"request_id":    "req-1",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v, want %v", key, fields[key], value)
+		}
+	}
+	headers, _ := fields["headers"].(map[string]any)
+	if headers["X-Internal-Secret"] != RedactedValue || headers["Accept"] != "text/plain" {
+		t.Errorf("headers = %v, want the secret redacted and Accept kept", headers)
+	}
+	if _, ok := fields["client_ip"]; ok {
+		t.Errorf("client_ip is logged, want only the configured fields")
+	}
+}
+
+// TestRequestLoggerCombined checks the Apache combined format, which leaves the query string out.
+func TestRequestLoggerCombined(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/internal/apikeys/abc?token=abc", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	entry := serveLogged(t, &AccessLogConfig{Format: AccessLogCombined}, req)
+	if !strings.HasPrefix(entry.Message, "192.0.2.1 - - [") || !strings.HasSuffix(entry.Message, `] "GET /internal/apikeys/abc HTTP/1.1" 200 5 "-" "curl/8.0"`) {
+		t.Errorf("message = %q, want an Apache combined line without the query string", entry.Message)
+	}
+}
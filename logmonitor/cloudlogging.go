@@ -0,0 +1,192 @@
+package logmonitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// cloudLoggingSeverities maps the levels of zap to the severities of Google Cloud Logging.
+var cloudLoggingSeverities = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "DEBUG",
+	zapcore.InfoLevel:   "INFO",
+	zapcore.WarnLevel:   "WARNING",
+	zapcore.ErrorLevel:  "ERROR",
+	zapcore.DPanicLevel: "CRITICAL",
+	zapcore.PanicLevel:  "ALERT",
+	zapcore.FatalLevel:  "EMERGENCY",
+}
+
+// cloudLoggingEncoderConfig returns an encoder configuration that writes the level, message and time
+// of each entry under the names Google Cloud Logging reads them from.
+func cloudLoggingEncoderConfig() zapcore.EncoderConfig {
+	config := zap.NewProductionEncoderConfig()
+	config.LevelKey = cloudLoggingSeverityKey
+	config.MessageKey = cloudLoggingMessageKey
+	config.TimeKey = cloudLoggingTimeKey
+	config.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	config.EncodeLevel = func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(cloudLoggingSeverities[level])
+	}
+	return config
+}
+
+// cloudLoggingCore rewrites the fields of the entries into the special fields of Google Cloud Logging,
+// so that entries are grouped by request and linked to their trace in the console:
+//   - 'trace_id' and 'span_id' become 'logging.googleapis.com/trace' and 'logging.googleapis.com/spanId'.
+//   - The 'method', 'path', 'status' and 'duration' fields of the request log become an 'httpRequest' object,
+//     together with its optional 'client_ip', 'user_agent', 'referer' and 'response_size' fields.
+type cloudLoggingCore struct {
+	zapcore.Core
+	projectID string
+}
+
+// With adds structured context to the core, rewriting the fields like Write does.
+func (c *cloudLoggingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &cloudLoggingCore{Core: c.Core.With(c.rewrite(fields)), projectID: c.projectID}
+}
+
+// Check adds the core to the checked entry if the level of the entry is enabled.
+func (c *cloudLoggingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write rewrites the fields of the entry and writes it to the wrapped core.
+func (c *cloudLoggingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.rewrite(fields))
+}
+
+// rewrite returns the fields with the correlation and request fields renamed. The given slice is not modified.
+func (c *cloudLoggingCore) rewrite(fields []zapcore.Field) []zapcore.Field {
+	isRequestLog := hasFields(fields, "method", "path", "status", "duration")
+	rewritten := make([]zapcore.Field, 0, len(fields))
+	var request httpRequest
+	for _, field := range fields {
+		switch {
+		case field.Key == "trace_id":
+			trace := field.String
+			if c.projectID != "" {
+				trace = fmt.Sprintf(cloudLoggingTraceFormat, c.projectID, trace)
+			}
+			rewritten = append(rewritten, zap.String(cloudLoggingTraceKey, trace))
+		case field.Key == "span_id":
+			rewritten = append(rewritten, zap.String(cloudLoggingSpanIDKey, field.String))
+		case isRequestLog && field.Key == "method":
+			request.method = field.String
+		case isRequestLog && field.Key == "path":
+			request.url = field.String
+		case isRequestLog && field.Key == "status":
+			request.status = field.Integer
+		case isRequestLog && field.Key == "duration":
+			request.latency = time.Duration(field.Integer)
+		case isRequestLog && field.Key == AccessLogFieldClientIP:
+			request.remoteIP = field.String
+		case isRequestLog && field.Key == AccessLogFieldUserAgent:
+			request.userAgent = field.String
+		case isRequestLog && field.Key == AccessLogFieldReferer:
+			request.referer = field.String
+		case isRequestLog && field.Key == AccessLogFieldResponseSize:
+			request.responseSize = field.Integer
+		default:
+			rewritten = append(rewritten, field)
+		}
+	}
+	if isRequestLog {
+		rewritten = append(rewritten, zap.Object(cloudLoggingHTTPRequestKey, request))
+	}
+	return rewritten
+}
+
+// hasFields reports whether fields contains a field with each of the given keys.
+func hasFields(fields []zapcore.Field, keys ...string) bool {
+	for _, key := range keys {
+		found := false
+		for _, field := range fields {
+			if field.Key == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// httpRequest is the 'httpRequest' field of a request log entry in Google Cloud Logging.
+type httpRequest struct {
+	method       string
+	url          string
+	status       int64
+	latency      time.Duration
+	remoteIP     string
+	userAgent    string
+	referer      string
+	responseSize int64
+}
+
+// MarshalLogObject writes the request with the field names of Google Cloud Logging.
+func (r httpRequest) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("requestMethod", r.method)
+	enc.AddString("requestUrl", r.url)
+	enc.AddInt64("status", r.status)
+	enc.AddString("latency", strconv.FormatFloat(r.latency.Seconds(), 'f', -1, 64)+"s")
+	// The optional fields of the request log are only written when they were logged.
+	if r.remoteIP != "" {
+		enc.AddString("remoteIp", r.remoteIP)
+	}
+	if r.userAgent != "" {
+		enc.AddString("userAgent", r.userAgent)
+	}
+	if r.referer != "" {
+		enc.AddString("referer", r.referer)
+	}
+	if r.responseSize > 0 {
+		enc.AddString("responseSize", strconv.FormatInt(r.responseSize, 10))
+	}
+	return nil
+}
+
+// stripEmojiCore removes the emoji prefixes of the messages, for log collectors and terminals that
+// do not render them.
+type stripEmojiCore struct {
+	zapcore.Core
+}
+
+// With adds structured context to the core.
+func (c *stripEmojiCore) With(fields []zapcore.Field) zapcore.Core {
+	return &stripEmojiCore{Core: c.Core.With(fields)}
+}
+
+// Check adds the core to the checked entry if the level of the entry is enabled.
+func (c *stripEmojiCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write removes the emoji prefix of the message and writes the entry to the wrapped core.
+func (c *stripEmojiCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = StripEmoji(entry.Message)
+	return c.Core.Write(entry, fields)
+}
+
+// StripEmoji removes the emojis and spaces the message starts with, such as the "❌  🔗  " prefix
+// used by most messages of this application.
+func StripEmoji(message string) string {
+	return strings.TrimLeftFunc(message, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.Is(unicode.So, r) ||
+			r == '\uFE0F' || // The variation selector that requests the emoji presentation of a character.
+			r == '\u200D' // The zero width joiner that combines emojis.
+	})
+}
@@ -0,0 +1,159 @@
+package logmonitor
+
+import (
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+)
+
+// Define environment variables used to configure the logger.
+//
+// Note: When none of them is set, the logger writes JSON at the info level to the standard error,
+// or colored console output at the debug level when GIN_MODE is debug.
+const (
+	LOG_FORMAT        = "LOG_FORMAT"
+	LOG_LEVEL         = "LOG_LEVEL"
+	LOG_SAMPLING      = "LOG_SAMPLING"
+	LOG_OUTPUT_PATHS  = "LOG_OUTPUT_PATHS"
+	LOG_CLOUD_LOGGING = "LOG_CLOUD_LOGGING"
+	LOG_STRIP_EMOJI   = "LOG_STRIP_EMOJI"
+)
+
+// Define environment variables used to configure the redaction of every log entry.
+//
+// Note: They extend the default rules below, which always apply. LOG_REDACT_PATTERNS is a
+// comma-separated list of regular expressions; LOG_TRUNCATE_IPS is off by default.
+const (
+	LOG_REDACT_FIELDS       = "LOG_REDACT_FIELDS"
+	LOG_REDACT_PATTERNS     = "LOG_REDACT_PATTERNS"
+	LOG_REDACT_QUERY_PARAMS = "LOG_REDACT_QUERY_PARAMS"
+	LOG_TRUNCATE_IPS        = "LOG_TRUNCATE_IPS"
+)
+
+// DefaultRedactedFields are the fields whose values are never written to the logs, whatever their type.
+var DefaultRedactedFields = []string{
+	"password", "passwd", "secret", "client_secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "cookie", "private_key",
+	constant.HeaderXinternalSecret, constant.HeaderXAPIKey,
+}
+
+// DefaultRedactedPatterns match the secrets and personal data redacted from every message and string value:
+// email addresses, bearer credentials and JSON Web Tokens.
+var DefaultRedactedPatterns = []string{
+	`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`,
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+}
+
+// DefaultRedactedQueryParams are the query parameters whose values are redacted wherever a query string
+// is logged, such as in a URL or an error message.
+var DefaultRedactedQueryParams = []string{
+	"token", "access_token", "key", "api_key", "apikey", "password", "secret", "signature", "sig", "code",
+}
+
+// Define environment variables used to configure the request log.
+//
+// Note: ACCESS_LOG_FIELDS replaces the default fields, so an empty value logs none of them. The
+// AlwaysRedactedHeaders are redacted whatever ACCESS_LOG_REDACT_HEADERS holds.
+const (
+	ACCESS_LOG_FORMAT         = "ACCESS_LOG_FORMAT"
+	ACCESS_LOG_FIELDS         = "ACCESS_LOG_FIELDS"
+	ACCESS_LOG_HEADERS        = "ACCESS_LOG_HEADERS"
+	ACCESS_LOG_REDACT_PATHS   = "ACCESS_LOG_REDACT_PATHS"
+	ACCESS_LOG_REDACT_HEADERS = "ACCESS_LOG_REDACT_HEADERS"
+)
+
+// Define the formats of the request log.
+const (
+	// AccessLogJSON writes each request as structured fields.
+	AccessLogJSON = "json"
+	// AccessLogCombined writes each request as a line in the Apache combined log format.
+	AccessLogCombined = "combined"
+	// combinedTimeFormat is the time format of the Apache log formats.
+	combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+// Define the optional fields of the request log.
+const (
+	AccessLogFieldClientIP     = "client_ip"
+	AccessLogFieldUserAgent    = "user_agent"
+	AccessLogFieldReferer      = "referer"
+	AccessLogFieldResponseSize = "response_size"
+	AccessLogFieldRoute        = "route"
+	AccessLogFieldID           = "id"
+	AccessLogFieldPrincipal    = "principal"
+	AccessLogFieldRequestID    = "request_id"
+)
+
+// AccessLogFields lists the optional fields of the request log.
+var AccessLogFields = []string{
+	AccessLogFieldClientIP, AccessLogFieldUserAgent, AccessLogFieldReferer, AccessLogFieldResponseSize,
+	AccessLogFieldRoute, AccessLogFieldID, AccessLogFieldPrincipal, AccessLogFieldRequestID,
+}
+
+// AlwaysRedactedHeaders are the request headers whose values are never written to the request log,
+// as they carry credentials.
+var AlwaysRedactedHeaders = []string{
+	constant.HeaderAuthorization, constant.HeaderXinternalSecret, constant.HeaderXAPIKey,
+	"Cookie", "Proxy-Authorization",
+}
+
+// RedactedValue replaces the redacted parts of the logs.
+const RedactedValue = "[REDACTED]"
+
+// Define the packages whose log level can be changed at runtime with SetLevel.
+const (
+	PackageApp        = "app" // The logger returned by NewLogger, used by the application itself.
+	PackageDatastore  = "datastore"
+	PackageHandlers   = "handlers"
+	PackageLogmonitor = "logmonitor"
+	PackageWorkerK8s  = "workerk8s"
+)
+
+// DefaultLevelDuration is how long a log level changed without an explicit duration is kept before it
+// reverts to the configured level.
+const DefaultLevelDuration = 15 * time.Minute
+
+// Packages lists the packages whose log level can be changed at runtime.
+var Packages = []string{PackageApp, PackageDatastore, PackageHandlers, PackageLogmonitor, PackageWorkerK8s}
+
+// Define the encodings the logger can write.
+const (
+	// FormatJSON writes one JSON object per entry, as expected by log collectors.
+	FormatJSON = "json"
+	// FormatConsole writes human-readable lines, which is handy in development.
+	FormatConsole = "console"
+)
+
+// Define the sampling of the logger. Within each second, the first samplingInitial entries with the same
+// level and message are logged, then only every samplingThereafter-th one.
+const (
+	samplingInitial    = 100
+	samplingThereafter = 100
+)
+
+// Define the field names of Google Cloud Logging structured logs.
+//
+// See https://cloud.google.com/logging/docs/structured-logging for details.
+const (
+	cloudLoggingSeverityKey    = "severity"
+	cloudLoggingMessageKey     = "message"
+	cloudLoggingTimeKey        = "timestamp"
+	cloudLoggingHTTPRequestKey = "httpRequest"
+	cloudLoggingTraceKey       = "logging.googleapis.com/trace"
+	cloudLoggingSpanIDKey      = "logging.googleapis.com/spanId"
+	cloudLoggingTraceFormat    = "projects/%s/traces/%s"
+)
+
+// Define error messages for the logger configuration.
+const (
+	ErrMsgUnknownFormat  = "logmonitor: unknown %s %q, expected json or console"
+	ErrMsgInvalidLevel   = "logmonitor: invalid %s %q, expected debug, info, warn or error"
+	ErrMsgInvalidBool    = "logmonitor: invalid %s %q, expected true or false"
+	ErrMsgUnknownPackage = "logmonitor: unknown package %q"
+
+	ErrMsgUnknownAccessLogFormat = "logmonitor: unknown %s %q, expected json or combined"
+	ErrMsgUnknownAccessLogField  = "logmonitor: unknown field %[2]q in %[1]s"
+	ErrMsgInvalidRedactPath      = "logmonitor: invalid pattern %[2]q in %[1]s: %[3]v"
+	ErrMsgInvalidRedactPattern   = "logmonitor: invalid pattern %[2]q in %[1]s: %[3]v"
+)
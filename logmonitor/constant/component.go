@@ -11,4 +11,5 @@ const (
 	ComponentInternalSecretENV = "customsecretkey"
 	ComponentMachineOperation  = "signal_notify"
 	ComponentGopher            = "hostmachine"
+	ComponentAuth              = "auth"
 )
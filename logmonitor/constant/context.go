@@ -39,6 +39,63 @@ const (
 	InfoAttemptingToRetrieveTheCurrentURL       = "Attempting to retrieve the current URL"
 	InfoFailedToRetrieveTheCurrentURL           = "Failed to retrieve the current URL for update"
 	InfoOldURLDoesMatchTheCurrentURL            = "Old URL does match the current URL"
+	URLsExportedContextLog                      = "URLs exported"
+	URLsImportedContextLog                      = "URLs imported"
+	FailedToTransferURLsContextLog              = "Failed to transfer URLs"
+	UnknownCommandContextLog                    = "unknown command:"
+	CommandFailedContextLog                     = "Command failed"
+	ForbiddenContextLog                         = "Principal lacks the required scope"
+	APIKeyCreatedContextLog                     = "API key created"
+	APIKeyRevokedContextLog                     = "API key revoked"
+	InvalidBearerTokenContextLog                = "Invalid bearer token"
+	FailedToSetupTokenVerifierContextLog        = "failed to set up bearer token verifier:"
+	TokenVerifierEnabledContextLog              = "Bearer token authentication enabled"
+	OwnershipDeniedContextLog                   = "Principal does not own the link"
+	IncorrectLinkPasswordContextLog             = "Incorrect password for protected link"
+	UnlockRateLimitedContextLog                 = "Too many unlock attempts for protected link"
+	RateLimitedContextLog                       = "Rate limit exceeded"
+	RateLimitBackendEnabledContextLog           = "Shared rate limit backend enabled"
+	RateLimitBackendUnavailableContextLog       = "Shared rate limit backend unavailable, falling back to local limiters"
+	FailedToSetupRateLimitBackendContextLog     = "failed to set up rate limit backend:"
+	URLPolicyViolationContextLog                = "Destination URL rejected by policy"
+	URLScannedContextLog                        = "Destination URL scanned"
+	URLScanFailedContextLog                     = "Failed to scan destination URL"
+	QuarantinedURLServedContextLog              = "Served warning page for quarantined link"
+	URLScannerEnabledContextLog                 = "URL scanning enabled"
+	URLScannerUnavailableContextLog             = "URL scanner unavailable, allowing the URL"
+	FailedToSetupURLScannerContextLog           = "failed to set up URL scanner:"
+	URLReusedContextLog                         = "Existing short link reused for destination"
+	IdempotentReplayContextLog                  = "Replayed stored response for idempotency key"
+	IdempotencyKeyMismatchContextLog            = "Idempotency key reused with a different request"
+	URLPatchedContextLog                        = "URL patched successfully"
+	LinkUnavailableContextLog                   = "Link is deleted, disabled or expired"
+	URLRestoredContextLog                       = "URL restored successfully"
+	URLRolledBackContextLog                     = "URL rolled back to a previous revision"
+	AuditWriteFailedContextLog                  = "Failed to write audit event"
+	AuditSinkEnabledContextLog                  = "Audit log enabled"
+	FailedToSetupAuditSinkContextLog            = "failed to set up audit log:"
+	TracingEnabledContextLog                    = "Tracing enabled"
+	FailedToSetupTracingContextLog              = "failed to set up tracing:"
+	FailedToShutdownTracingContextLog           = "Failed to flush the remaining spans"
+	MetricsServerStartContextLog                = "Metrics server is listening on address"
+	MetricsServerFailContextLog                 = "Metrics server failed"
+	WebhookCreatedContextLog                    = "Webhook subscription created"
+	FailedToSetupAccessLogContextLog            = "failed to set up request log:"
+	LogLevelChangedContextLog                   = "Log level changed"
+	LogLevelsResetContextLog                    = "Log levels reset to the configured level"
+	WebhookDeletedContextLog                    = "Webhook subscription deleted"
+	WebhookEnqueueFailedContextLog              = "Failed to queue webhook deliveries"
+	WebhookAttemptFailedContextLog              = "Webhook delivery failed, will retry"
+	WebhookDeadLetteredContextLog               = "Webhook delivery dead-lettered"
+	ClickFlushFailedContextLog                  = "Failed to store click count"
+	DeletedURLsPurgedContextLog                 = "Purged deleted URLs past their retention period"
+	FailedToPurgeURLsContextLog                 = "Failed to purge deleted URLs"
+	ReadinessCheckFailedContextLog              = "Readiness check failed"
+	ProbeServerStartContextLog                  = "Probe server is listening on address"
+	ProbeServerFailContextLog                   = "Probe server failed"
+	FailedToSetupDrainDelayContextLog           = "failed to set up shutdown drain delay:"
+	DrainDelayTooLongContextLog                 = "must be shorter than the shutdown timeout of"
+	DrainingContextLog                          = "Readiness is failing, waiting for traffic to drain before shutting down"
 )
 
 // Define JSON metadata for different components.
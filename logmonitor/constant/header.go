@@ -5,6 +5,7 @@ package constant
 // Note: some constants are not used in the code, indicate that for future use.
 const (
 	HeaderResponseError                     = "error"
+	HeaderResponseRequestID                 = "request_id"
 	HeaderResponseInternalServerError       = "internal server error"
 	HeaderResponseshortened_url             = "shortened_url"
 	HeaderResponseURlUpdated                = "URL updated successfully"
@@ -19,6 +20,50 @@ const (
 	HeaderResponseFailedtoSaveURL           = "Failed to save URL"
 	HeaderResponseStatus                    = "status"
 	HeaderResponseRateLimitExceeded         = "Too many requests, please try again later."
+	HeaderResponseUnsupportedFormat         = "Unsupported format"
+	HeaderResponseInvalidID                 = "Invalid ID"
+	HeaderResponseMalformedImport           = "Malformed import data"
+	HeaderResponseImportLineTooLong         = "Line exceeds the maximum size of 1 MiB"
+	HeaderResponseReport                    = "report"
+	HeaderResponseUnauthorized              = "Unauthorized"
+	HeaderResponseInvalidAPIKeyName         = "Invalid API key name"
+	HeaderResponseInvalidScope              = "Invalid scope"
+	HeaderResponseAPIKeyExists              = "API key already exists"
+	HeaderResponseAPIKeyNotFound            = "API key not found"
+	HeaderResponseAPIKeyRevoked             = "API key revoked successfully"
+	HeaderResponseAPIKey                    = "api_key"
+	HeaderResponseKey                       = "key"
+	HeaderResponseSecret                    = "secret"
+	HeaderResponseSubscription              = "subscription"
+	HeaderResponseKeys                      = "keys"
+	HeaderResponseIncorrectPassword         = "Incorrect password"
+	HeaderResponsePasswordTooLong           = "Password must not exceed 72 bytes"
+	HeaderResponseInvalidPasswordHash       = "Invalid password hash"
+	HeaderResponseQuarantined               = "quarantined"
+	HeaderResponseReused                    = "reused"
+	HeaderResponseInvalidIdempotencyKey     = "Invalid Idempotency-Key header"
+	HeaderResponseIdempotencyKeyMismatch    = "Idempotency-Key was already used with a different request"
+	HeaderResponseIdempotencyKeyInProgress  = "A request with this Idempotency-Key is still being processed"
+	HeaderResponseUnsupportedMediaType      = "Unsupported media type, expected application/merge-patch+json"
+	HeaderResponseLinkExpired               = "Link has expired"
+	HeaderResponseLinkDisabled              = "Link is disabled"
+	HeaderResponseLinkDeleted               = "Link has been deleted"
+	HeaderResponseURLRestored               = "URL restored successfully"
+	HeaderResponseURLNotDeleted             = "URL is not deleted"
+	HeaderResponseRevisionNotFound          = "Revision not found"
+	HeaderResponseAuditNotQueryable         = "The audit log cannot be queried with the configured sink"
+	HeaderResponseInvalidWebhookName        = "Invalid webhook name"
+	HeaderResponseWebhookExists             = "Webhook already exists"
+	HeaderResponseWebhookNotFound           = "Webhook not found"
+	HeaderResponseWebhookDeleted            = "Webhook deleted successfully"
+	HeaderResponseDeliveryNotFound          = "Delivery not found"
+	HeaderResponseDeliveryNotDead           = "Delivery is not dead-lettered"
+	HeaderResponseURLFlaggedUnsafe          = "Destination URL flagged as unsafe"
+	HeaderResponseScannerUnavailable        = "URL scanner unavailable, please try again later."
+	HeaderResponseLevels                    = "levels"
+	HeaderResponseInvalidLogLevel           = "Invalid log level, expected debug, info, warn or error"
+	HeaderResponseInvalidDuration           = "Invalid duration, expected up to 24h such as 30m"
+	HeaderResponseUnknownPackage            = "Unknown package"
 )
 
 // Define header request for different components.
@@ -40,11 +85,41 @@ const (
 	HeaderSchemeHTTPS     = "https"
 	HeaderXProto          = "X-Forwarded-Proto"
 	HeaderXinternalSecret = "X-Internal-Secret"
+	HeaderXAPIKey         = "X-API-Key"
+	HeaderAuthorization   = "Authorization"
+	HeaderSchemeBearer    = "Bearer"
+	HeaderName            = "name"
+	HeaderDelivery        = "delivery"
+	HeaderPasswordHash    = "password_hash"
+
+	HeaderContentType        = "Content-Type"
+	HeaderContentDisposition = "Content-Disposition"
+	ContentTypeCSV           = "text/csv; charset=utf-8"
+	ContentTypeNDJSON        = "application/x-ndjson"
+	ContentTypeHTML          = "text/html; charset=utf-8"
+	ContentTypeJSON          = "application/json; charset=utf-8"
+	MediaTypeJSON            = "application/json"
+	MediaTypeMergePatch      = "application/merge-patch+json"
+	HeaderCacheControl       = "Cache-Control"
+	CacheControlNoStore      = "no-store"
+	HeaderXFrameOptions      = "X-Frame-Options"
+	FrameOptionsDeny         = "DENY"
+	HeaderRetryAfter         = "Retry-After"
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	HeaderXRequestID         = "X-Request-ID"
 )
 
 // Define gin context log for different components.
 //
 // Note: some constants are not used in the code, indicate that for future use.
 const (
-	GinContextErrLog = "errorLogged"
+	GinContextErrLog        = "errorLogged"
+	GinContextPrincipal     = "principal"
+	GinContextPrincipalName = "principalName"
+	GinContextAuditChange   = "auditChange"
+	GinContextRequestID     = "requestID"
 )
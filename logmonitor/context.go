@@ -0,0 +1,40 @@
+package logmonitor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// CreateLogFieldsContext generates the same fields as CreateLogFields, followed by the fields that
+// correlate the entry with the request it was written for, taken from ctx. Use it wherever a context
+// is at hand, so that every log line of a request can be found from its trace.
+func CreateLogFieldsContext(ctx context.Context, operation string, options ...LogFieldOption) []zap.Field {
+	return append(CreateLogFields(operation, options...), ContextFields(ctx)...)
+}
+
+// ContextFields returns the 'request_id' field of the request carried by ctx, followed by the
+// 'trace_id' and 'span_id' fields of its span. Fields that ctx does not carry are left out. The trace
+// IDs are those of the incoming W3C trace context when the caller sent one, even when tracing is
+// disabled in this service.
+func ContextFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if id := RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, WithRequestID(id)())
+	}
+	return append(fields, traceFields(ctx)...)
+}
+
+// traceFields returns the 'trace_id' and 'span_id' fields of the span carried by ctx, or no fields
+// if there is none.
+func traceFields(ctx context.Context) []zap.Field {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", spanContext.TraceID().String()),
+		zap.String("span_id", spanContext.SpanID().String()),
+	}
+}
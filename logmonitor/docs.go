@@ -10,15 +10,43 @@
 //
 // # Functions:
 //   - SetLogger(logger *zap.Logger): Sets the global Logger variable to a specified zap.Logger.
+//   - NewLoggerConfigFromEnv(): Builds a LoggerConfig from the LOG_* environment variables.
+//   - NewLogger(config *LoggerConfig): Builds a zap.Logger with the configured encoding, level, sampling,
+//     output paths, Google Cloud Logging field names, emoji stripping and redaction.
+//   - NewRedactionConfigFromEnv(): Builds a RedactionConfig from the default rules and the LOG_REDACT_*
+//     environment variables.
+//   - NewRedactCore(core zapcore.Core, config *RedactionConfig): Wraps a core so that secrets and personal
+//     data are redacted from every entry before it is encoded.
+//   - StripEmoji(message string): Removes the emoji prefix of a message.
+//   - PackageLogger(logger *zap.Logger, pkg string): Returns a logger filtered by the runtime level of a package.
+//   - SetLevel(pkg string, level zapcore.Level, revertAfter time.Duration): Changes the level of one or every
+//     package until revertAfter has elapsed.
+//   - ResetLevels(): Sets every package back to the configured level.
+//   - Levels(): Returns the current level of every package.
 //   - NewBadRequestError(userMessage string, err error): Creates a new BadRequestError instance.
 //   - CreateLogFields(operation string, options ...LogFieldOption): Generates common log fields.
+//   - CreateLogFieldsContext(ctx context.Context, operation string, options ...LogFieldOption): Generates
+//     common log fields followed by the request ID and the trace and span IDs of the request carried by ctx.
+//   - ContextFields(ctx context.Context): Returns the 'request_id', 'trace_id' and 'span_id' fields of the
+//     request carried by ctx.
+//   - ContextWithRequestID(ctx context.Context, id string): Returns a copy of ctx that carries a request ID.
+//   - RequestIDFromContext(ctx context.Context): Returns the request ID carried by ctx.
 //   - WithComponent(component string): Returns a LogFieldOption that adds a 'component' field.
 //   - WithID(id string): Returns a LogFieldOption that adds an 'id' field.
 //   - WithError(err error): Returns a LogFieldOption that adds an 'error' field.
 //   - WithSignal(signal os.Signal): Returns a LogFieldOption that adds a 'signal' field.
+//   - WithRequestID(id string): Returns a LogFieldOption that adds a 'request_id' field.
+//   - RequestID(): Gin middleware that accepts or generates the X-Request-ID of each request.
 //   - RequestLogger(logger *zap.Logger): Gin middleware that logs HTTP or HTTPS requests.
+//   - RequestLoggerWithConfig(logger *zap.Logger, config *AccessLogConfig): Gin middleware that logs requests
+//     with optional fields and headers, as structured fields or Apache combined lines, with secrets redacted.
+//   - DefaultAccessLogConfig(), NewAccessLogConfigFromEnv(): Build the configuration of the request log.
 //
 // # Types:
+//   - LoggerConfig: Configuration of the logger built by NewLogger.
+//   - RedactionConfig: The field names, patterns, query parameters and IP truncation applied to every entry.
+//   - PackageLevel: The current level of a package, and when it reverts.
+//   - AccessLogConfig: Configuration of the request log written by RequestLoggerWithConfig.
 //   - BadRequestError: Custom error type with a user-friendly message and an underlying error.
 //   - LogFieldOption: Function signature for options to create log fields.
 //
@@ -29,11 +57,33 @@
 //
 //	func main() {
 //	    router := gin.Default()
+//	    router.Use(logmonitor.RequestID())
 //	    router.Use(logmonitor.RequestLogger(logmonitor.Logger))
 //	    // ... additional middleware and route setup ...
 //	    router.Run(":8080")
 //	}
 //
+// Configuring the Logger:
+// The logger is configured from the LOG_* environment variables. The defaults write JSON at the info level,
+// or colored console output at the debug level when GIN_MODE is debug.
+//
+//	config, err := logmonitor.NewLoggerConfigFromEnv()
+//	if err != nil {
+//	    return err
+//	}
+//	logger, err := logmonitor.NewLogger(config)
+//	if err != nil {
+//	    return err
+//	}
+//	logmonitor.SetLogger(logmonitor.PackageLogger(logger, logmonitor.PackageLogmonitor))
+//
+// Runtime Log Levels:
+// Each package in Packages has a log level of its own, initially the configured one. SetLevel changes it
+// for a while, for example to debug an incident on a live instance, after which it reverts on its own.
+//
+//	handlers.SetLogger(logmonitor.PackageLogger(logger, logmonitor.PackageHandlers))
+//	err := logmonitor.SetLevel(logmonitor.PackageHandlers, zapcore.DebugLevel, 15*time.Minute)
+//
 // Flushing Logs:
 // It is crucial to flush any buffered log entries upon application termination to ensure all logs
 // are committed to their intended destination. This is accomplished by invoking the Logger.Sync()
@@ -52,7 +102,14 @@
 //	}
 //
 // The RequestLogger middleware logs vital request details and should be included as part of the
-// Gin router setup to capture request metrics in a structured log format.
+// Gin router setup to capture request metrics in a structured log format. RequestLoggerWithConfig
+// can log more of each request; the values of the AlwaysRedactedHeaders and the query string are
+// never logged, so that credentials cannot leak into the logs.
+//
+// Every entry of the logger built by NewLogger, whichever package writes it, also goes through the
+// redaction rules: the values of sensitive fields such as 'password' or 'api_key', email addresses,
+// bearer credentials, JSON Web Tokens and sensitive query parameters are replaced with RedactedValue
+// in messages, errors and logged payloads alike.
 //
 // Copyright (c) 2023 by H0llyW00dzZ
 package logmonitor
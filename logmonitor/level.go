@@ -0,0 +1,186 @@
+package logmonitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// PackageLevel is the log level of one package, as reported by Levels.
+type PackageLevel struct {
+	Package   string     `json:"package"`
+	Level     string     `json:"level"`
+	RevertsAt *time.Time `json:"reverts_at,omitempty"` // When the level goes back to the configured one, if it was changed.
+}
+
+// levelRegistry holds the log level of each package, which can be changed at runtime. Every changed
+// level goes back to the configured one after a while, so that a forgotten debug level does not
+// flood the logs.
+type levelRegistry struct {
+	mu         sync.Mutex
+	configured zapcore.Level
+	levels     map[string]zap.AtomicLevel
+	reverts    map[string]*time.Timer
+	revertsAt  map[string]time.Time
+}
+
+// levels is the level registry of the application. NewLogger sets its configured level.
+var levels = newLevelRegistry(zapcore.InfoLevel)
+
+// newLevelRegistry creates a registry in which every package logs at the given level.
+func newLevelRegistry(configured zapcore.Level) *levelRegistry {
+	r := &levelRegistry{
+		configured: configured,
+		levels:     make(map[string]zap.AtomicLevel, len(Packages)),
+		reverts:    make(map[string]*time.Timer),
+		revertsAt:  make(map[string]time.Time),
+	}
+	for _, pkg := range Packages {
+		r.levels[pkg] = zap.NewAtomicLevelAt(configured)
+	}
+	return r
+}
+
+// PackageLogger returns a logger that writes the entries of logger that are enabled at the current level
+// of the package pkg, so that its level can be changed with SetLevel. The level of the logger itself
+// is replaced if it was built by NewLogger, so a package can log more than the configured level.
+func PackageLogger(logger *zap.Logger, pkg string) *zap.Logger {
+	level, ok := levels.level(pkg)
+	if !ok {
+		panic(fmt.Sprintf(ErrMsgUnknownPackage, pkg))
+	}
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if lc, ok := core.(*levelCore); ok {
+			core = lc.Core
+		}
+		return &levelCore{Core: core, level: level}
+	}))
+}
+
+// SetLevel changes the log level of the package pkg, or of every package if pkg is empty, until
+// revertAfter has elapsed. The level then goes back to the configured one.
+func SetLevel(pkg string, level zapcore.Level, revertAfter time.Duration) error {
+	return levels.set(pkg, level, revertAfter)
+}
+
+// ResetLevels sets every package back to the configured log level.
+func ResetLevels() {
+	levels.reset()
+}
+
+// Levels returns the current log level of every package.
+func Levels() []PackageLevel {
+	return levels.list()
+}
+
+// level returns the level of the package pkg.
+func (r *levelRegistry) level(pkg string) (zap.AtomicLevel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	level, ok := r.levels[pkg]
+	return level, ok
+}
+
+// configure sets the configured level, and sets every package to it.
+func (r *levelRegistry) configure(level zapcore.Level) {
+	r.mu.Lock()
+	r.configured = level
+	r.mu.Unlock()
+	r.reset()
+}
+
+// set changes the level of one or every package and schedules its reversion.
+func (r *levelRegistry) set(pkg string, level zapcore.Level, revertAfter time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pkgs := Packages
+	if pkg != "" {
+		if _, ok := r.levels[pkg]; !ok {
+			return fmt.Errorf(ErrMsgUnknownPackage, pkg)
+		}
+		pkgs = []string{pkg}
+	}
+
+	for _, pkg := range pkgs {
+		r.levels[pkg].SetLevel(level)
+		if timer, ok := r.reverts[pkg]; ok {
+			timer.Stop()
+		}
+		var timer *time.Timer
+		timer = time.AfterFunc(revertAfter, func() { r.revert(pkg, &timer) })
+		r.reverts[pkg] = timer
+		r.revertsAt[pkg] = time.Now().Add(revertAfter)
+	}
+	return nil
+}
+
+// revert sets the package pkg back to the configured level, unless the timer that fired has been
+// replaced by a later change or cancelled by a reset in the meantime.
+func (r *levelRegistry) revert(pkg string, timer **time.Timer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reverts[pkg] != *timer {
+		return
+	}
+	r.levels[pkg].SetLevel(r.configured)
+	delete(r.reverts, pkg)
+	delete(r.revertsAt, pkg)
+}
+
+// reset sets every package back to the configured level and cancels the pending reversions.
+func (r *levelRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for pkg, level := range r.levels {
+		level.SetLevel(r.configured)
+		if timer, ok := r.reverts[pkg]; ok {
+			timer.Stop()
+		}
+	}
+	clear(r.reverts)
+	clear(r.revertsAt)
+}
+
+// list returns the level of every package, in the order of Packages.
+func (r *levelRegistry) list() []PackageLevel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]PackageLevel, 0, len(r.levels))
+	for _, pkg := range Packages {
+		entry := PackageLevel{Package: pkg, Level: r.levels[pkg].Level().String()}
+		if at, ok := r.revertsAt[pkg]; ok {
+			entry.RevertsAt = &at
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// levelCore writes the entries of the wrapped core that are enabled at a level that can change at runtime.
+// The wrapped core is built at the debug level, so that it does not filter them further.
+type levelCore struct {
+	zapcore.Core
+	level zap.AtomicLevel
+}
+
+// Enabled reports whether entries of the given level are written.
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// With adds structured context to the core, keeping its level.
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+// Check lets the wrapped core check the entry if its level is enabled.
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -0,0 +1,48 @@
+// Gopher Unit Testing was here
+package logmonitor
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestSetLevel checks that the level of one package can be raised without affecting the others,
+// and that it reverts on its own once the duration has elapsed.
+func TestSetLevel(t *testing.T) {
+	levels.configure(zapcore.InfoLevel)
+	t.Cleanup(ResetLevels)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+	handlersLogger := PackageLogger(logger, PackageHandlers)
+	datastoreLogger := PackageLogger(logger, PackageDatastore).With(zap.String("worker", "1"))
+
+	if err := SetLevel(PackageHandlers, zapcore.DebugLevel, 50*time.Millisecond); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	handlersLogger.Debug("handlers")
+	datastoreLogger.Debug("datastore")
+	if logs.Len() != 1 || logs.All()[0].Message != "handlers" {
+		t.Errorf("logged %v, want only the debug entry of handlers", logs.All())
+	}
+
+	for _, level := range Levels() {
+		if level.Package == PackageHandlers && (level.Level != "debug" || level.RevertsAt == nil) {
+			t.Errorf("Levels() reports %+v for handlers, want debug with a reversion time", level)
+		}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	handlersLogger.Debug("handlers again")
+	if logs.Len() != 1 {
+		t.Errorf("logged %d entries, want the level of handlers to have reverted to info", logs.Len())
+	}
+
+	if err := SetLevel("unknown", zapcore.DebugLevel, time.Minute); err == nil {
+		t.Errorf("SetLevel() for an unknown package error = nil, want an error")
+	}
+}
@@ -0,0 +1,144 @@
+package logmonitor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggerConfig holds the configuration of the logger built by NewLogger.
+type LoggerConfig struct {
+	Format       string           // The encoding of the entries, FormatJSON or FormatConsole.
+	Level        zapcore.Level    // The minimum level of the entries that are written.
+	Sampling     bool             // Whether repeated entries are sampled, to bound the cost of logging under load.
+	OutputPaths  []string         // Where the entries are written: "stdout", "stderr" or file paths.
+	CloudLogging bool             // Whether the field names of Google Cloud Logging are used.
+	ProjectID    string           // The Google Cloud project that trace IDs are qualified with when CloudLogging is set.
+	StripEmoji   bool             // Whether the emoji prefixes of the messages are removed.
+	Redaction    *RedactionConfig // The rules applied to every entry before it is encoded, or nil for none.
+}
+
+// NewLoggerConfigFromEnv builds a LoggerConfig from the LOG_* environment variables. The defaults suit
+// production, unless GIN_MODE is debug, in which case they suit development.
+func NewLoggerConfigFromEnv() (*LoggerConfig, error) {
+	development := os.Getenv(gin.EnvGinMode) == gin.DebugMode
+	config := &LoggerConfig{
+		Format:      FormatJSON,
+		Level:       zapcore.InfoLevel,
+		Sampling:    !development,
+		OutputPaths: []string{"stderr"},
+	}
+	if development {
+		config.Format = FormatConsole
+		config.Level = zapcore.DebugLevel
+	}
+
+	if value := os.Getenv(LOG_FORMAT); value != "" {
+		if value != FormatJSON && value != FormatConsole {
+			return nil, fmt.Errorf(ErrMsgUnknownFormat, LOG_FORMAT, value)
+		}
+		config.Format = value
+	}
+	if value := os.Getenv(LOG_LEVEL); value != "" {
+		level, err := zapcore.ParseLevel(value)
+		if err != nil || level > zapcore.ErrorLevel {
+			return nil, fmt.Errorf(ErrMsgInvalidLevel, LOG_LEVEL, value)
+		}
+		config.Level = level
+	}
+	if value := os.Getenv(LOG_OUTPUT_PATHS); value != "" {
+		config.OutputPaths = nil
+		for _, path := range strings.Split(value, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				config.OutputPaths = append(config.OutputPaths, path)
+			}
+		}
+	}
+
+	var err error
+	if config.Sampling, err = boolFromEnv(LOG_SAMPLING, config.Sampling); err != nil {
+		return nil, err
+	}
+	if config.CloudLogging, err = boolFromEnv(LOG_CLOUD_LOGGING, false); err != nil {
+		return nil, err
+	}
+	if config.StripEmoji, err = boolFromEnv(LOG_STRIP_EMOJI, false); err != nil {
+		return nil, err
+	}
+	if config.Redaction, err = NewRedactionConfigFromEnv(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// boolFromEnv returns the boolean value of an environment variable, or fallback if it is not set.
+func boolFromEnv(name string, fallback bool) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf(ErrMsgInvalidBool, name, value)
+	}
+	return b, nil
+}
+
+// NewLogger builds a zap logger from the configuration. Errors of the logger itself, such as a failed
+// write, are reported on the standard error.
+//
+// The configured level becomes the level of every package, which SetLevel can change at runtime.
+// The returned logger logs at the level of PackageApp; use PackageLogger to derive the logger of
+// another package. As every package logger shares its core, the redaction rules apply to all of them.
+func NewLogger(config *LoggerConfig) (*zap.Logger, error) {
+	levels.configure(config.Level)
+	level, _ := levels.level(PackageApp)
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	switch {
+	case config.CloudLogging:
+		encoderConfig = cloudLoggingEncoderConfig()
+	case config.Format == FormatConsole:
+		encoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	encoder := zapcore.NewJSONEncoder(encoderConfig)
+	if config.Format == FormatConsole {
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
+
+	sink, closeSink, err := zap.Open(config.OutputPaths...)
+	if err != nil {
+		return nil, err
+	}
+	errorSink, _, err := zap.Open("stderr")
+	if err != nil {
+		closeSink()
+		return nil, err
+	}
+
+	// Entries are filtered by the level of their package, so the core itself writes every level.
+	var core zapcore.Core = zapcore.NewCore(encoder, sink, zapcore.DebugLevel)
+	if config.CloudLogging {
+		core = &cloudLoggingCore{Core: core, projectID: config.ProjectID}
+	}
+	if config.StripEmoji {
+		core = &stripEmojiCore{Core: core}
+	}
+	if config.Redaction != nil {
+		core = NewRedactCore(core, config.Redaction)
+	}
+	// The cores above rewrite entries as they are written, so they sit beneath the sampler, whose
+	// decision is taken when an entry is checked.
+	if config.Sampling {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, samplingInitial, samplingThereafter)
+	}
+
+	return zap.New(&levelCore{Core: core, level: level},
+		zap.ErrorOutput(errorSink), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
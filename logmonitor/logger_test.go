@@ -0,0 +1,69 @@
+// Gopher Unit Testing was here
+package logmonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestNewLoggerConfigFromEnv checks the production defaults and that invalid settings are rejected.
+func TestNewLoggerConfigFromEnv(t *testing.T) {
+	t.Setenv("GIN_MODE", "release")
+	config, err := NewLoggerConfigFromEnv()
+	if err != nil || config.Format != FormatJSON || config.Level != zapcore.InfoLevel || !config.Sampling {
+		t.Errorf("NewLoggerConfigFromEnv() = %+v, %v, want sampled JSON at the info level", config, err)
+	}
+
+	t.Setenv(LOG_OUTPUT_PATHS, "stdout, /var/log/app.log")
+	t.Setenv(LOG_STRIP_EMOJI, "true")
+	config, err = NewLoggerConfigFromEnv()
+	if err != nil || len(config.OutputPaths) != 2 || config.OutputPaths[1] != "/var/log/app.log" || !config.StripEmoji {
+		t.Errorf("NewLoggerConfigFromEnv() = %+v, %v, want two output paths and emojis stripped", config, err)
+	}
+
+	for name, value := range map[string]string{LOG_FORMAT: "xml", LOG_LEVEL: "fatal", LOG_SAMPLING: "sometimes"} {
+		t.Setenv(name, value)
+		if _, err := NewLoggerConfigFromEnv(); err == nil {
+			t.Errorf("NewLoggerConfigFromEnv() with %s=%s error = nil, want an error", name, value)
+		}
+	}
+}
+
+// TestCloudLoggingCore checks the field names of Google Cloud Logging, including the request log entry.
+func TestCloudLoggingCore(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(cloudLoggingEncoderConfig()), zapcore.AddSync(&buf), zapcore.DebugLevel)
+	logger := zap.New(&stripEmojiCore{Core: &cloudLoggingCore{Core: core, projectID: "demo"}})
+
+	logger.Warn(constant.K8sEmoji+"  "+constant.WarningEmoji+"  "+constant.RequestDetails,
+		zap.Int("status", 404),
+		zap.String("method", "GET"),
+		zap.String("path", "/abc"),
+		zap.Duration("duration", 1500*time.Millisecond),
+		zap.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+	)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if entry["severity"] != "WARNING" || entry["message"] != constant.RequestDetails {
+		t.Errorf("severity, message = %v, %v, want WARNING, %q", entry["severity"], entry["message"], constant.RequestDetails)
+	}
+	if trace := entry["logging.googleapis.com/trace"]; trace != "projects/demo/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace = %v, want it qualified with the project", trace)
+	}
+	request, _ := entry["httpRequest"].(map[string]any)
+	if request["requestMethod"] != "GET" || request["requestUrl"] != "/abc" || request["status"] != 404.0 || request["latency"] != "1.5s" {
+		t.Errorf("httpRequest = %v, want GET /abc with status 404 and latency 1.5s", entry["httpRequest"])
+	}
+	if _, ok := entry["status"]; ok {
+		t.Errorf("status is still a top-level field")
+	}
+}
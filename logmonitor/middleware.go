@@ -81,6 +81,14 @@ func WithError(err error) LogFieldOption {
 	}
 }
 
+// WithPrincipal returns a LogFieldOption that adds a 'principal' field to the log.
+// The principal is the name of the API key (or other identity) that made the request.
+func WithPrincipal(name string) LogFieldOption {
+	return func() zap.Field {
+		return zap.String("principal", name)
+	}
+}
+
 // WithSignal returns a LogFieldOption that adds a 'signal' field to the log.
 func WithSignal(signal os.Signal) LogFieldOption {
 	return func() zap.Field {
@@ -112,14 +120,22 @@ func WithAnyZapField(field zap.Field) LogFieldOption {
 //   - HTTP or HTTPS method of the request
 //   - Requested path
 //   - Duration taken to process the request
+//   - The authenticated principal and the ID and trace of the request, when known
 //
 // The logs are output in a structured format, making them easy to read and parse.
+// Use RequestLoggerWithConfig to log more fields or to write Apache combined lines.
 func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
+	return RequestLoggerWithConfig(logger, DefaultAccessLogConfig())
+}
+
+// RequestLoggerWithConfig returns a gin.HandlerFunc (middleware) that logs requests like RequestLogger,
+// with the optional fields, headers, format and redaction rules of the configuration. The values of
+// the AlwaysRedactedHeaders are never logged, and neither is the query string of the request.
+func RequestLoggerWithConfig(logger *zap.Logger, config *AccessLogConfig) gin.HandlerFunc {
+	access := newAccessLogger(logger, config)
 	return func(c *gin.Context) {
 		// Start timer to track the duration of the request processing.
 		start := time.Now()
-		// Format the start time as a string in the desired format.
-		startTimeFormatted := start.Format(constant.TimestampFormat)
 
 		// Process the request by calling the next handler in the chain.
 		c.Next()
@@ -127,6 +143,11 @@ func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
 		// Calculate the duration taken for the request to be processed.
 		duration := time.Since(start)
 
+		if config.Format == AccessLogCombined {
+			logger.Info(access.combinedLine(c, start), access.correlationFields(c)...)
+			return
+		}
+
 		// Choose the emoji based on the HTTP status code.
 		statusEmoji := constant.InfoEmoji
 		if c.Writer.Status() >= 400 && c.Writer.Status() < 500 {
@@ -137,12 +158,6 @@ func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
 
 		// Log details of the request with zap, including the emoji.
 		// Here we add the K8sEmoji to the log message.
-		logger.Info(constant.K8sEmoji+"  "+statusEmoji+"  "+constant.RequestDetails,
-			zap.String("hostmachine_start_time", startTimeFormatted),
-			zap.Int("status", c.Writer.Status()),
-			zap.String("method", c.Request.Method),
-			zap.String("path", c.Request.URL.Path),
-			zap.Duration("duration", duration),
-		)
+		logger.Info(constant.K8sEmoji+"  "+statusEmoji+"  "+constant.RequestDetails, access.requestFields(c, start, duration)...)
 	}
 }
@@ -0,0 +1,317 @@
+package logmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactionConfig holds the rules applied to every log entry before it is encoded.
+type RedactionConfig struct {
+	Fields      []string         // Names of the fields whose values are always redacted, compared without case.
+	Patterns    []*regexp.Regexp // Patterns whose matches in messages and string values are redacted.
+	QueryParams []string         // Names of the query parameters whose values are redacted, compared without case.
+	TruncateIPs bool             // Whether IP addresses are truncated to their network (/24 for IPv4, /48 for IPv6).
+}
+
+// NewRedactionConfigFromEnv builds a RedactionConfig from the default rules, extended with the
+// LOG_REDACT_* environment variables. The default rules cannot be turned off.
+func NewRedactionConfigFromEnv() (*RedactionConfig, error) {
+	config := &RedactionConfig{
+		Fields:      slices.Concat(DefaultRedactedFields, splitList(os.Getenv(LOG_REDACT_FIELDS))),
+		QueryParams: slices.Concat(DefaultRedactedQueryParams, splitList(os.Getenv(LOG_REDACT_QUERY_PARAMS))),
+	}
+	for _, pattern := range slices.Concat(DefaultRedactedPatterns, splitList(os.Getenv(LOG_REDACT_PATTERNS))) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf(ErrMsgInvalidRedactPattern, LOG_REDACT_PATTERNS, pattern, err)
+		}
+		config.Patterns = append(config.Patterns, re)
+	}
+
+	var err error
+	if config.TruncateIPs, err = boolFromEnv(LOG_TRUNCATE_IPS, false); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ipv4Pattern matches IPv4 addresses within a string, capturing their first three bytes.
+var ipv4Pattern = regexp.MustCompile(`\b(\d{1,3}\.\d{1,3}\.\d{1,3})\.\d{1,3}\b`)
+
+// redactor applies the rules of a RedactionConfig to strings, fields and values.
+type redactor struct {
+	config      *RedactionConfig
+	fields      map[string]bool
+	queryParams *regexp.Regexp
+}
+
+// newRedactor prepares the rules for use on every entry.
+func newRedactor(config *RedactionConfig) *redactor {
+	r := &redactor{config: config, fields: make(map[string]bool, len(config.Fields))}
+	for _, name := range config.Fields {
+		r.fields[strings.ToLower(name)] = true
+	}
+	if len(config.QueryParams) > 0 {
+		names := make([]string, len(config.QueryParams))
+		for i, name := range config.QueryParams {
+			names[i] = regexp.QuoteMeta(name)
+		}
+		r.queryParams = regexp.MustCompile(`(?i)(^|[?&;\s])(` + strings.Join(names, "|") + `)=[^&#\s"']*`)
+	}
+	return r
+}
+
+// isRedactedField reports whether the values of the field named key are always redacted.
+func (r *redactor) isRedactedField(key string) bool {
+	return r.fields[strings.ToLower(key)]
+}
+
+// redactString applies the query parameter, pattern and IP rules to a string.
+func (r *redactor) redactString(s string) string {
+	if r.config.TruncateIPs {
+		if addr, err := netip.ParseAddr(s); err == nil {
+			return truncateIP(addr)
+		}
+		s = ipv4Pattern.ReplaceAllString(s, "${1}.0")
+	}
+	if r.queryParams != nil {
+		s = r.queryParams.ReplaceAllString(s, "${1}${2}="+RedactedValue)
+	}
+	for _, re := range r.config.Patterns {
+		s = re.ReplaceAllString(s, RedactedValue)
+	}
+	return s
+}
+
+// truncateIP returns the network of the address: its first 24 bits for IPv4, or 48 bits for IPv6.
+func truncateIP(addr netip.Addr) string {
+	bits := 48
+	if addr.Is4() || addr.Is4In6() {
+		addr, bits = addr.Unmap(), 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.Addr().String()
+}
+
+// redactFields returns the fields with the rules applied. The given slice is not modified.
+func (r *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = r.redactField(field)
+	}
+	return redacted
+}
+
+// redactField applies the rules to one field. Errors, stringers and reflected values are turned into
+// their text or JSON form when the rules change it, as their content could not be redacted otherwise.
+func (r *redactor) redactField(field zapcore.Field) zapcore.Field {
+	if field.Type == zapcore.SkipType || field.Type == zapcore.NamespaceType {
+		return field
+	}
+	if r.isRedactedField(field.Key) {
+		return zap.String(field.Key, RedactedValue)
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = r.redactString(field.String)
+	case zapcore.ByteStringType:
+		return zap.String(field.Key, r.redactString(string(field.Interface.([]byte))))
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok {
+			if text := err.Error(); r.redactString(text) != text {
+				return zap.String(field.Key, r.redactString(text))
+			}
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok {
+			if text := stringer.String(); r.redactString(text) != text {
+				return zap.String(field.Key, r.redactString(text))
+			}
+		}
+	case zapcore.ReflectType:
+		return zap.Any(field.Key, r.redactValue(field.Interface))
+	case zapcore.ObjectMarshalerType:
+		return zap.Object(field.Key, redactingObject{ObjectMarshaler: field.Interface.(zapcore.ObjectMarshaler), r: r})
+	case zapcore.ArrayMarshalerType:
+		return zap.Array(field.Key, redactingArray{ArrayMarshaler: field.Interface.(zapcore.ArrayMarshaler), r: r})
+	}
+	return field
+}
+
+// redactValue applies the rules to a value logged by reflection, such as a bound JSON payload. The value
+// is returned as is, unless its JSON form contains something to redact.
+func (r *redactor) redactValue(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return value
+	}
+	if redacted, changed := r.redactJSON(decoded); changed {
+		return redacted
+	}
+	return value
+}
+
+// redactJSON applies the rules to a decoded JSON value and reports whether anything was redacted.
+func (r *redactor) redactJSON(value any) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		redacted := r.redactString(v)
+		return redacted, redacted != v
+	case map[string]any:
+		changed := false
+		for key, item := range v {
+			if r.isRedactedField(key) {
+				v[key], changed = RedactedValue, true
+				continue
+			}
+			if redacted, ok := r.redactJSON(item); ok {
+				v[key], changed = redacted, true
+			}
+		}
+		return v, changed
+	case []any:
+		changed := false
+		for i, item := range v {
+			if redacted, ok := r.redactJSON(item); ok {
+				v[i], changed = redacted, true
+			}
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+// redactingObject applies the rules to the values an object marshaler writes.
+type redactingObject struct {
+	zapcore.ObjectMarshaler
+	r *redactor
+}
+
+// MarshalLogObject writes the object through an encoder that applies the rules.
+func (o redactingObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.ObjectMarshaler.MarshalLogObject(&redactingObjectEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+// redactingArray applies the rules to the values an array marshaler writes.
+type redactingArray struct {
+	zapcore.ArrayMarshaler
+	r *redactor
+}
+
+// MarshalLogArray writes the array through an encoder that applies the rules.
+func (a redactingArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.ArrayMarshaler.MarshalLogArray(&redactingArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+// redactingObjectEncoder applies the rules to the strings, objects, arrays and reflected values of an object.
+type redactingObjectEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+func (e *redactingObjectEncoder) AddString(key, value string) {
+	if e.r.isRedactedField(key) {
+		value = RedactedValue
+	}
+	e.ObjectEncoder.AddString(key, e.r.redactString(value))
+}
+
+func (e *redactingObjectEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *redactingObjectEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	if e.r.isRedactedField(key) {
+		e.ObjectEncoder.AddString(key, RedactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactingObject{ObjectMarshaler: marshaler, r: e.r})
+}
+
+func (e *redactingObjectEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	if e.r.isRedactedField(key) {
+		e.ObjectEncoder.AddString(key, RedactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, redactingArray{ArrayMarshaler: marshaler, r: e.r})
+}
+
+func (e *redactingObjectEncoder) AddReflected(key string, value any) error {
+	if e.r.isRedactedField(key) {
+		e.ObjectEncoder.AddString(key, RedactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, e.r.redactValue(value))
+}
+
+// redactingArrayEncoder applies the rules to the strings, objects, arrays and reflected values of an array.
+type redactingArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redactor
+}
+
+func (e *redactingArrayEncoder) AppendString(value string) {
+	e.ArrayEncoder.AppendString(e.r.redactString(value))
+}
+
+func (e *redactingArrayEncoder) AppendByteString(value []byte) {
+	e.AppendString(string(value))
+}
+
+func (e *redactingArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactingObject{ObjectMarshaler: marshaler, r: e.r})
+}
+
+func (e *redactingArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactingArray{ArrayMarshaler: marshaler, r: e.r})
+}
+
+func (e *redactingArrayEncoder) AppendReflected(value any) error {
+	return e.ArrayEncoder.AppendReflected(e.r.redactValue(value))
+}
+
+// redactCore applies the redaction rules to the message and fields of every entry before the wrapped
+// core encodes it.
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+// With adds structured context to the core, redacting it once.
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.redactFields(fields)), r: c.r}
+}
+
+// Check adds the core to the checked entry if the level of the entry is enabled.
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write redacts the entry and writes it to the wrapped core.
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.r.redactString(entry.Message)
+	return c.Core.Write(entry, c.r.redactFields(fields))
+}
+
+// NewRedactCore wraps core so that the rules of the configuration are applied to every entry before it
+// is encoded. NewLogger applies it when LoggerConfig.Redaction is set; use it to protect loggers built
+// otherwise.
+func NewRedactCore(core zapcore.Core, config *RedactionConfig) zapcore.Core {
+	return &redactCore{Core: core, r: newRedactor(config)}
+}
@@ -0,0 +1,60 @@
+// Gopher Unit Testing was here
+package logmonitor
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestRedactCore checks that secrets and personal data are redacted from the message, the fields and
+// the context of every entry, and that IP addresses are truncated.
+func TestRedactCore(t *testing.T) {
+	config, err := NewRedactionConfigFromEnv()
+	if err != nil {
+		t.Fatalf("NewRedactionConfigFromEnv() error = %v", err)
+	}
+	config.TruncateIPs = true
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(NewRedactCore(core, config)).With(zap.String("api_key", "ci.s3cr3t"))
+	payload := map[string]any{"url": "https://example.com/?token=abc&page=2", "owner": map[string]any{"password": "hunter2"}}
+	logger.Info("sent to jane@example.com",
+		zap.Error(errors.New("rpc error: GET /v1/keys?key=s3cr3t failed")),
+		zap.String("client_ip", "192.0.2.123"),
+		zap.String("remote", "2001:db8:1234:5678::1"),
+		zap.Strings("scopes", []string{"Bearer abc.def"}),
+		zap.Any("payload", payload),
+	)
+
+	entry := logs.All()[0]
+	if entry.Message != "sent to [REDACTED]" {
+		t.Errorf("message = %q, want the email redacted", entry.Message)
+	}
+	fields := entry.ContextMap()
+	want := map[string]any{
+		"api_key":   RedactedValue,
+		"error":     "rpc error: GET /v1/keys?key=[REDACTED] failed",
+		"client_ip": "192.0.2.0",
+		"remote":    "2001:db8:1234::",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v, want %v", key, fields[key], value)
+		}
+	}
+	if scopes, _ := fields["scopes"].([]any); len(scopes) != 1 || scopes[0] != RedactedValue {
+		t.Errorf("scopes = %v, want the bearer credential redacted", fields["scopes"])
+	}
+	logged, _ := fields["payload"].(map[string]any)
+	owner, _ := logged["owner"].(map[string]any)
+	if logged["url"] != "https://example.com/?token=[REDACTED]&page=2" || owner["password"] != RedactedValue {
+		t.Errorf("payload = %v, want the token and password redacted", logged)
+	}
+	if payload["owner"].(map[string]any)["password"] != "hunter2" {
+		t.Errorf("the logged payload was modified")
+	}
+}
@@ -0,0 +1,81 @@
+package logmonitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/H0llyW00dzZ/go-urlshortner/logmonitor/constant"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxRequestIDLength is the longest request ID accepted from a client. Longer IDs are replaced,
+// so that a client cannot bloat every log line of its request.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key under which the ID of the request is stored.
+type requestIDKey struct{}
+
+// RequestID returns a gin.HandlerFunc (middleware) that assigns an ID to every request. The ID sent
+// by the client in the X-Request-ID header is kept if it is valid, so that a request can be followed
+// across services; otherwise a random ID is generated. The ID is stored in the request context, where
+// CreateLogFieldsContext and ContextFields find it, and is echoed in the X-Request-ID response header.
+//
+// It should be registered before any middleware that logs, so that their entries carry the ID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(constant.HeaderXRequestID)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Request = c.Request.WithContext(ContextWithRequestID(c.Request.Context(), id))
+		c.Set(constant.GinContextRequestID, id)
+		c.Header(constant.HeaderXRequestID, id)
+		c.Next()
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx that carries the given request ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequestID returns a LogFieldOption that adds a 'request_id' field to the log.
+func WithRequestID(id string) LogFieldOption {
+	return func() zap.Field {
+		return zap.String("request_id", id)
+	}
+}
+
+// isValidRequestID reports whether a request ID sent by a client can be used as is. Only printable
+// characters that need no escaping in logs or headers are accepted.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':', r == '/', r == '+', r == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random request ID of 32 hexadecimal characters.
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read does not fail on the supported platforms.
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,35 @@
+package metrics
+
+// Define environment variables used to configure the metrics listener.
+const (
+	METRICS_ADDR = "METRICS_ADDR"
+)
+
+// Define the path and the content type of the metrics endpoint.
+const (
+	PathMetrics = "/metrics"
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Define the outcomes of a redirect, used as the "result" label of Redirects.
+const (
+	RedirectHit  = "hit"  // The visitor was redirected to the destination.
+	RedirectMiss = "miss" // No link exists under the requested ID.
+	RedirectGone = "gone" // The link was deleted, disabled or has expired.
+)
+
+// unmatchedRoute is the route label of requests that matched no route, so that unknown paths
+// do not each create a new series.
+const unmatchedRoute = "unmatched"
+
+// otherMethod is the method label of requests with a method outside the standard ones, as clients can
+// send any token as the method.
+const otherMethod = "OTHER"
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Define error messages for metrics.
+const (
+	ErrMsgLabelCount = "metrics: %s expects %d label values, got %d"
+)
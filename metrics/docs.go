@@ -0,0 +1,46 @@
+// Package metrics exposes the metrics of the URL shortener service in the Prometheus text format,
+// so that they can be scraped alongside the zap logs written by logmonitor.RequestLogger.
+//
+// The metrics are kept in the Default registry and served by Handler, which the service binds to
+// its own listener at METRICS_ADDR, so that they are not reachable through the public port.
+//
+// # Metrics
+//
+// The following metrics are exported:
+//   - urlshortener_http_requests_total: Requests handled, by route, method and status code.
+//   - urlshortener_http_request_duration_seconds: A histogram of the time taken to handle requests,
+//     by route, method and status code.
+//   - urlshortener_redirects_total: Visits of short links, by result: hit, miss or gone.
+//   - urlshortener_datastore_call_duration_seconds: A histogram of the latency of Datastore calls,
+//     by operation, such as Lookup, RunQuery or Commit.
+//   - urlshortener_datastore_errors_total: Failed Datastore calls, by operation and gRPC status code.
+//   - urlshortener_id_generation_retries_total: Short IDs generated again because they were taken.
+//   - urlshortener_ratelimit_rejections_total: Requests rejected by the rate limiter, by route group.
+//   - urlshortener_ratelimit_store_size: Rate limiters held in memory, once ObserveRateLimitStore is called.
+//   - urlshortener_ratelimit_store_evictions_total: Rate limiters evicted from memory, idle or least
+//     recently used.
+//   - go_*: Statistics of the Go runtime, such as goroutines, memory and garbage collection.
+//
+// Requests are labelled with their route pattern, such as "/:id", and requests that match no route
+// are labelled "unmatched", so that the number of series does not grow with the paths requested.
+// Likewise, methods outside the standard ones are labelled "OTHER".
+//
+// # Why Not client_golang
+//
+// The registry and the text exposition are implemented here rather than with
+// github.com/prometheus/client_golang, which would pull in its dependency tree (client_model,
+// common, procfs and protobuf) for the few counters and histograms the service needs. Only the
+// text format 0.0.4 is served, without OpenMetrics or protobuf negotiation. If the service ever
+// needs more than that, such as exemplars or native histograms, the metrics can be moved to
+// client_golang without changing their names or labels.
+//
+// # Example Usage
+//
+//	router.Use(metrics.Middleware())
+//
+//	mux := http.NewServeMux()
+//	mux.Handle(metrics.PathMetrics, metrics.Handler())
+//	go http.ListenAndServe(os.Getenv(metrics.METRICS_ADDR), mux)
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package metrics
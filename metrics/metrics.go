@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default is the registry of the metrics of the service, served by Handler.
+var Default = NewRegistry()
+
+// The metrics of the service. They are safe for concurrent use.
+var (
+	// HTTPRequests counts the requests handled, by route, method and status code.
+	HTTPRequests = Default.NewCounterVec("urlshortener_http_requests_total",
+		"Number of HTTP requests handled, by route, method and status code.", "route", "method", "status")
+	// HTTPRequestDuration records how long requests took to handle, by route, method and status code.
+	HTTPRequestDuration = Default.NewHistogramVec("urlshortener_http_request_duration_seconds",
+		"Time taken to handle HTTP requests, by route, method and status code.", DefaultBuckets, "route", "method", "status")
+	// Redirects counts the visits of short links, by result: hit, miss or gone.
+	Redirects = Default.NewCounterVec("urlshortener_redirects_total",
+		"Number of visits of short links, by result.", "result")
+	// DatastoreCallDuration records the latency of the calls to Datastore, by operation.
+	DatastoreCallDuration = Default.NewHistogramVec("urlshortener_datastore_call_duration_seconds",
+		"Latency of Datastore calls, by operation.", DefaultBuckets, "operation")
+	// DatastoreErrors counts the calls to Datastore that failed, by operation and gRPC status code.
+	DatastoreErrors = Default.NewCounterVec("urlshortener_datastore_errors_total",
+		"Number of failed Datastore calls, by operation and status code.", "operation", "code")
+	// IDGenerationRetries counts the short IDs that were generated again because they were taken.
+	IDGenerationRetries = Default.NewCounterVec("urlshortener_id_generation_retries_total",
+		"Number of short IDs generated again because they were already taken.")
+	// RateLimitRejections counts the requests rejected by the rate limiter, by route group.
+	RateLimitRejections = Default.NewCounterVec("urlshortener_ratelimit_rejections_total",
+		"Number of requests rejected by the rate limiter, by route group.", "group")
+)
+
+func init() {
+	Default.register(runtimeCollector{})
+}
+
+// limiterStore is the part of ratelimit.Store read by ObserveRateLimitStore.
+type limiterStore interface {
+	Len() int
+	Evictions() int64
+}
+
+// ObserveRateLimitStore exports the number of limiters held by the rate limiter store and the number it
+// has evicted, which tell whether RATE_LIMIT_STORE_SIZE is large enough for the clients seen. It must be
+// called at most once, with the store used by the service.
+func ObserveRateLimitStore(store limiterStore) {
+	Default.NewGaugeFunc("urlshortener_ratelimit_store_size",
+		"Number of rate limiters held in memory.", func() float64 { return float64(store.Len()) })
+	Default.NewCounterFunc("urlshortener_ratelimit_store_evictions_total",
+		"Number of rate limiters evicted from memory, idle or least recently used.", func() float64 { return float64(store.Evictions()) })
+}
+
+// Handler returns an HTTP handler that serves the metrics of the service.
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// Middleware returns a gin.HandlerFunc (middleware) that records the number and the latency of the
+// requests handled by the router. Requests are labelled with their route pattern, such as "/:id",
+// rather than their path, and methods outside the standard ones are labelled "OTHER", so that the
+// number of series stays bounded whatever clients send.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := methodLabel(c.Request.Method)
+		status := strconv.Itoa(c.Writer.Status())
+		HTTPRequests.Inc(route, method, status)
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(), route, method, status)
+	}
+}
+
+// methodLabel returns the method label of a request: the method itself if it is one of the standard
+// methods, or otherMethod.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return otherMethod
+}
@@ -0,0 +1,108 @@
+// Gopher Unit Testing was here
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestExpose checks the text format of counters and histograms, including the escaping of label values.
+func TestExpose(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "A test counter.", "result")
+	counter.Inc("hit")
+	counter.Add(2, `say "hi"`)
+	histogram := registry.NewHistogramVec("test_seconds", "A test histogram.", []float64{0.1, 1}, "op")
+	histogram.Observe(0.1, "Lookup")
+	histogram.Observe(0.5, "Lookup")
+	histogram.Observe(3, "Lookup")
+
+	var buf bytes.Buffer
+	if err := registry.Expose(&buf); err != nil {
+		t.Fatalf("Expose() error = %v", err)
+	}
+	want := `# HELP test_total A test counter.
+# TYPE test_total counter
+test_total{result="hit"} 1
+test_total{result="say \"hi\""} 2
+# HELP test_seconds A test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{op="Lookup",le="0.1"} 1
+test_seconds_bucket{op="Lookup",le="1"} 2
+test_seconds_bucket{op="Lookup",le="+Inf"} 3
+test_seconds_sum{op="Lookup"} 3.6
+test_seconds_count{op="Lookup"} 3
+`
+	if buf.String() != want {
+		t.Errorf("Expose() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// TestFunc checks that gauges and counters backed by a function are read when they are exposed.
+func TestFunc(t *testing.T) {
+	registry := NewRegistry()
+	size := 42
+	registry.NewGaugeFunc("test_size", "A test gauge.", func() float64 { return float64(size) })
+	registry.NewCounterFunc("test_evictions_total", "A test counter.", func() float64 { return 7 })
+
+	var buf bytes.Buffer
+	if err := registry.Expose(&buf); err != nil {
+		t.Fatalf("Expose() error = %v", err)
+	}
+	want := `# HELP test_size A test gauge.
+# TYPE test_size gauge
+test_size 42
+# HELP test_evictions_total A test counter.
+# TYPE test_evictions_total counter
+test_evictions_total 7
+`
+	if buf.String() != want {
+		t.Errorf("Expose() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// TestLabelCount checks that using the wrong number of label values panics.
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc() with a missing label value did not panic")
+		}
+	}()
+	NewRegistry().NewCounterVec("test_total", "A test counter.", "a", "b").Inc("x")
+}
+
+// TestMiddleware checks that requests are counted by route pattern, and unknown paths as unmatched.
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/:id", func(c *gin.Context) { c.Status(http.StatusFound) })
+
+	before := HTTPRequests.Value("/:id", http.MethodGet, "302")
+	beforeUnmatched := HTTPRequests.Value(unmatchedRoute, http.MethodPost, "404")
+	beforeOther := HTTPRequests.Value(unmatchedRoute, otherMethod, "404")
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc12", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/a/b/c", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("X-RANDOM-1", "/a/b/c", nil))
+
+	if got := HTTPRequests.Value("/:id", http.MethodGet, "302") - before; got != 1 {
+		t.Errorf("requests to /:id = %v, want 1", got)
+	}
+	if got := HTTPRequests.Value(unmatchedRoute, http.MethodPost, "404") - beforeUnmatched; got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := HTTPRequests.Value(unmatchedRoute, otherMethod, "404") - beforeOther; got != 1 {
+		t.Errorf("requests with an unknown method = %v, want 1", got)
+	}
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, PathMetrics, nil))
+	if body := recorder.Body.String(); !strings.Contains(body, "go_goroutines ") || !strings.Contains(body, `urlshortener_http_requests_total{route="/:id",method="GET",status="302"}`) {
+		t.Errorf("Handler() body is missing metrics:\n%s", body)
+	}
+}
@@ -0,0 +1,279 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is a metric, or a group of metrics, that can write itself in the Prometheus text format.
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds a set of metrics and exposes them in the Prometheus text format.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds a collector to the registry.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Expose writes every metric of the registry to w in the Prometheus text format, in the order
+// they were registered.
+func (r *Registry) Expose(w io.Writer) error {
+	r.mu.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an HTTP handler that serves the metrics of the registry.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.Expose(w)
+	})
+}
+
+// desc describes a metric family: its name, its help text, its type and the names of its labels.
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// writeHeader writes the HELP and TYPE lines of the metric family.
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.kind)
+}
+
+// key returns the key of the series with the given label values, after checking their number.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf(ErrMsgLabelCount, d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// CounterVec is a family of counters, one per combination of label values.
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+// counterSeries is one counter of a CounterVec.
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+// NewCounterVec creates a family of counters and registers it.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{desc: desc{name, help, "counter", labels}, series: make(map[string]*counterSeries)}
+	r.register(v)
+	return v
+}
+
+// Inc adds one to the counter with the given label values.
+func (v *CounterVec) Inc(values ...string) {
+	v.Add(1, values...)
+}
+
+// Add adds n, which must not be negative, to the counter with the given label values.
+func (v *CounterVec) Add(n float64, values ...string) {
+	key := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &counterSeries{values: slices.Clone(values)}
+		v.series[key] = s
+	}
+	s.value += n
+}
+
+// Value returns the current value of the counter with the given label values.
+func (v *CounterVec) Value(values ...string) float64 {
+	key := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, key := range sortedKeys(v.series) {
+		s := v.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.values), formatValue(s.value))
+	}
+}
+
+// HistogramVec is a family of histograms, one per combination of label values.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// histogramSeries is one histogram of a HistogramVec. Counts are per bucket, not cumulative.
+type histogramSeries struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates a family of histograms with the given bucket upper bounds, in increasing
+// order, and registers it.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{desc: desc{name, help, "histogram", labels}, buckets: buckets, series: make(map[string]*histogramSeries)}
+	r.register(v)
+	return v
+}
+
+// Observe records a value in the histogram with the given label values.
+func (v *HistogramVec) Observe(value float64, values ...string) {
+	key := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &histogramSeries{values: slices.Clone(values), counts: make([]uint64, len(v.buckets))}
+		v.series[key] = s
+	}
+	if i, _ := slices.BinarySearch(v.buckets, value); i < len(v.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	bucketLabels := append(slices.Clone(v.labels), "le")
+	for _, key := range sortedKeys(v.series) {
+		s := v.series[key]
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(bucketLabels, append(slices.Clone(s.values), formatValue(bound))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(bucketLabels, append(slices.Clone(s.values), "+Inf")), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labels, s.values), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labels, s.values), s.count)
+	}
+}
+
+// Func is a metric without labels whose value is read from a function at every scrape, for values that
+// are already kept elsewhere, such as the size of a cache.
+type Func struct {
+	desc
+	value func() float64
+}
+
+// NewGaugeFunc creates a gauge whose value is returned by fn, and registers it.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *Func {
+	f := &Func{desc: desc{name: name, help: help, kind: "gauge"}, value: fn}
+	r.register(f)
+	return f
+}
+
+// NewCounterFunc creates a counter whose value is returned by fn, and registers it.
+// The value returned by fn must never decrease.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) *Func {
+	f := &Func{desc: desc{name: name, help: help, kind: "counter"}, value: fn}
+	r.register(f)
+	return f
+}
+
+func (f *Func) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", f.name, formatValue(f.value()))
+}
+
+// sortedKeys returns the keys of the series in order, so that the output is stable between scrapes.
+func sortedKeys[T any](series map[string]T) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// formatLabels formats the label pairs of a series, such as {route="/:id",status="200"}, or nothing
+// if there are no labels.
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelValueEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatValue formats a sample value as Prometheus expects it.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Escapers for help texts and label values, as defined by the text format.
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// escapeHelp escapes a help text.
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
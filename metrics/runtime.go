@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"runtime"
+)
+
+// runtimeCollector exposes statistics of the Go runtime. The memory statistics are read once per
+// scrape, as reading them briefly stops the world.
+type runtimeCollector struct{}
+
+// runtimeGauge is one statistic of the Go runtime.
+type runtimeGauge struct {
+	name  string
+	help  string
+	kind  string
+	value func(m *runtime.MemStats) float64
+}
+
+// runtimeGauges are the statistics exposed by runtimeCollector, named as in the official Go client.
+var runtimeGauges = []runtimeGauge{
+	{"go_goroutines", "Number of goroutines that currently exist.", "gauge",
+		func(*runtime.MemStats) float64 { return float64(runtime.NumGoroutine()) }},
+	{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", "gauge",
+		func(m *runtime.MemStats) float64 { return float64(m.Alloc) }},
+	{"go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", "counter",
+		func(m *runtime.MemStats) float64 { return float64(m.TotalAlloc) }},
+	{"go_memstats_sys_bytes", "Number of bytes obtained from the system.", "gauge",
+		func(m *runtime.MemStats) float64 { return float64(m.Sys) }},
+	{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", "gauge",
+		func(m *runtime.MemStats) float64 { return float64(m.HeapInuse) }},
+	{"go_memstats_heap_objects", "Number of allocated objects.", "gauge",
+		func(m *runtime.MemStats) float64 { return float64(m.HeapObjects) }},
+	{"go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.", "gauge",
+		func(m *runtime.MemStats) float64 { return float64(m.LastGC) / 1e9 }},
+	{"go_gc_cycles_total", "Number of completed garbage collection cycles.", "counter",
+		func(m *runtime.MemStats) float64 { return float64(m.NumGC) }},
+	{"go_gc_pause_seconds_total", "Total time the world was stopped for garbage collection.", "counter",
+		func(m *runtime.MemStats) float64 { return float64(m.PauseTotalNs) / 1e9 }},
+}
+
+func (runtimeCollector) write(w *bufio.Writer) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	for _, g := range runtimeGauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", g.name, escapeHelp(g.help), g.name, g.kind, g.name, formatValue(g.value(&m)))
+	}
+	fmt.Fprintf(w, "# HELP go_info Information about the Go environment.\n# TYPE go_info gauge\ngo_info%s 1\n",
+		formatLabels([]string{"version"}, []string{runtime.Version()}))
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// Policy describes how many requests a client may make: Rate requests per second
+// on average, with bursts of up to Burst requests.
+type Policy struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// Result is the outcome of a rate limit check.
+type Result struct {
+	Allowed    bool          // Whether the request may proceed.
+	Limit      int           // The burst size of the policy.
+	Remaining  int           // The number of requests that may still be made right away.
+	RetryAfter time.Duration // How long to wait before retrying, if the request was not allowed.
+	ResetAfter time.Duration // How long until the client's full burst is available again.
+}
+
+// Backend takes one request from the budget of a client identified by key.
+// Implementations must be safe for concurrent use by multiple goroutines.
+type Backend interface {
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// LocalBackend limits requests with token bucket limiters held in a Store.
+// Limits are enforced per process, so they multiply with the number of replicas.
+type LocalBackend struct {
+	store *Store
+}
+
+// NewLocalBackend creates a LocalBackend that keeps its limiters in the given store.
+func NewLocalBackend(store *Store) *LocalBackend {
+	return &LocalBackend{store: store}
+}
+
+// Allow takes a token from the client's limiter, unless none is available. It never returns an error.
+func (b *LocalBackend) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	limiter := b.store.Get(key, func() *rate.Limiter {
+		return rate.NewLimiter(policy.Rate, policy.Burst)
+	})
+
+	now := time.Now()
+	result := Result{Limit: policy.Burst, Allowed: true}
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// Give the token back, as the request is rejected rather than delayed.
+		reservation.CancelAt(now)
+		result.Allowed = false
+		result.RetryAfter = delay
+	}
+
+	tokens := math.Max(0, limiter.TokensAt(now))
+	result.Remaining = int(tokens)
+	result.ResetAfter = time.Duration((float64(policy.Burst) - tokens) / float64(policy.Rate) * float64(time.Second))
+	return result, nil
+}
+
+// FallbackBackend uses a primary backend, typically a shared one, and switches to a fallback
+// backend when the primary fails. After a failure, the primary is left alone for a cooldown
+// period so that an unreachable server does not slow down every request.
+type FallbackBackend struct {
+	primary   Backend
+	fallback  Backend
+	cooldown  time.Duration
+	onError   func(error)
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// NewFallbackBackend creates a FallbackBackend. onError, if not nil, is called with the error
+// each time the primary backend fails and the fallback takes over.
+func NewFallbackBackend(primary Backend, fallback Backend, cooldown time.Duration, onError func(error)) *FallbackBackend {
+	return &FallbackBackend{primary: primary, fallback: fallback, cooldown: cooldown, onError: onError}
+}
+
+// Allow checks the limit with the primary backend, or with the fallback backend if the primary is unavailable.
+func (b *FallbackBackend) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	if !b.primaryDown() {
+		result, err := b.primary.Allow(ctx, key, policy)
+		if err == nil {
+			return result, nil
+		}
+		b.markPrimaryDown(err)
+	}
+	return b.fallback.Allow(ctx, key, policy)
+}
+
+// primaryDown reports whether the primary backend is cooling down after a failure.
+func (b *FallbackBackend) primaryDown() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.downUntil)
+}
+
+// markPrimaryDown starts the cooldown of the primary backend and reports the error.
+func (b *FallbackBackend) markPrimaryDown(err error) {
+	b.mu.Lock()
+	b.downUntil = time.Now().Add(b.cooldown)
+	b.mu.Unlock()
+
+	if b.onError != nil {
+		b.onError(err)
+	}
+}
+
+// NewBackendFromEnv builds the backend selected by RATE_LIMIT_BACKEND. It returns nil without an
+// error for the local backend, which callers are expected to use by default. The redis backend
+// is wrapped in a FallbackBackend that uses the local limiters in store while Redis is unavailable;
+// onError is called whenever that happens.
+func NewBackendFromEnv(ctx context.Context, store *Store, onError func(error)) (Backend, error) {
+	switch name := os.Getenv(RATE_LIMIT_BACKEND); name {
+	case "", BackendLocal:
+		return nil, nil
+	case BackendRedis:
+		client, err := newRedisClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		// An unreachable server is not fatal, as requests fall back to the local limiters.
+		if err := client.Ping(ctx).Err(); err != nil && onError != nil {
+			onError(err)
+		}
+		return NewFallbackBackend(NewRedisBackend(client, defaultKeyPrefix), NewLocalBackend(store), defaultFallbackCooldown, onError), nil
+	default:
+		return nil, fmt.Errorf(ErrMsgUnknownBackend, name)
+	}
+}
+
+// newRedisClientFromEnv creates a Redis client from RATE_LIMIT_REDIS_URL, such as
+// "redis://:password@localhost:6379/0". Timeouts that are not set in the URL default to defaultRedisTimeout.
+func newRedisClientFromEnv() (*redis.Client, error) {
+	url := os.Getenv(RATE_LIMIT_REDIS_URL)
+	if url == "" {
+		return nil, fmt.Errorf(ErrMsgMissingRedisURL)
+	}
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	for _, timeout := range []*time.Duration{&options.DialTimeout, &options.ReadTimeout, &options.WriteTimeout} {
+		if *timeout == 0 {
+			*timeout = defaultRedisTimeout
+		}
+	}
+	return redis.NewClient(options), nil
+}
@@ -0,0 +1,90 @@
+// Gopher Unit Testing was here
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testPolicy allows a burst of 3 requests, then one request every 10 seconds.
+var testPolicy = Policy{Rate: 0.1, Burst: 3}
+
+// checkBackend checks that a backend allows the burst of testPolicy and then rejects the next request.
+func checkBackend(t *testing.T, backend Backend, key string) {
+	t.Helper()
+	for i := 0; i < testPolicy.Burst; i++ {
+		result, err := backend.Allow(context.Background(), key, testPolicy)
+		if err != nil {
+			t.Fatalf("Allow returned an unexpected error: %v", err)
+		}
+		if !result.Allowed || result.Remaining != testPolicy.Burst-1-i {
+			t.Fatalf("request %d: got allowed=%v remaining=%d, want allowed=true remaining=%d",
+				i, result.Allowed, result.Remaining, testPolicy.Burst-1-i)
+		}
+	}
+
+	result, err := backend.Allow(context.Background(), key, testPolicy)
+	if err != nil {
+		t.Fatalf("Allow returned an unexpected error: %v", err)
+	}
+	if result.Allowed {
+		t.Fatal("a request over the burst was allowed")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > 10*time.Second {
+		t.Errorf("RetryAfter is %v, want between 0 and 10s", result.RetryAfter)
+	}
+	if result.ResetAfter < 29*time.Second || result.ResetAfter > 30*time.Second {
+		t.Errorf("ResetAfter is %v, want about 30s", result.ResetAfter)
+	}
+}
+
+// TestLocalBackend checks the local token bucket backend.
+func TestLocalBackend(t *testing.T) {
+	checkBackend(t, NewLocalBackend(NewStore(10, 1, time.Minute)), "local")
+}
+
+// failingBackend is a Backend that always fails and counts how often it is called.
+type failingBackend struct {
+	calls int
+}
+
+func (b *failingBackend) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	b.calls++
+	return Result{}, errors.New("connection refused")
+}
+
+// TestFallbackBackend checks that the fallback takes over while the primary is cooling down.
+func TestFallbackBackend(t *testing.T) {
+	primary := &failingBackend{}
+	var reported []error
+	backend := NewFallbackBackend(primary, NewLocalBackend(NewStore(10, 1, time.Minute)), time.Minute, func(err error) {
+		reported = append(reported, err)
+	})
+
+	checkBackend(t, backend, "fallback")
+	if primary.calls != 1 {
+		t.Errorf("the primary backend was called %d times during its cooldown, want 1", primary.calls)
+	}
+	if len(reported) != 1 {
+		t.Errorf("onError was called %d times, want 1", len(reported))
+	}
+}
+
+// TestRedisBackend checks the GCRA script against a local Redis server.
+// It is skipped unless RATE_LIMIT_TEST_REDIS_ADDR is set, for example to "localhost:6379".
+func TestRedisBackend(t *testing.T) {
+	addr := os.Getenv("RATE_LIMIT_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("RATE_LIMIT_TEST_REDIS_ADDR is not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+
+	key := "test:" + time.Now().Format(time.RFC3339Nano)
+	checkBackend(t, NewRedisBackend(client, defaultKeyPrefix), key)
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+import "time"
+
+// Define environment variables used to select the rate limit backend.
+//
+// Note: The local backend is used unless RATE_LIMIT_BACKEND is set to "redis".
+const (
+	RATE_LIMIT_BACKEND   = "RATE_LIMIT_BACKEND"
+	RATE_LIMIT_REDIS_URL = "RATE_LIMIT_REDIS_URL"
+	BackendLocal         = "local"
+	BackendRedis         = "redis"
+)
+
+// Define the defaults of the shared backend.
+const (
+	// defaultKeyPrefix namespaces the rate limit keys in a Redis database shared with other data.
+	defaultKeyPrefix = "ratelimit:"
+	// defaultRedisTimeout bounds each Redis call, as it is made on the request path.
+	defaultRedisTimeout = 250 * time.Millisecond
+	// defaultFallbackCooldown is how long the local limiter is used after the shared backend fails
+	// before the shared backend is tried again.
+	defaultFallbackCooldown = 5 * time.Second
+)
+
+// Define error messages for the rate limit backends.
+const (
+	ErrMsgUnknownBackend  = "ratelimit: unknown backend %q"
+	ErrMsgMissingRedisURL = "ratelimit: RATE_LIMIT_REDIS_URL must be set for the redis backend"
+	ErrMsgUnexpectedReply = "ratelimit: unexpected reply from the redis backend: %v"
+)
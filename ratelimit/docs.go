@@ -0,0 +1,58 @@
+// Package ratelimit provides a bounded, expiring store of token bucket rate limiters,
+// keyed by client identifiers such as IP addresses or API key names, along with backends
+// that enforce limits either per process or shared across every replica through Redis.
+//
+// A plain map of limiters grows without bound when a service is scanned or flooded with
+// spoofed addresses. The Store in this package caps its total size with least recently used
+// (LRU) eviction, drops limiters that have been idle for longer than a TTL, and splits its
+// entries across shards, each with its own lock, to reduce contention between requests.
+//
+// # Types
+//
+//   - Store: A sharded LRU cache of *rate.Limiter values with idle expiry.
+//   - Policy: The rate and burst allowed to each client.
+//   - Result: The outcome of a check, with the values needed for the RateLimit-* and Retry-After headers.
+//   - Backend: The interface implemented by every backend.
+//   - LocalBackend: Enforces limits with the limiters of a Store, per process.
+//   - RedisBackend: Enforces limits shared by every replica with the generic cell rate algorithm (GCRA),
+//     run atomically as a Lua script against the Redis server clock.
+//   - FallbackBackend: Uses a primary backend and switches to a fallback for a cooldown period whenever
+//     the primary fails, so that an unavailable Redis degrades to local limits instead of failing requests.
+//
+// # Functions
+//
+//   - NewStore: Creates a Store with a total capacity, a number of shards and an idle TTL.
+//   - NewLocalBackend, NewRedisBackend, NewFallbackBackend: Create the backends.
+//   - NewBackendFromEnv: Builds the backend selected by RATE_LIMIT_BACKEND ("local" or "redis"), where
+//     the Redis server is given by RATE_LIMIT_REDIS_URL and falls back to the local limiters of a Store.
+//
+// # Methods
+//
+//   - Get: Returns the limiter for a key, creating it with the given constructor if needed.
+//   - Len: Returns the number of limiters currently held.
+//   - Evictions: Returns the number of limiters evicted so far, because they were idle or the store was full.
+//   - Sweep: Removes every idle limiter. Idle limiters are also removed lazily as new ones are added.
+//   - Publish: Exposes the size and eviction count of the store through the expvar package.
+//
+// Evicting an idle limiter is harmless as long as the TTL is longer than the time a limiter
+// needs to refill its burst, because a full limiter behaves exactly like a new one.
+//
+// # Example Usage
+//
+//	store := ratelimit.NewStore(100000, 32, 10*time.Minute)
+//	store.Publish("ratelimit_store")
+//
+//	limiter := store.Get(clientIP, func() *rate.Limiter {
+//	    return rate.NewLimiter(1, 10)
+//	})
+//	if !limiter.Allow() {
+//	    // Reject the request.
+//	}
+//
+// The RedisBackend can be tested against a local server by setting RATE_LIMIT_TEST_REDIS_ADDR:
+//
+//	docker run --rm -p 6379:6379 redis:7
+//	RATE_LIMIT_TEST_REDIS_ADDR=localhost:6379 go test ./ratelimit
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package ratelimit
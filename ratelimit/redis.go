@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript implements the generic cell rate algorithm (GCRA) atomically in Redis.
+// It stores a single "theoretical arrival time" (TAT) per client, which expires once the
+// client's full burst is available again. The Redis server clock is used, so replicas with
+// skewed clocks still share one consistent limit.
+//
+// KEYS[1] is the key of the client, ARGV[1] the burst and ARGV[2] the rate per second.
+// It returns {allowed, remaining, retry after in ms, reset after in ms}.
+var gcraScript = redis.NewScript(`
+if redis.replicate_commands then redis.replicate_commands() end
+
+local burst = tonumber(ARGV[1])
+local emission_interval = 1 / tonumber(ARGV[2])
+local burst_offset = emission_interval * burst
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + emission_interval
+local diff = now - (new_tat - burst_offset)
+if diff < 0 then
+  return {0, 0, math.ceil(-diff * 1000), math.ceil((tat - now) * 1000)}
+end
+
+local reset_after = new_tat - now
+redis.call("SET", KEYS[1], string.format("%.6f", new_tat), "PX", math.ceil(reset_after * 1000))
+return {1, math.floor(diff / emission_interval), 0, math.ceil(reset_after * 1000)}
+`)
+
+// RedisBackend enforces limits shared by every replica of the service, using the GCRA in Redis.
+type RedisBackend struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisBackend creates a RedisBackend that stores its state under keys starting with prefix.
+func NewRedisBackend(client redis.Scripter, prefix string) *RedisBackend {
+	return &RedisBackend{client: client, prefix: prefix}
+}
+
+// Allow takes one request from the client's budget in Redis.
+func (b *RedisBackend) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	values, err := gcraScript.Run(ctx, b.client, []string{b.prefix + key}, policy.Burst, float64(policy.Rate)).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf(ErrMsgUnexpectedReply, values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      policy.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
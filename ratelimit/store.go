@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"container/list"
+	"expvar"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Store holds rate limiters keyed by client identifiers. Its size is bounded by LRU eviction,
+// and limiters that have been idle for longer than the TTL are dropped.
+// It is safe for concurrent use by multiple goroutines.
+type Store struct {
+	shards    []*shard
+	ttl       time.Duration
+	evictions atomic.Int64
+	now       func() time.Time
+}
+
+// shard is one independently locked part of a Store.
+type shard struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // Most recently used entries are at the front.
+}
+
+// entry is a limiter along with the time it was last used.
+type entry struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewStore creates a Store that holds up to capacity limiters split across the given number of shards.
+// Limiters that are not used for ttl are evicted; a ttl of zero disables idle expiry.
+func NewStore(capacity int, shards int, ttl time.Duration) *Store {
+	shards = max(shards, 1)
+	perShard := max((capacity+shards-1)/shards, 1)
+
+	s := &Store{shards: make([]*shard, shards), ttl: ttl, now: time.Now}
+	for i := range s.shards {
+		s.shards[i] = &shard{
+			capacity: perShard,
+			items:    make(map[string]*list.Element),
+			order:    list.New(),
+		}
+	}
+	return s
+}
+
+// Get returns the limiter for the key, creating it with newLimiter if the key is unknown
+// or its previous limiter has been evicted.
+func (s *Store) Get(key string, newLimiter func() *rate.Limiter) *rate.Limiter {
+	sh := s.shardFor(key)
+	now := s.now()
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if element, ok := sh.items[key]; ok && !s.expired(element, now) {
+		e := element.Value.(*entry)
+		e.lastSeen = now
+		sh.order.MoveToFront(element)
+		return e.limiter
+	}
+
+	// Expired entries, including the one for this key, are at the back of the list.
+	s.evictIdle(sh, now)
+	if sh.order.Len() >= sh.capacity {
+		s.remove(sh, sh.order.Back())
+	}
+
+	e := &entry{key: key, limiter: newLimiter(), lastSeen: now}
+	sh.items[key] = sh.order.PushFront(e)
+	return e.limiter
+}
+
+// Len returns the number of limiters currently held by the store.
+func (s *Store) Len() int {
+	total := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		total += sh.order.Len()
+		sh.mu.Unlock()
+	}
+	return total
+}
+
+// Evictions returns the number of limiters evicted so far.
+func (s *Store) Evictions() int64 {
+	return s.evictions.Load()
+}
+
+// Sweep removes every limiter that has been idle for longer than the TTL and returns how many were removed.
+// Calling it periodically releases memory even when no new clients arrive.
+func (s *Store) Sweep() int {
+	now := s.now()
+	removed := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		removed += s.evictIdle(sh, now)
+		sh.mu.Unlock()
+	}
+	return removed
+}
+
+// Publish exposes the size and eviction count of the store as an expvar variable with the given name.
+// It panics if the name is already in use, like expvar.Publish.
+func (s *Store) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return map[string]int64{
+			"size":      int64(s.Len()),
+			"evictions": s.Evictions(),
+		}
+	}))
+}
+
+// shardFor returns the shard that holds the key.
+func (s *Store) shardFor(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// evictIdle removes idle entries from the back of the shard, where the least recently used entries are.
+// The shard must be locked by the caller.
+func (s *Store) evictIdle(sh *shard, now time.Time) int {
+	removed := 0
+	for element := sh.order.Back(); element != nil && s.expired(element, now); element = sh.order.Back() {
+		s.remove(sh, element)
+		removed++
+	}
+	return removed
+}
+
+// expired reports whether the entry has been idle for longer than the TTL.
+func (s *Store) expired(element *list.Element, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(element.Value.(*entry).lastSeen) > s.ttl
+}
+
+// remove deletes an entry from the shard and counts the eviction.
+// The shard must be locked by the caller.
+func (s *Store) remove(sh *shard, element *list.Element) {
+	sh.order.Remove(element)
+	delete(sh.items, element.Value.(*entry).key)
+	s.evictions.Add(1)
+}
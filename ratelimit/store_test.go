@@ -0,0 +1,89 @@
+// Gopher Unit Testing was here
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// newTestLimiter creates the limiter used by the tests.
+func newTestLimiter() *rate.Limiter {
+	return rate.NewLimiter(1, 1)
+}
+
+// TestStore_Get checks that the same limiter is returned for the same key.
+func TestStore_Get(t *testing.T) {
+	store := NewStore(10, 2, time.Minute)
+
+	first := store.Get("192.0.2.1", newTestLimiter)
+	if store.Get("192.0.2.1", newTestLimiter) != first {
+		t.Error("Get returned a different limiter for the same key")
+	}
+	if store.Get("192.0.2.2", newTestLimiter) == first {
+		t.Error("Get returned the same limiter for different keys")
+	}
+	if got := store.Len(); got != 2 {
+		t.Errorf("Len returned %d, want 2", got)
+	}
+}
+
+// TestStore_Capacity checks that the least recently used limiter is evicted when the store is full.
+func TestStore_Capacity(t *testing.T) {
+	store := NewStore(3, 1, 0)
+
+	oldest := store.Get("a", newTestLimiter)
+	store.Get("b", newTestLimiter)
+	store.Get("c", newTestLimiter)
+	store.Get("b", newTestLimiter) // "a" is now the least recently used key.
+	store.Get("d", newTestLimiter)
+
+	if got := store.Len(); got != 3 {
+		t.Errorf("Len returned %d, want 3", got)
+	}
+	if store.Get("a", newTestLimiter) == oldest {
+		t.Error("the least recently used limiter was not evicted")
+	}
+	if got := store.Evictions(); got != 2 {
+		t.Errorf("Evictions returned %d, want 2", got)
+	}
+}
+
+// TestStore_TTL checks that idle limiters are evicted lazily and by Sweep.
+func TestStore_TTL(t *testing.T) {
+	now := time.Now()
+	store := NewStore(100, 1, time.Minute)
+	store.now = func() time.Time { return now }
+
+	idle := store.Get("idle", newTestLimiter)
+	store.Get("active", newTestLimiter)
+
+	now = now.Add(45 * time.Second)
+	store.Get("active", newTestLimiter)
+
+	now = now.Add(30 * time.Second)
+	if store.Get("idle", newTestLimiter) == idle {
+		t.Error("an idle limiter was returned after its TTL")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if removed := store.Sweep(); removed != 2 {
+		t.Errorf("Sweep removed %d limiters, want 2", removed)
+	}
+	if got := store.Len(); got != 0 {
+		t.Errorf("Len returned %d after Sweep, want 0", got)
+	}
+}
+
+// TestStore_Shards checks that the capacity is spread across shards and bounds the total size.
+func TestStore_Shards(t *testing.T) {
+	store := NewStore(64, 8, 0)
+	for i := 0; i < 1000; i++ {
+		store.Get(fmt.Sprintf("198.51.100.%d", i), newTestLimiter)
+	}
+	if got := store.Len(); got > 64 {
+		t.Errorf("Len returned %d, want at most 64", got)
+	}
+}
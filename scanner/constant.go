@@ -0,0 +1,36 @@
+package scanner
+
+import "time"
+
+// Define environment variables used to configure URL scanning.
+//
+// Note: Scanning is enabled only when URL_SCANNER_HASH_FILE or URL_SCANNER_WEBHOOK_URL is set.
+const (
+	URL_SCANNER_HASH_FILE      = "URL_SCANNER_HASH_FILE"
+	URL_SCANNER_WEBHOOK_URL    = "URL_SCANNER_WEBHOOK_URL"
+	URL_SCANNER_WEBHOOK_SECRET = "URL_SCANNER_WEBHOOK_SECRET"
+	URL_SCANNER_TIMEOUT        = "URL_SCANNER_TIMEOUT"
+	URL_SCANNER_FAIL_OPEN      = "URL_SCANNER_FAIL_OPEN"
+	defaultTimeout             = 3 * time.Second
+)
+
+// Define the limits of the hash-prefix list format.
+const (
+	minPrefixBytes = 4
+	maxPrefixBytes = 32
+	// maxHostSuffixes and maxPathPrefixes bound the number of expressions checked for each URL,
+	// following the Safe Browsing lookup rules.
+	maxHostSuffixes = 4
+	maxPathPrefixes = 4
+	// maxWebhookResponse bounds the size of the verdict read from a webhook.
+	maxWebhookResponse = 64 << 10
+)
+
+// Define error messages for the scanners.
+const (
+	ErrMsgInvalidHashLine  = "scanner: line %d: %s"
+	ErrMsgInvalidPrefix    = "invalid hash prefix %q"
+	ErrMsgUnknownVerdict   = "unknown verdict %q"
+	ErrMsgUnexpectedStatus = "scanner: unexpected webhook status %d"
+	ErrMsgInvalidFailOpen  = "scanner: invalid URL_SCANNER_FAIL_OPEN %q"
+)
@@ -0,0 +1,31 @@
+// Package scanner checks destination URLs for malware, phishing and other abuse before the URL
+// shortener service stores them. Scanners are pluggable: any type implementing URLScanner can be
+// installed in the handlers package with handlers.SetURLScanner.
+//
+// # Verdicts
+//
+//   - Allow: The link is created and redirects normally.
+//   - Quarantine: The link is created, but visitors are shown a warning page instead of being
+//     redirected, and have to confirm before they continue to the destination.
+//   - Deny: The link is rejected.
+//
+// # Scanners
+//
+//   - HashPrefixScanner: Matches URLs against a local list of SHA-256 hash prefixes, in the style
+//     of the Safe Browsing lists, loaded from a file.
+//   - WebhookScanner: Asks an external HTTP service for a verdict.
+//   - MultiScanner: Combines scanners; the most severe verdict wins.
+//   - FailOpenScanner: Allows URLs when the wrapped scanner fails, instead of rejecting the request.
+//
+// # Example Usage
+//
+//	s, err := scanner.NewFromEnv(nil)
+//	if err != nil {
+//	    // Handle the error.
+//	}
+//	if s != nil {
+//	    handlers.SetURLScanner(s)
+//	}
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package scanner
@@ -0,0 +1,163 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// HashPrefixScanner matches URLs against a local list of SHA-256 hash prefixes, in the style of
+// the Safe Browsing lists. Each URL is expanded into host suffix and path prefix expressions such
+// as "evil.example/" or "www.evil.example/phish/", and each expression is hashed and looked up.
+//
+// Unlike the Safe Browsing API, matches are not confirmed against full hashes, so lists should use
+// full 32-byte hashes, or prefixes long enough to make false positives negligible.
+type HashPrefixScanner struct {
+	prefixes map[int]map[string]Verdict // Hex-encoded prefixes indexed by their length in bytes.
+}
+
+// LoadHashPrefixFile reads a hash-prefix list from a file. See ParseHashPrefixes for the format.
+func LoadHashPrefixFile(path string) (*HashPrefixScanner, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseHashPrefixes(f)
+}
+
+// ParseHashPrefixes reads a hash-prefix list. Each line holds a hex-encoded prefix of 4 to 32 bytes
+// of the SHA-256 hash of an expression, optionally followed by a verdict ("deny" by default, or
+// "quarantine"). Empty lines and lines starting with "#" are ignored. For example:
+//
+//	# sha256("evil.example/")
+//	f001957c833da35384097567d684bbfdccfd3c0aea51b672d740b5858f6e9aa5 deny
+//	9f86d081 quarantine
+func ParseHashPrefixes(r io.Reader) (*HashPrefixScanner, error) {
+	s := &HashPrefixScanner{prefixes: make(map[int]map[string]Verdict)}
+	lines := bufio.NewScanner(r)
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := s.addLine(line); err != nil {
+			return nil, fmt.Errorf(ErrMsgInvalidHashLine, n, err)
+		}
+	}
+	return s, lines.Err()
+}
+
+// addLine parses one entry of the list and adds it.
+func (s *HashPrefixScanner) addLine(line string) error {
+	fields := strings.Fields(line)
+	prefix := strings.ToLower(fields[0])
+	raw, err := hex.DecodeString(prefix)
+	if err != nil || len(raw) < minPrefixBytes || len(raw) > maxPrefixBytes {
+		return fmt.Errorf(ErrMsgInvalidPrefix, fields[0])
+	}
+
+	verdict := Deny
+	if len(fields) > 1 {
+		if verdict, err = ParseVerdict(fields[1]); err != nil {
+			return err
+		}
+	}
+	if s.prefixes[len(raw)] == nil {
+		s.prefixes[len(raw)] = make(map[string]Verdict)
+	}
+	s.prefixes[len(raw)][prefix] = verdict
+	return nil
+}
+
+// Scan looks up every expression of the URL and returns the most severe verdict found.
+func (s *HashPrefixScanner) Scan(ctx context.Context, rawURL string) (Result, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Verdict: Allow}
+	for _, expression := range Expressions(u) {
+		if verdict, ok := s.lookup(expression); ok && severity[verdict] > severity[result.Verdict] {
+			result = Result{Verdict: verdict, Reason: "matched " + expression}
+		}
+	}
+	return result, nil
+}
+
+// lookup hashes the expression and checks it against the prefixes of every length in the list.
+// The hash may match prefixes of several lengths with different verdicts, so the most severe one is
+// returned rather than the first found, which would depend on the order of map iteration.
+func (s *HashPrefixScanner) lookup(expression string) (Verdict, bool) {
+	sum := sha256.Sum256([]byte(expression))
+	hash := hex.EncodeToString(sum[:])
+	worst, found := Allow, false
+	for length, prefixes := range s.prefixes {
+		if verdict, ok := prefixes[hash[:length*2]]; ok && (!found || severity[verdict] > severity[worst]) {
+			worst, found = verdict, true
+		}
+	}
+	return worst, found
+}
+
+// Expressions returns the host suffix and path prefix combinations of the URL that are looked up,
+// following the Safe Browsing rules: the exact host and up to four suffixes of it, combined with
+// the exact path with and without the query, and up to four leading path prefixes.
+func Expressions(u *url.URL) []string {
+	var expressions []string
+	for _, host := range hostSuffixes(strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")) {
+		for _, path := range pathPrefixes(u) {
+			expressions = append(expressions, host+path)
+		}
+	}
+	return expressions
+}
+
+// hostSuffixes returns the exact host and, unless it is an IP address, up to four hosts formed by
+// taking the last five components and successively removing the leading one. The top-level
+// domain alone is never used.
+func hostSuffixes(host string) []string {
+	suffixes := []string{host}
+	if net.ParseIP(host) != nil {
+		return suffixes
+	}
+	labels := strings.Split(host, ".")
+	start := max(len(labels)-5, 1)
+	for i := start; i < len(labels)-1 && len(suffixes) <= maxHostSuffixes; i++ {
+		suffixes = append(suffixes, strings.Join(labels[i:], "."))
+	}
+	return suffixes
+}
+
+// pathPrefixes returns the exact path with the query, the exact path without it, and the root
+// followed by up to three leading directories.
+func pathPrefixes(u *url.URL) []string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	var prefixes []string
+	if u.RawQuery != "" {
+		prefixes = append(prefixes, path+"?"+u.RawQuery)
+	}
+	prefixes = append(prefixes, path)
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	prefix := "/"
+	for i := 0; i < len(segments) && i < maxPathPrefixes; i++ {
+		if prefix != path {
+			prefixes = append(prefixes, prefix)
+		}
+		prefix += segments[i] + "/"
+	}
+	return prefixes
+}
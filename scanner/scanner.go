@@ -0,0 +1,137 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Verdict is the decision of a scanner about a destination URL.
+type Verdict string
+
+// Define the verdicts a scanner can return, from the least to the most severe.
+const (
+	// Allow lets the link be created and followed normally.
+	Allow Verdict = "allow"
+	// Quarantine lets the link be created, but visitors see a warning instead of being redirected.
+	Quarantine Verdict = "quarantine"
+	// Deny rejects the link.
+	Deny Verdict = "deny"
+)
+
+// severity orders the verdicts so that the most severe one wins when several scanners disagree.
+var severity = map[Verdict]int{Allow: 0, Quarantine: 1, Deny: 2}
+
+// ParseVerdict converts a string into a Verdict.
+func ParseVerdict(s string) (Verdict, error) {
+	verdict := Verdict(s)
+	if _, ok := severity[verdict]; !ok {
+		return "", fmt.Errorf(ErrMsgUnknownVerdict, s)
+	}
+	return verdict, nil
+}
+
+// Result is the outcome of scanning a URL.
+type Result struct {
+	Verdict Verdict `json:"verdict"`
+	Reason  string  `json:"reason,omitempty"`
+}
+
+// URLScanner checks destination URLs for malware, phishing or other abuse before they are stored.
+// Implementations must be safe for concurrent use by multiple goroutines.
+type URLScanner interface {
+	Scan(ctx context.Context, rawURL string) (Result, error)
+}
+
+// MultiScanner runs several scanners and returns the most severe verdict.
+type MultiScanner []URLScanner
+
+// Scan runs every scanner in order, stopping early at the first Deny verdict or error.
+func (m MultiScanner) Scan(ctx context.Context, rawURL string) (Result, error) {
+	worst := Result{Verdict: Allow}
+	for _, s := range m {
+		result, err := s.Scan(ctx, rawURL)
+		if err != nil {
+			return Result{}, err
+		}
+		if severity[result.Verdict] > severity[worst.Verdict] {
+			worst = result
+		}
+		if worst.Verdict == Deny {
+			break
+		}
+	}
+	return worst, nil
+}
+
+// FailOpenScanner allows URLs that the wrapped scanner fails to scan, instead of returning the error.
+// It trades safety for availability, for example when a webhook scanner is down.
+type FailOpenScanner struct {
+	Scanner URLScanner
+	OnError func(error) // Called with each error that is ignored, if not nil.
+}
+
+// Scan returns the verdict of the wrapped scanner, or Allow if it fails.
+func (f *FailOpenScanner) Scan(ctx context.Context, rawURL string) (Result, error) {
+	result, err := f.Scanner.Scan(ctx, rawURL)
+	if err != nil {
+		if f.OnError != nil {
+			f.OnError(err)
+		}
+		return Result{Verdict: Allow}, nil
+	}
+	return result, nil
+}
+
+// NewFromEnv builds the scanner configured by the URL_SCANNER_* environment variables.
+// It returns nil without an error if no scanner is configured. When both a hash-prefix file and
+// a webhook are configured, the local list is consulted first. onError is only used with
+// URL_SCANNER_FAIL_OPEN=true, to report the errors that are ignored.
+func NewFromEnv(onError func(error)) (URLScanner, error) {
+	var scanners MultiScanner
+	if file := os.Getenv(URL_SCANNER_HASH_FILE); file != "" {
+		s, err := LoadHashPrefixFile(file)
+		if err != nil {
+			return nil, err
+		}
+		scanners = append(scanners, s)
+	}
+	if endpoint := os.Getenv(URL_SCANNER_WEBHOOK_URL); endpoint != "" {
+		timeout, err := parseTimeout()
+		if err != nil {
+			return nil, err
+		}
+		scanners = append(scanners, NewWebhookScanner(endpoint, os.Getenv(URL_SCANNER_WEBHOOK_SECRET), timeout))
+	}
+	if len(scanners) == 0 {
+		return nil, nil
+	}
+	return wrapFailOpen(scanners, onError)
+}
+
+// wrapFailOpen wraps the scanner in a FailOpenScanner if URL_SCANNER_FAIL_OPEN is true.
+func wrapFailOpen(s URLScanner, onError func(error)) (URLScanner, error) {
+	value := os.Getenv(URL_SCANNER_FAIL_OPEN)
+	if value == "" {
+		return s, nil
+	}
+	failOpen, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf(ErrMsgInvalidFailOpen, value)
+	}
+	if failOpen {
+		return &FailOpenScanner{Scanner: s, OnError: onError}, nil
+	}
+	return s, nil
+}
+
+// parseTimeout returns the webhook timeout from URL_SCANNER_TIMEOUT, or the default.
+func parseTimeout() (time.Duration, error) {
+	value := os.Getenv(URL_SCANNER_TIMEOUT)
+	if value == "" {
+		return defaultTimeout, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,150 @@
+// Gopher Unit Testing was here
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hashOf returns the hex-encoded SHA-256 hash of the expression.
+func hashOf(expression string) string {
+	sum := sha256.Sum256([]byte(expression))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestExpressions checks the host suffix and path prefix expansion.
+func TestExpressions(t *testing.T) {
+	u, _ := url.Parse("http://a.b.c/1/2.html?param=1")
+	want := []string{
+		"a.b.c/1/2.html?param=1", "a.b.c/1/2.html", "a.b.c/", "a.b.c/1/",
+		"b.c/1/2.html?param=1", "b.c/1/2.html", "b.c/", "b.c/1/",
+	}
+	if got := Expressions(u); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expressions() = %q, want %q", got, want)
+	}
+}
+
+// TestHashPrefixScanner checks that full hashes and short prefixes match with their verdicts.
+func TestHashPrefixScanner(t *testing.T) {
+	list := "# Test list\n\n" +
+		hashOf("evil.example/") + "\n" +
+		hashOf("shady.example/downloads/")[:8] + " quarantine\n"
+	s, err := ParseHashPrefixes(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("ParseHashPrefixes() error = %v", err)
+	}
+
+	testCases := map[string]Verdict{
+		"https://go.dev/doc":                         Allow,
+		"https://evil.example/":                      Deny,
+		"https://login.EVIL.example/account?next=/":  Deny,
+		"https://shady.example/downloads/setup.exe":  Quarantine,
+		"https://shady.example/other/downloads/file": Allow,
+	}
+	for rawURL, want := range testCases {
+		result, err := s.Scan(context.Background(), rawURL)
+		if err != nil {
+			t.Fatalf("Scan(%q) error = %v", rawURL, err)
+		}
+		if result.Verdict != want {
+			t.Errorf("Scan(%q) = %q, want %q", rawURL, result.Verdict, want)
+		}
+	}
+}
+
+// TestHashPrefixScanner_MostSevereLength checks that when an expression matches prefixes of several
+// lengths, the most severe verdict wins whatever the order in which the lengths are checked.
+func TestHashPrefixScanner_MostSevereLength(t *testing.T) {
+	hash := hashOf("evil.example/")
+	list := hash[:8] + " quarantine\n" + hash[:16] + " quarantine\n" + hash[:24] + "\n" + hash[:32] + " quarantine\n"
+	s, err := ParseHashPrefixes(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("ParseHashPrefixes() error = %v", err)
+	}
+
+	// Map iteration order is random, so repeat the lookup to catch an order-dependent result.
+	for i := 0; i < 50; i++ {
+		if verdict, ok := s.lookup("evil.example/"); !ok || verdict != Deny {
+			t.Fatalf("lookup() = %q, %v, want %q, true", verdict, ok, Deny)
+		}
+	}
+}
+
+// TestParseHashPrefixes_Invalid checks that malformed lines are rejected with their line number.
+func TestParseHashPrefixes_Invalid(t *testing.T) {
+	for _, list := range []string{"abc", "zzzzzzzz", "9f86d081 block", strings.Repeat("ab", 33)} {
+		if _, err := ParseHashPrefixes(strings.NewReader("# comment\n" + list)); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("ParseHashPrefixes(%q) error = %v, want an error on line 2", list, err)
+		}
+	}
+}
+
+// TestWebhookScanner checks the request sent to the webhook and the handling of its responses.
+func TestWebhookScanner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct{ URL string }
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case strings.Contains(body.URL, "phish"):
+			w.Write([]byte(`{"verdict":"deny","reason":"phishing"}`))
+		case strings.Contains(body.URL, "bogus"):
+			w.Write([]byte(`{"verdict":"maybe"}`))
+		default:
+			w.Write([]byte(`{"verdict":"allow"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewWebhookScanner(server.URL, "s3cret", time.Second)
+	if result, err := s.Scan(context.Background(), "https://go.dev/"); err != nil || result.Verdict != Allow {
+		t.Errorf("Scan(allowed) = %+v, %v", result, err)
+	}
+	if result, err := s.Scan(context.Background(), "https://phish.example/"); err != nil || result != (Result{Deny, "phishing"}) {
+		t.Errorf("Scan(phishing) = %+v, %v", result, err)
+	}
+	if _, err := s.Scan(context.Background(), "https://bogus.example/"); err == nil {
+		t.Error("Scan(bogus) succeeded, want an unknown verdict error")
+	}
+	if _, err := NewWebhookScanner(server.URL, "wrong", time.Second).Scan(context.Background(), "https://go.dev/"); err == nil {
+		t.Error("Scan() with a wrong secret succeeded, want a status error")
+	}
+}
+
+// scannerFunc adapts a function to the URLScanner interface.
+type scannerFunc func(ctx context.Context, rawURL string) (Result, error)
+
+func (f scannerFunc) Scan(ctx context.Context, rawURL string) (Result, error) { return f(ctx, rawURL) }
+
+// TestMultiScanner checks that the most severe verdict wins, and that FailOpenScanner hides errors.
+func TestMultiScanner(t *testing.T) {
+	quarantine := scannerFunc(func(context.Context, string) (Result, error) { return Result{Verdict: Quarantine}, nil })
+	allow := scannerFunc(func(context.Context, string) (Result, error) { return Result{Verdict: Allow}, nil })
+	failing := scannerFunc(func(context.Context, string) (Result, error) { return Result{}, errors.New("down") })
+
+	if result, _ := (MultiScanner{allow, quarantine, allow}).Scan(context.Background(), ""); result.Verdict != Quarantine {
+		t.Errorf("MultiScanner verdict = %q, want %q", result.Verdict, Quarantine)
+	}
+	if _, err := (MultiScanner{allow, failing}).Scan(context.Background(), ""); err == nil {
+		t.Error("MultiScanner with a failing scanner succeeded, want an error")
+	}
+
+	var reported error
+	failOpen := &FailOpenScanner{Scanner: failing, OnError: func(err error) { reported = err }}
+	if result, err := failOpen.Scan(context.Background(), ""); err != nil || result.Verdict != Allow || reported == nil {
+		t.Errorf("FailOpenScanner = %+v, %v, reported %v", result, err, reported)
+	}
+}
@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookScanner asks an external HTTP service for a verdict. It sends a POST request with the
+// JSON body {"url": "<destination>"} and expects a 2xx response with the JSON body
+// {"verdict": "allow|deny|quarantine", "reason": "..."}. Any other response is an error.
+type WebhookScanner struct {
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+// NewWebhookScanner creates a scanner that calls the endpoint. If secret is not empty, it is sent as
+// a bearer token in the Authorization header. timeout bounds each call.
+func NewWebhookScanner(endpoint, secret string, timeout time.Duration) *WebhookScanner {
+	return &WebhookScanner{
+		endpoint: endpoint,
+		secret:   secret,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Scan sends the URL to the webhook and returns its verdict.
+func (w *WebhookScanner) Scan(ctx context.Context, rawURL string) (Result, error) {
+	body, err := json.Marshal(map[string]string{"url": rawURL})
+	if err != nil {
+		return Result{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+w.secret)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	return decodeVerdict(resp)
+}
+
+// decodeVerdict reads the verdict from a webhook response.
+func decodeVerdict(resp *http.Response) (Result, error) {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Result{}, fmt.Errorf(ErrMsgUnexpectedStatus, resp.StatusCode)
+	}
+
+	var result Result
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxWebhookResponse)).Decode(&result); err != nil {
+		return Result{}, err
+	}
+	if _, err := ParseVerdict(string(result.Verdict)); err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
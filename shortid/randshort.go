@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/H0llyW00dzZ/go-urlshortner/datastore"
+	"github.com/H0llyW00dzZ/go-urlshortner/metrics"
 )
 
 // Generate creates a cryptographically secure, URL-friendly short ID of a specified length.
@@ -40,6 +41,7 @@ func GenerateUniqueDataStore(ctx context.Context, client *datastore.Client, leng
 			return "", fmt.Errorf("error checking ID uniqueness: %w", err)
 		}
 		// The ID exists, so it is not unique, try generating another one
+		metrics.IDGenerationRetries.Inc()
 	}
 
 	return "", errors.New("failed to generate a unique short ID after several attempts")
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// levelDebugSignal and levelResetSignal are not available on this platform, where log levels can
+// only be changed through the admin endpoint.
+var (
+	levelDebugSignal os.Signal
+	levelResetSignal os.Signal
+)
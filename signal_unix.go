@@ -0,0 +1,15 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// levelDebugSignal sets every package to the debug log level for logmonitor.DefaultLevelDuration,
+// and levelResetSignal sets every package back to the configured log level.
+var (
+	levelDebugSignal os.Signal = syscall.SIGUSR1
+	levelResetSignal os.Signal = syscall.SIGHUP
+)
@@ -0,0 +1,29 @@
+package tracing
+
+// Define environment variables used to configure tracing.
+//
+// Note: Tracing is enabled only when TRACING_EXPORTER is set. The OTLP exporter is configured with the
+// standard OTEL_EXPORTER_OTLP_* variables, such as OTEL_EXPORTER_OTLP_ENDPOINT, and the service name can
+// be overridden with OTEL_SERVICE_NAME.
+const (
+	TRACING_EXPORTER     = "TRACING_EXPORTER"
+	TRACING_SAMPLE_RATIO = "TRACING_SAMPLE_RATIO"
+	defaultSampleRatio   = 1.0
+)
+
+// Define the exporters that spans can be sent to.
+const (
+	// ExporterOTLP sends spans over OTLP/HTTP, for example to a local OpenTelemetry collector.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON to the standard output, which is useful for tests and development.
+	ExporterStdout = "stdout"
+)
+
+// ServiceName is the default name of the service in the resource attached to every span.
+const ServiceName = "go-urlshortner"
+
+// Define error messages for tracing.
+const (
+	ErrMsgUnknownExporter = "tracing: unknown exporter %q, expected otlp or stdout"
+	ErrMsgInvalidRatio    = "tracing: invalid %s %q, expected a number between 0 and 1"
+)
@@ -0,0 +1,39 @@
+// Package tracing configures OpenTelemetry tracing for the URL shortener service, so that a request can
+// be followed from the Gin router down to the Datastore calls it made.
+//
+// Each incoming request gets a span from the otelgin middleware, which continues the trace of the caller
+// when it sends a W3C "traceparent" header. The datastore package adds a child span for GetURL, SaveURL,
+// InsertURL, MutateURL (which UpdateURL and SoftDeleteURL run in) and DeleteURL. The trace and span IDs
+// are also added to the log lines written with logmonitor.CreateLogFieldsContext and by
+// logmonitor.RequestLogger, so logs and traces can be matched.
+//
+// # Configuration
+//
+// Tracing is enabled by setting TRACING_EXPORTER:
+//   - otlp: Sends spans over OTLP/HTTP. The endpoint and headers are read from the standard
+//     OTEL_EXPORTER_OTLP_* variables, for example OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318
+//     for a local collector.
+//   - stdout: Writes spans as JSON to the standard output, or to Config.Writer, for tests and development.
+//
+// TRACING_SAMPLE_RATIO sets the fraction of new traces that are recorded, 1 by default. Traces started by
+// a caller follow the sampling decision of the caller. The service is named "go-urlshortner" unless
+// OTEL_SERVICE_NAME says otherwise.
+//
+// # Example Usage
+//
+//	otel.SetTextMapPropagator(tracing.Propagator)
+//	config, err := tracing.NewConfigFromEnv()
+//	if err != nil {
+//	    // Handle the error.
+//	}
+//	if config != nil {
+//	    provider, err := tracing.NewProvider(ctx, config)
+//	    if err != nil {
+//	        // Handle the error.
+//	    }
+//	    otel.SetTracerProvider(provider)
+//	    defer provider.Shutdown(context.Background())
+//	}
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package tracing
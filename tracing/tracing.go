@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// Propagator reads and writes the W3C trace context and baggage headers, so that the spans of a request
+// join the trace of the caller.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Config holds the configuration of tracing.
+type Config struct {
+	Exporter    string    // The exporter spans are sent to, ExporterOTLP or ExporterStdout.
+	SampleRatio float64   // The fraction of new traces that are recorded; traces started by a caller follow its decision.
+	Writer      io.Writer // Where ExporterStdout writes spans; the standard output if nil.
+}
+
+// NewConfigFromEnv builds a Config from the TRACING_* environment variables.
+// It returns nil if TRACING_EXPORTER is not set, meaning tracing is disabled.
+func NewConfigFromEnv() (*Config, error) {
+	config := &Config{Exporter: os.Getenv(TRACING_EXPORTER), SampleRatio: defaultSampleRatio}
+	if config.Exporter == "" {
+		return nil, nil
+	}
+	if config.Exporter != ExporterOTLP && config.Exporter != ExporterStdout {
+		return nil, fmt.Errorf(ErrMsgUnknownExporter, config.Exporter)
+	}
+
+	if value := os.Getenv(TRACING_SAMPLE_RATIO); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf(ErrMsgInvalidRatio, TRACING_SAMPLE_RATIO, value)
+		}
+		config.SampleRatio = ratio
+	}
+	return config, nil
+}
+
+// NewProvider creates a tracer provider that batches spans to the configured exporter.
+// The provider must be shut down to flush the spans that are still buffered.
+func NewProvider(ctx context.Context, config *Config) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence.
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	), nil
+}
+
+// newExporter creates the exporter selected by the configuration.
+func newExporter(ctx context.Context, config *Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterOTLP:
+		return otlptracehttp.New(ctx)
+	case ExporterStdout:
+		writer := config.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(writer))
+	}
+	return nil, fmt.Errorf(ErrMsgUnknownExporter, config.Exporter)
+}
@@ -0,0 +1,51 @@
+// Gopher Unit Testing was here
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestNewConfigFromEnv checks that tracing is disabled by default and that invalid settings are rejected.
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Setenv(TRACING_EXPORTER, "")
+	if config, err := NewConfigFromEnv(); config != nil || err != nil {
+		t.Errorf("NewConfigFromEnv() = %v, %v, want nil, nil", config, err)
+	}
+
+	t.Setenv(TRACING_EXPORTER, ExporterStdout)
+	t.Setenv(TRACING_SAMPLE_RATIO, "0.25")
+	config, err := NewConfigFromEnv()
+	if err != nil || config.SampleRatio != 0.25 {
+		t.Errorf("NewConfigFromEnv() = %+v, %v, want a sample ratio of 0.25", config, err)
+	}
+
+	for name, value := range map[string]string{TRACING_SAMPLE_RATIO: "1.5", TRACING_EXPORTER: "zipkin"} {
+		t.Setenv(name, value)
+		if _, err := NewConfigFromEnv(); err == nil {
+			t.Errorf("NewConfigFromEnv() with %s=%s error = nil, want an error", name, value)
+		}
+	}
+}
+
+// TestStdoutProvider checks that spans are written by the stdout exporter with the service name once
+// the provider is shut down.
+func TestStdoutProvider(t *testing.T) {
+	var buf bytes.Buffer
+	provider, err := NewProvider(context.Background(), &Config{Exporter: ExporterStdout, SampleRatio: 1, Writer: &buf})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	_, span := provider.Tracer("test").Start(context.Background(), "GET /:id")
+	span.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, `"Name":"GET /:id"`) || !strings.Contains(out, ServiceName) {
+		t.Errorf("exported spans = %s, want the span and the service name", out)
+	}
+}
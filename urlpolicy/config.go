@@ -0,0 +1,90 @@
+package urlpolicy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NewConfigFromEnv builds a Config from the URL_* environment variables.
+// Private addresses are rejected and hostnames are resolved unless configured otherwise.
+func NewConfigFromEnv() (Config, error) {
+	config := Config{
+		AllowedSchemes: splitList(envOrDefault(URL_ALLOWED_SCHEMES, defaultAllowedSchemes)),
+		BlockedDomains: splitList(os.Getenv(URL_BLOCKED_DOMAINS)),
+		SelfHosts:      splitList(os.Getenv(URL_SELF_HOSTS)),
+		ShortenerHosts: splitList(os.Getenv(URL_SHORTENER_HOSTS)),
+		MaxRedirects:   defaultMaxRedirects,
+	}
+
+	var err error
+	if config.AllowPrivateAddresses, err = parseEnvBool(URL_ALLOW_PRIVATE_ADDRESSES, false); err != nil {
+		return config, err
+	}
+	if config.ResolveHostnames, err = parseEnvBool(URL_RESOLVE_HOSTNAMES, true); err != nil {
+		return config, err
+	}
+	if config.ResolveShorteners, err = parseEnvBool(URL_RESOLVE_SHORTENERS, false); err != nil {
+		return config, err
+	}
+	return config, parseEnvInt(URL_MAX_REDIRECTS, &config.MaxRedirects)
+}
+
+// normalizeHost lowercases the host, removes a trailing dot and converts internationalized
+// domain names to their ASCII form, so that look-alike spellings match the same rules.
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envOrDefault returns the value of the environment variable, or the fallback if it is unset.
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// parseEnvBool parses the environment variable as a boolean, or returns the fallback if it is unset.
+func parseEnvBool(name string, fallback bool) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback, fmt.Errorf(ErrMsgInvalidBool, name, value)
+	}
+	return b, nil
+}
+
+// parseEnvInt parses the environment variable as an integer into dst if it is set.
+func parseEnvInt(name string, dst *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf(ErrMsgInvalidInt, name, value)
+	}
+	*dst = n
+	return nil
+}
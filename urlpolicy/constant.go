@@ -0,0 +1,45 @@
+package urlpolicy
+
+import "time"
+
+// Define environment variables used to configure the destination URL policy.
+const (
+	URL_ALLOWED_SCHEMES         = "URL_ALLOWED_SCHEMES"
+	URL_BLOCKED_DOMAINS         = "URL_BLOCKED_DOMAINS"
+	URL_SELF_HOSTS              = "URL_SELF_HOSTS"
+	URL_ALLOW_PRIVATE_ADDRESSES = "URL_ALLOW_PRIVATE_ADDRESSES"
+	URL_RESOLVE_HOSTNAMES       = "URL_RESOLVE_HOSTNAMES"
+	URL_SHORTENER_HOSTS         = "URL_SHORTENER_HOSTS"
+	URL_RESOLVE_SHORTENERS      = "URL_RESOLVE_SHORTENERS"
+	URL_MAX_REDIRECTS           = "URL_MAX_REDIRECTS"
+)
+
+// Define the defaults of the policy.
+const (
+	defaultAllowedSchemes = "http,https"
+	defaultMaxRedirects   = 5
+	defaultLookupTimeout  = 2 * time.Second
+	defaultHTTPTimeout    = 5 * time.Second
+)
+
+// Define the reasons for which a destination URL is rejected.
+const (
+	ReasonMalformed        = "malformed URL"
+	ReasonScheme           = "scheme is not allowed"
+	ReasonMissingHost      = "URL has no host"
+	ReasonCredentials      = "URL must not contain credentials"
+	ReasonBlockedDomain    = "domain is blocked"
+	ReasonSelfLoop         = "URL points back to this shortener"
+	ReasonPrivateAddress   = "address is private, loopback or link-local"
+	ReasonNumericHost      = "numeric host is not a valid IP address"
+	ReasonTooManyRedirects = "too many redirects through URL shorteners"
+	ReasonUnresolvable     = "shortened URL could not be resolved"
+)
+
+// Define error messages for the policy.
+const (
+	ErrMsgViolation          = "destination not allowed: %s"
+	ErrMsgInvalidBool        = "%s: invalid boolean %q"
+	ErrMsgInvalidInt         = "%s: invalid integer %q"
+	ErrMsgInvalidDialAddress = "cannot check dial address %q"
+)
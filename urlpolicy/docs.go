@@ -0,0 +1,37 @@
+// Package urlpolicy decides which destination URLs the URL shortener service accepts.
+// It is applied when links are created or edited, so that short links cannot be abused to hide
+// scripts, local files, internal services or known bad domains behind a trusted host.
+//
+// # Rules
+//
+//   - Only the allowed schemes are accepted, "http" and "https" by default, which rules out
+//     "javascript:", "data:" and "file:" URLs.
+//   - URLs with embedded credentials are rejected, as they are a common phishing trick.
+//   - Configured domains are blocked together with all of their subdomains. Hosts are lowercased
+//     and converted to their ASCII (punycode) form before they are compared.
+//   - URLs pointing back at this shortener are rejected to prevent redirect loops.
+//   - Private, loopback, link-local, multicast and carrier-grade NAT addresses are rejected, whether
+//     they are given literally, as numeric hosts such as "2130706433", or as hostnames resolving to them.
+//   - Optionally, redirects through known URL shorteners are followed, up to a limit, and every hop
+//     as well as the final destination has to pass the same rules. The shorteners are only contacted
+//     at addresses that pass CheckIP, which is checked when connecting, after DNS resolution.
+//
+// # Types
+//
+//   - Config: The rules, usually built with NewConfigFromEnv.
+//   - Policy: Checks URLs against a Config.
+//   - Violation: The error returned for a rejected URL, with a reason that is safe to show to clients.
+//
+// # Example Usage
+//
+//	config, err := urlpolicy.NewConfigFromEnv()
+//	if err != nil {
+//	    // Handle the error.
+//	}
+//	policy := urlpolicy.New(config)
+//	if err := policy.Check(ctx, "http://169.254.169.254/latest/meta-data", r.Host); err != nil {
+//	    // err.Error() is "destination not allowed: address is private, loopback or link-local"
+//	}
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package urlpolicy
@@ -0,0 +1,266 @@
+package urlpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// Config holds the rules that destination URLs must satisfy.
+type Config struct {
+	AllowedSchemes        []string // The accepted schemes, "http" and "https" by default.
+	BlockedDomains        []string // Domains that are rejected along with all of their subdomains.
+	SelfHosts             []string // The hosts of this shortener, rejected to prevent redirect loops.
+	AllowPrivateAddresses bool     // Accept private, loopback and link-local addresses; meant for development only.
+	ResolveHostnames      bool     // Resolve hostnames and reject those pointing at private addresses.
+	ShortenerHosts        []string // Hosts of known URL shorteners, whose redirects may be followed.
+	ResolveShorteners     bool     // Follow redirects through ShortenerHosts and check the final destination.
+	MaxRedirects          int      // The maximum number of shortener redirects followed.
+
+	// LookupIP resolves hostnames; net.DefaultResolver is used if it is nil.
+	LookupIP func(ctx context.Context, host string) ([]net.IP, error)
+	// HTTPClient is used to follow shortener redirects. It must not follow redirects by itself.
+	// If it is nil, a client is created that checks every address it connects to with CheckIP.
+	HTTPClient *http.Client
+}
+
+// Violation is returned when a destination URL breaks the policy.
+type Violation struct {
+	URL    string
+	Reason string
+}
+
+// Error returns a message that is safe to return to the client.
+func (v *Violation) Error() string {
+	return fmt.Sprintf(ErrMsgViolation, v.Reason)
+}
+
+// Policy checks destination URLs against a Config.
+// It is safe for concurrent use by multiple goroutines.
+type Policy struct {
+	config  Config
+	schemes map[string]bool
+}
+
+// New creates a Policy from the configuration, filling in the defaults of unset fields.
+func New(config Config) *Policy {
+	if len(config.AllowedSchemes) == 0 {
+		config.AllowedSchemes = splitList(defaultAllowedSchemes)
+	}
+	if config.MaxRedirects <= 0 {
+		config.MaxRedirects = defaultMaxRedirects
+	}
+	if config.LookupIP == nil {
+		config.LookupIP = lookupIP
+	}
+
+	p := &Policy{config: config, schemes: make(map[string]bool)}
+	for _, scheme := range config.AllowedSchemes {
+		p.schemes[strings.ToLower(scheme)] = true
+	}
+	if p.config.HTTPClient == nil {
+		p.config.HTTPClient = p.newHTTPClient()
+	}
+	return p
+}
+
+// newHTTPClient creates the client that follows shortener redirects. Its dialer checks every address
+// with CheckIP when it connects, after DNS resolution, so that a shortener host cannot be rebound to an
+// internal address after it passed Check. Proxies are disabled, as the dialer would only see the proxy.
+func (p *Policy) newHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: defaultHTTPTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf(ErrMsgInvalidDialAddress, address)
+			}
+			return p.CheckIP(ip)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   defaultHTTPTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Check reports whether the destination URL is allowed. selfHosts are added to the configured hosts
+// of this shortener, typically the Host of the request that creates the link. If shortener resolution
+// is enabled, every hop through a known shortener is checked as well as the final destination.
+// The returned error is a *Violation.
+func (p *Policy) Check(ctx context.Context, rawURL string, selfHosts ...string) error {
+	target := rawURL
+	for hop := 0; ; hop++ {
+		u, err := p.checkOne(ctx, target, selfHosts)
+		if err != nil {
+			return err
+		}
+		if !p.config.ResolveShorteners || !matchesHost(hostOf(u), p.config.ShortenerHosts) {
+			return nil
+		}
+		if hop >= p.config.MaxRedirects {
+			return &Violation{URL: rawURL, Reason: ReasonTooManyRedirects}
+		}
+		if target, err = p.resolveShortener(ctx, u); err != nil {
+			return &Violation{URL: rawURL, Reason: ReasonUnresolvable}
+		}
+	}
+}
+
+// checkOne checks a single URL, without following any redirect.
+func (p *Policy) checkOne(ctx context.Context, rawURL string, selfHosts []string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, &Violation{URL: rawURL, Reason: ReasonMalformed}
+	}
+	if reason := p.checkSyntax(u, selfHosts); reason != "" {
+		return nil, &Violation{URL: rawURL, Reason: reason}
+	}
+	if reason := p.checkAddress(ctx, hostOf(u)); reason != "" {
+		return nil, &Violation{URL: rawURL, Reason: reason}
+	}
+	return u, nil
+}
+
+// checkSyntax checks the scheme, credentials and host of the URL against the static rules.
+func (p *Policy) checkSyntax(u *url.URL, selfHosts []string) string {
+	host := hostOf(u)
+	switch {
+	case !p.schemes[strings.ToLower(u.Scheme)]:
+		return ReasonScheme
+	case host == "":
+		return ReasonMissingHost
+	case u.User != nil:
+		return ReasonCredentials
+	case matchesHost(host, p.config.BlockedDomains):
+		return ReasonBlockedDomain
+	case matchesHost(host, p.config.SelfHosts) || matchesHost(host, selfHosts):
+		return ReasonSelfLoop
+	}
+	return ""
+}
+
+// checkAddress rejects hosts that are, or resolve to, addresses inside private networks.
+func (p *Policy) checkAddress(ctx context.Context, host string) string {
+	if p.config.AllowPrivateAddresses {
+		return ""
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return reasonForIP(ip)
+	}
+	if isNumericHost(host) {
+		return ReasonNumericHost
+	}
+	if !p.config.ResolveHostnames {
+		return ""
+	}
+
+	// Hostnames that cannot be resolved are accepted, as they cannot reach an internal address either.
+	ips, _ := p.config.LookupIP(ctx, host)
+	for _, ip := range ips {
+		if reason := reasonForIP(ip); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// CheckIP returns a Violation if the policy does not accept connections to the IP address, such as a private
+// one. It is meant for the Control function of a net.Dialer, so that the address is checked when it is
+// connected to, after every DNS lookup, and a hostname cannot be rebound to an internal address after it
+// passed Check.
+func (p *Policy) CheckIP(ip net.IP) error {
+	if p.config.AllowPrivateAddresses {
+		return nil
+	}
+	if reason := reasonForIP(ip); reason != "" {
+		return &Violation{URL: ip.String(), Reason: reason}
+	}
+	return nil
+}
+
+// resolveShortener asks a URL shortener where the URL leads, without following the redirect.
+func (p *Policy) resolveShortener(ctx context.Context, u *url.URL) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", err
+	}
+	return location.String(), nil
+}
+
+// reasonForIP returns the reason an IP address is rejected, or an empty string if it is public.
+func reasonForIP(ip net.IP) string {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return ReasonPrivateAddress
+	}
+	return ""
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is not routable on the internet.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isNumericHost reports whether the host would be read as an IPv4 address by browsers because its
+// last label is a number, for example "2130706433" or "0177.0.0.1". Such hosts are not valid IP
+// addresses for net.ParseIP, so they would otherwise slip past the private address check.
+func isNumericHost(host string) bool {
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	last := strings.ToLower(labels[len(labels)-1])
+	if strings.HasPrefix(last, "0x") {
+		last = last[2:]
+		return last == "" || strings.Trim(last, "0123456789abcdef") == ""
+	}
+	return last != "" && strings.Trim(last, "0123456789") == ""
+}
+
+// hostOf returns the normalized host of the URL, without its port.
+func hostOf(u *url.URL) string {
+	return normalizeHost(u.Hostname())
+}
+
+// matchesHost reports whether the host equals one of the domains or is a subdomain of one.
+// Domains may carry a port, as the Host header of a request does; it is ignored.
+func matchesHost(host string, domains []string) bool {
+	for _, domain := range domains {
+		if hostname, _, err := net.SplitHostPort(domain); err == nil {
+			domain = hostname
+		}
+		domain = normalizeHost(domain)
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupIP resolves a hostname with the default resolver, bounded by defaultLookupTimeout.
+func lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultLookupTimeout)
+	defer cancel()
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the webhook delivery settings of the service.
+type Config struct {
+	Timeout      time.Duration // How long a subscriber has to answer a delivery.
+	MaxAttempts  int           // How many times a delivery is attempted before it is dead-lettered.
+	Backoff      Backoff       // The delays between the attempts of a delivery.
+	PollInterval time.Duration // How often pending deliveries are sent and click counts are stored.
+
+	// ClickThresholds are the numbers of clicks at which a link.clicked event is sent, in increasing
+	// order. Clicks are only counted when at least one threshold is configured.
+	ClickThresholds []int64
+}
+
+// NewConfigFromEnv builds a Config from the WEBHOOK_* environment variables, using the defaults
+// for those left unset.
+func NewConfigFromEnv() (Config, error) {
+	config := Config{
+		Timeout:      defaultTimeout,
+		MaxAttempts:  defaultMaxAttempts,
+		Backoff:      Backoff{Base: defaultRetryBase, Max: defaultRetryMax},
+		PollInterval: defaultPollInterval,
+	}
+	for name, dst := range map[string]*time.Duration{
+		WEBHOOK_TIMEOUT:       &config.Timeout,
+		WEBHOOK_RETRY_BASE:    &config.Backoff.Base,
+		WEBHOOK_RETRY_MAX:     &config.Backoff.Max,
+		WEBHOOK_POLL_INTERVAL: &config.PollInterval,
+	} {
+		if err := parseEnvDuration(name, dst); err != nil {
+			return config, err
+		}
+	}
+
+	if value := os.Getenv(WEBHOOK_MAX_ATTEMPTS); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf(ErrMsgInvalidAttempts, WEBHOOK_MAX_ATTEMPTS, value)
+		}
+		config.MaxAttempts = n
+	}
+
+	var err error
+	config.ClickThresholds, err = ParseClickThresholds(os.Getenv(WEBHOOK_CLICK_THRESHOLDS))
+	return config, err
+}
+
+// ParseClickThresholds parses a comma-separated list of increasing positive numbers of clicks.
+// An empty string yields no thresholds.
+func ParseClickThresholds(s string) ([]int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var thresholds []int64
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || n <= 0 || (len(thresholds) > 0 && n <= thresholds[len(thresholds)-1]) {
+			return nil, fmt.Errorf(ErrMsgInvalidThresholds, WEBHOOK_CLICK_THRESHOLDS, s)
+		}
+		thresholds = append(thresholds, n)
+	}
+	return thresholds, nil
+}
+
+// CrossedThresholds returns the thresholds passed when a click count went from before to after.
+func CrossedThresholds(thresholds []int64, before, after int64) []int64 {
+	var crossed []int64
+	for _, t := range thresholds {
+		if before < t && t <= after {
+			crossed = append(crossed, t)
+		}
+	}
+	return crossed
+}
+
+// parseEnvDuration parses the environment variable as a positive duration into dst if it is set.
+func parseEnvDuration(name string, dst *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf(ErrMsgInvalidDuration, name, value)
+	}
+	*dst = d
+	return nil
+}
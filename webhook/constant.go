@@ -0,0 +1,48 @@
+package webhook
+
+import "time"
+
+// Define environment variables used to configure webhook delivery.
+const (
+	WEBHOOK_TIMEOUT          = "WEBHOOK_TIMEOUT"
+	WEBHOOK_MAX_ATTEMPTS     = "WEBHOOK_MAX_ATTEMPTS"
+	WEBHOOK_RETRY_BASE       = "WEBHOOK_RETRY_BASE"
+	WEBHOOK_RETRY_MAX        = "WEBHOOK_RETRY_MAX"
+	WEBHOOK_POLL_INTERVAL    = "WEBHOOK_POLL_INTERVAL"
+	WEBHOOK_CLICK_THRESHOLDS = "WEBHOOK_CLICK_THRESHOLDS"
+	defaultTimeout           = 10 * time.Second
+	defaultMaxAttempts       = 8
+	defaultRetryBase         = 30 * time.Second
+	defaultRetryMax          = 6 * time.Hour
+	defaultPollInterval      = 5 * time.Second
+)
+
+// Define the headers sent with each delivery.
+const (
+	HeaderSignature = "Webhook-Signature"
+	HeaderEventType = "Webhook-Event"
+	HeaderDelivery  = "Webhook-Delivery"
+	signatureScheme = "v1"
+)
+
+// Define the limits of webhook delivery.
+const (
+	// maxResponseBody bounds the part of a response body that is read, and then discarded.
+	maxResponseBody = 64 << 10
+	// maxJitter is the largest fraction of a retry delay added at random, so that failed deliveries
+	// do not all retry at the same moment.
+	maxJitter = 0.2
+)
+
+// Define error messages for webhooks.
+const (
+	ErrMsgUnexpectedStatus   = "webhook: unexpected status %d"
+	ErrMsgInvalidDialAddress = "webhook: cannot check dial address %q"
+	ErrMsgInvalidSignature   = "webhook: invalid signature"
+	ErrMsgExpiredSignature   = "webhook: signature timestamp outside the tolerance"
+	ErrMsgMalformedHeader    = "webhook: malformed signature header"
+	ErrMsgUnknownEventType   = "webhook: unknown event type %q"
+	ErrMsgInvalidDuration    = "%s: invalid duration %q"
+	ErrMsgInvalidAttempts    = "%s: invalid value %q, expected a positive integer"
+	ErrMsgInvalidThresholds  = "%s: invalid thresholds %q, expected increasing positive integers"
+)
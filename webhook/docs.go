@@ -0,0 +1,42 @@
+// Package webhook notifies external systems of the lifecycle of links, by posting signed JSON
+// events to the endpoints they subscribed with. It contains the pieces that do not depend on
+// storage: the event types and payload, the signature scheme, the HTTP sender, the retry backoff
+// and the configuration. Subscriptions and the queue of deliveries are stored by the datastore
+// package, and the handlers package enqueues events and runs the dispatcher.
+//
+// # Events
+//
+// The following event types can be subscribed to:
+//   - link.created: A link was created.
+//   - link.edited: The destination or the settings of a link changed, including by a rollback.
+//   - link.deleted: A link was deleted.
+//   - link.restored: A deleted link was restored.
+//   - link.expired: The expiry of a link has passed.
+//   - link.clicked: The number of clicks of a link passed one of WEBHOOK_CLICK_THRESHOLDS.
+//
+// # Signatures
+//
+// Each delivery carries a Webhook-Signature header of the form "t=<unix seconds>,v1=<hex>",
+// where the hex is the HMAC-SHA256, keyed with the secret of the subscription, of the timestamp,
+// a dot and the raw request body. Receivers should check it with Verify, or an equivalent, and
+// reject timestamps that are too old to prevent replays. The Webhook-Delivery header is the same
+// across the retries of a delivery and can be used to discard duplicates.
+//
+// # Retries
+//
+// A delivery that fails, because of a network error or a status code other than 2xx, is retried
+// after a delay computed by Backoff, which doubles from WEBHOOK_RETRY_BASE up to WEBHOOK_RETRY_MAX
+// with some jitter. After WEBHOOK_MAX_ATTEMPTS attempts, the delivery is dead-lettered; it can
+// still be inspected and retried by hand.
+//
+// # Example Usage
+//
+//	config, err := webhook.NewConfigFromEnv()
+//	if err != nil {
+//	    // Handle the error.
+//	}
+//	sender := webhook.NewSender(config.Timeout, policy.CheckIP)
+//	status, err := sender.Send(ctx, endpoint, secret, webhook.NewDeliveryID(time.Now()), webhook.EventLinkCreated, payload)
+//
+// Copyright (c) 2023 by H0llyW00dzZ
+package webhook
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Define the types of events that subscriptions can receive.
+const (
+	// EventLinkCreated is sent when a link is created.
+	EventLinkCreated = "link.created"
+	// EventLinkEdited is sent when the destination or settings of a link change, including rollbacks.
+	EventLinkEdited = "link.edited"
+	// EventLinkDeleted is sent when a link is deleted.
+	EventLinkDeleted = "link.deleted"
+	// EventLinkRestored is sent when a deleted link is restored.
+	EventLinkRestored = "link.restored"
+	// EventLinkExpired is sent once when the expiry of a link has passed.
+	EventLinkExpired = "link.expired"
+	// EventLinkClicked is sent when the number of clicks of a link passes one of the configured thresholds.
+	EventLinkClicked = "link.clicked"
+)
+
+// EventTypes lists every event type, in the order they are documented.
+var EventTypes = []string{
+	EventLinkCreated, EventLinkEdited, EventLinkDeleted, EventLinkRestored, EventLinkExpired, EventLinkClicked,
+}
+
+// ValidateEventTypes checks that each of the types is a known event type.
+func ValidateEventTypes(types []string) error {
+	for _, t := range types {
+		if !isEventType(t) {
+			return fmt.Errorf(ErrMsgUnknownEventType, t)
+		}
+	}
+	return nil
+}
+
+// isEventType reports whether t is a known event type.
+func isEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the JSON payload sent to subscribers.
+type Event struct {
+	ID        string    `json:"id"`         // A unique ID, identical across the retries of a delivery.
+	Type      string    `json:"type"`       // One of the event types.
+	CreatedAt time.Time `json:"created_at"` // When the event happened.
+	Data      any       `json:"data"`       // The details of the event, such as the link concerned.
+}
+
+// NewEvent creates an event of the given type with a new random ID.
+func NewEvent(eventType string, data any) Event {
+	return Event{ID: "evt_" + randomHex(12), Type: eventType, CreatedAt: time.Now(), Data: data}
+}
+
+// NewDeliveryID returns a new ID for a delivery. IDs sort in the order they were created, so that
+// deliveries stored under them are listed chronologically.
+func NewDeliveryID(now time.Time) string {
+	return fmt.Sprintf("%016x-%s", now.UnixNano(), randomHex(4))
+}
+
+// NewSecret returns a new random signing secret for a subscription.
+func NewSecret() string {
+	return "whsec_" + randomHex(32)
+}
+
+// randomHex returns n random bytes, hex-encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms.
+	}
+	return hex.EncodeToString(b)
+}
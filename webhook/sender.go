@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Sender delivers payloads to subscribers over HTTP.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a sender whose requests time out after timeout. Redirects are not followed,
+// so a subscriber cannot send a delivery on to another address.
+//
+// If checkIP is not nil, it is called with the IP address of every connection before it is made, and
+// an error aborts the delivery. Checking at dial time rather than when the subscription is created
+// prevents DNS rebinding, where the endpoint resolves to a public address when it is checked and to
+// an internal one when it is delivered to. Proxies from the environment are not used in that case, as
+// the address checked would be the one of the proxy.
+func NewSender(timeout time.Duration, checkIP func(ip net.IP) error) *Sender {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if checkIP != nil {
+		dialer := &net.Dialer{
+			Timeout: timeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil {
+					return fmt.Errorf(ErrMsgInvalidDialAddress, address)
+				}
+				return checkIP(ip)
+			},
+		}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+
+	return &Sender{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send posts the payload to the endpoint, signed with the secret. It returns the status code of the
+// response, and an error unless the status code is 2xx.
+func (s *Sender) Send(ctx context.Context, endpoint, secret, deliveryID, eventType string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventType, eventType)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderSignature, Sign(secret, time.Now(), payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf(ErrMsgUnexpectedStatus, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff computes the delay before the next attempt of a failed delivery.
+type Backoff struct {
+	Base time.Duration // The delay after the first failed attempt.
+	Max  time.Duration // The largest delay, before jitter.
+}
+
+// Delay returns the delay after the given number of failed attempts: Base doubled for each attempt
+// after the first, capped at Max, plus up to 20% of random jitter.
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.Max)
+	return delay + time.Duration(rand.Float64()*maxJitter*float64(delay))
+}
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sign returns the value of the Webhook-Signature header for the payload, in the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256>". The signed message is the timestamp, a dot and the
+// payload, so that a captured request cannot be replayed later with a new timestamp.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + "," + signatureScheme + "=" + computeMAC(secret, t, payload)
+}
+
+// Verify checks a Webhook-Signature header against the payload, as a receiver would. The timestamp
+// must lie within tolerance of now.
+func Verify(secret string, header string, payload []byte, tolerance time.Duration, now time.Time) error {
+	var t, mac string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			t = value
+		case signatureScheme:
+			mac = value
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || mac == "" {
+		return fmt.Errorf(ErrMsgMalformedHeader)
+	}
+
+	if !hmac.Equal([]byte(mac), []byte(computeMAC(secret, t, payload))) {
+		return fmt.Errorf(ErrMsgInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf(ErrMsgExpiredSignature)
+	}
+	return nil
+}
+
+// computeMAC returns the hex-encoded HMAC-SHA256 of the timestamp and the payload.
+func computeMAC(secret string, timestamp string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,133 @@
+// Gopher Unit Testing was here
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestSignVerify checks that a signature is accepted, and rejected once the payload, the secret
+// or the timestamp no longer match.
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	payload := []byte(`{"type":"link.created"}`)
+	header := Sign("whsec_test", now, payload)
+
+	if err := Verify("whsec_test", header, payload, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+	testCases := map[string]struct {
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+	}{
+		"tampered payload": {"whsec_test", header, []byte(`{"type":"link.deleted"}`), now},
+		"wrong secret":     {"whsec_other", header, payload, now},
+		"expired":          {"whsec_test", header, payload, now.Add(time.Hour)},
+		"malformed header": {"whsec_test", "v1=abc", payload, now},
+	}
+	for name, tc := range testCases {
+		if err := Verify(tc.secret, tc.header, tc.payload, 5*time.Minute, tc.now); err == nil {
+			t.Errorf("%s: Verify() error = nil, want an error", name)
+		}
+	}
+}
+
+// TestBackoffDelay checks that delays double from the base, are capped and stay within the jitter.
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Base: time.Second, Max: time.Minute}
+	testCases := map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		10: time.Minute,
+	}
+	for attempts, want := range testCases {
+		got := backoff.Delay(attempts)
+		if got < want || got > want+time.Duration(maxJitter*float64(want)) {
+			t.Errorf("Delay(%d) = %v, want between %v and %v", attempts, got, want, want+time.Duration(maxJitter*float64(want)))
+		}
+	}
+}
+
+// TestClickThresholds checks the parsing of thresholds and which of them a change of count crosses.
+func TestClickThresholds(t *testing.T) {
+	thresholds, err := ParseClickThresholds("10, 100,1000")
+	if err != nil {
+		t.Fatalf("ParseClickThresholds() error = %v", err)
+	}
+	if !slices.Equal(thresholds, []int64{10, 100, 1000}) {
+		t.Errorf("ParseClickThresholds() = %v", thresholds)
+	}
+	for _, invalid := range []string{"10,5", "0", "ten", "10,,20"} {
+		if _, err := ParseClickThresholds(invalid); err == nil {
+			t.Errorf("ParseClickThresholds(%q) error = nil, want an error", invalid)
+		}
+	}
+
+	if got := CrossedThresholds(thresholds, 9, 150); !slices.Equal(got, []int64{10, 100}) {
+		t.Errorf("CrossedThresholds(9, 150) = %v, want [10 100]", got)
+	}
+	if got := CrossedThresholds(thresholds, 10, 99); got != nil {
+		t.Errorf("CrossedThresholds(10, 99) = %v, want none", got)
+	}
+}
+
+// TestValidateEventTypes checks that only known event types are accepted.
+func TestValidateEventTypes(t *testing.T) {
+	if err := ValidateEventTypes(EventTypes); err != nil {
+		t.Errorf("ValidateEventTypes(EventTypes) error = %v", err)
+	}
+	if err := ValidateEventTypes([]string{EventLinkCreated, "link.renamed"}); err == nil {
+		t.Error("ValidateEventTypes() error = nil, want an error for an unknown type")
+	}
+}
+
+// TestSenderSend checks that a delivery is signed and carries its headers, and that a status
+// code other than 2xx is reported as an error.
+func TestSenderSend(t *testing.T) {
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify("whsec_test", r.Header.Get(HeaderSignature), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+		if r.Header.Get(HeaderEventType) != EventLinkEdited || r.Header.Get(HeaderDelivery) != "d1" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := NewSender(time.Second, nil)
+	got, err := sender.Send(context.Background(), server.URL, "whsec_test", "d1", EventLinkEdited, []byte(`{}`))
+	if err != nil || got != http.StatusNoContent {
+		t.Errorf("Send() = %d, %v, want 204, nil", got, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	got, err = sender.Send(context.Background(), server.URL, "whsec_test", "d1", EventLinkEdited, []byte(`{}`))
+	if err == nil || got != http.StatusServiceUnavailable {
+		t.Errorf("Send() = %d, %v, want 503 and an error", got, err)
+	}
+
+	// The address is checked when it is connected to, so the test server on the loopback is refused.
+	refused := errors.New("address refused")
+	sender = NewSender(time.Second, func(ip net.IP) error {
+		if !ip.IsLoopback() {
+			t.Errorf("checkIP(%v), want the loopback address of the test server", ip)
+		}
+		return refused
+	})
+	if _, err := sender.Send(context.Background(), server.URL, "whsec_test", "d1", EventLinkEdited, []byte(`{}`)); !errors.Is(err, refused) {
+		t.Errorf("Send() error = %v, want %v", err, refused)
+	}
+}
@@ -30,6 +30,8 @@
 //	if err != nil {
 //	    // Handle error
 //	}
+//	// Log at the runtime level of this package, see logmonitor.SetLevel.
+//	workerk8s.SetLogger(logmonitor.PackageLogger(logger, logmonitor.PackageWorkerK8s))
 //	namespace := "default" // Replace with your namespace
 //	ctx := context.Background() // Use context to control worker lifetimes
 //	results, shutdown := workerk8s.RunWorkers(ctx, clientset, namespace, 5)